@@ -7,8 +7,9 @@ import (
 )
 
 type BookActionRequest struct {
-	BookID uuid.UUID `json:"book_id"`
-	Email  string    `json:"email"`
+	BookID     uuid.UUID `json:"book_id" validate:"required"`
+	Email      string    `json:"email" validate:"omitempty,email"`
+	CardNumber string    `json:"card_number"`
 }
 
 type BookActionResponse struct {
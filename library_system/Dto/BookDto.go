@@ -1,16 +1,23 @@
 package Dto
 
-import "github.com/gobuffalo/uuid"
+import "github.com/gofrs/uuid"
 
 type BookRequest struct {
 	ID        string `json:"id"`
-	Title     string `json:"title"`
-	Author    string `json:"author"`
-	ISBN      string `json:"isbn"`
-	Status    string `json:"status"`
+	Title     string `json:"title" validate:"required"`
+	Author    string `json:"author" validate:"required"`
+	ISBN      string `json:"isbn" validate:"required,isbn"`
+	Status    string `json:"status" validate:"omitempty,book_status"`
 	UserToken string `json:"-"`
 }
 
+// BookPatchRequest changes only the fields that are present. The ISBN identifies the
+// edition and cannot be changed; status changes go through the status endpoint.
+type BookPatchRequest struct {
+	Title  *string `json:"title" validate:"omitnil,notblank"`
+	Author *string `json:"author" validate:"omitnil,notblank"`
+}
+
 type BookResponse struct {
 	ID     uuid.UUID `json:"id"`
 	Title  string    `json:"title"`
@@ -0,0 +1,21 @@
+package Dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type BookStatusRequest struct {
+	Status string `json:"status" validate:"required,book_status"`
+	Reason string `json:"reason" validate:"required"`
+}
+
+type BookStatusChangeResponse struct {
+	ID         uuid.UUID `json:"id"`
+	BookID     uuid.UUID `json:"book_id"`
+	FromStatus string    `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	Reason     string    `json:"reason"`
+	ChangedAt  time.Time `json:"changed_at"`
+}
@@ -0,0 +1,33 @@
+package Dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+type APITokenRequest struct {
+	Name          string    `json:"name" validate:"required"`
+	Scopes        []string  `json:"scopes" validate:"required,dive,oneof=books:write loans:write users:admin"`
+	ExpiresInDays int       `json:"expires_in_days" validate:"omitempty,min=1"`
+	UserID        uuid.UUID `json:"user_id"`
+}
+
+type APITokenResponse struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// APITokenCreatedResponse is only returned once, when the token is issued; the plain
+// token cannot be recovered afterwards.
+type APITokenCreatedResponse struct {
+	APITokenResponse
+	Token string `json:"token"`
+}
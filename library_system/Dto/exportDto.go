@@ -0,0 +1,43 @@
+package Dto
+
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
+
+// UserDataExport is everything the library holds about one user, as handed over in
+// answer to a subject access request.
+type UserDataExport struct {
+	GeneratedAt time.Time          `json:"generated_at"`
+	Profile     UserProfileExport  `json:"profile"`
+	Loans       []LoanSummary      `json:"loans"`
+	Holds       []LoanSummary      `json:"holds"`
+	Fines       []FineExport       `json:"fines"`
+	APITokens   []APITokenResponse `json:"api_tokens"`
+}
+
+type UserProfileExport struct {
+	ID               uuid.UUID  `json:"id"`
+	Name             string     `json:"name"`
+	Email            string     `json:"email"`
+	CardNumber       string     `json:"card_number"`
+	Role             string     `json:"role"`
+	PatronType       string     `json:"patron_type"`
+	EmailVerifiedAt  *time.Time `json:"email_verified_at,omitempty"`
+	ClosedAt         *time.Time `json:"closed_at,omitempty"`
+	SuspendedAt      *time.Time `json:"suspended_at,omitempty"`
+	SuspendedUntil   *time.Time `json:"suspended_until,omitempty"`
+	SuspensionReason string     `json:"suspension_reason,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+type FineExport struct {
+	ID          uuid.UUID  `json:"id"`
+	LoanID      *uuid.UUID `json:"loan_id,omitempty"`
+	AmountCents int        `json:"amount_cents"`
+	Reason      string     `json:"reason"`
+	PaidAt      *time.Time `json:"paid_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
@@ -5,16 +5,23 @@ import (
 	"time"
 )
 
-type LoanRequest struct {
-	BookID uuid.UUID `json:"book_id" validate:"required"`
-	UserID uuid.UUID `json:"user_id" validate:"required"`
-}
-
 type LoanResponse struct {
 	ID         uuid.UUID  `json:"id"`
 	BookID     uuid.UUID  `json:"book_id"`
 	UserID     uuid.UUID  `json:"user_id"`
+	Kind       string     `json:"kind"`
 	LoanDate   time.Time  `json:"loan_date"`
 	ReturnDate *time.Time `json:"return_date,omitempty"`
 	UserName   string     `json:"user_name,omitempty"`
 }
+
+// LoanSummary describes a loan or hold from the patron's side, with the book it is for.
+type LoanSummary struct {
+	ID         uuid.UUID  `json:"id"`
+	BookID     uuid.UUID  `json:"book_id"`
+	BookTitle  string     `json:"book_title,omitempty"`
+	BookISBN   string     `json:"book_isbn,omitempty"`
+	Email      string     `json:"email"`
+	LoanDate   time.Time  `json:"loan_date"`
+	ReturnDate *time.Time `json:"return_date,omitempty"`
+}
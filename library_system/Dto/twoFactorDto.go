@@ -0,0 +1,18 @@
+package Dto
+
+type TwoFactorCodeRequest struct {
+	// Code is a six-digit authenticator code or an unused recovery code.
+	Code string `json:"code" validate:"required"`
+}
+
+// TwoFactorEnrolmentResponse carries the secret for the authenticator app. The
+// provisioning URI is what the client renders as a QR code.
+type TwoFactorEnrolmentResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+// RecoveryCodesResponse is only returned when codes are issued; they cannot be shown again.
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
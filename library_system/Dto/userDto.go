@@ -1,14 +1,93 @@
 package Dto
 
-import "github.com/gobuffalo/uuid"
+import (
+	"time"
+
+	"github.com/gofrs/uuid"
+)
 
 type UserRequest struct {
+	Name     string `json:"name" validate:"required"`
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required"`
+}
+
+type DirectoryLoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=patron librarian admin"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+type ProfileUpdateRequest struct {
 	Name  string `json:"name"`
-	Email string `json:"email"`
+	Email string `json:"email" validate:"omitempty,email"`
+}
+
+type PasswordResetRequest struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+type SuspensionRequest struct {
+	Reason string     `json:"reason" validate:"required"`
+	Until  *time.Time `json:"until"`
 }
 
 type UserResponse struct {
-	ID    uuid.UUID `json:"id"`
-	Name  string    `json:"name"`
-	Email string    `json:"email"`
+	ID            uuid.UUID           `json:"id"`
+	Name          string              `json:"name"`
+	Email         string              `json:"email"`
+	CardNumber    string              `json:"card_number"`
+	EmailVerified bool                `json:"email_verified"`
+	Role          string              `json:"role"`
+	PatronType    string              `json:"patron_type"`
+	Status        string              `json:"status"`
+	Suspended     bool                `json:"suspended"`
+	Suspension    *SuspensionResponse `json:"suspension,omitempty"`
+	TwoFactor     bool                `json:"two_factor_enabled"`
+}
+
+type PatronTypeRequest struct {
+	PatronType string `json:"patron_type" validate:"required,oneof=adult child student staff"`
+}
+
+type UserSearchRequest struct {
+	Query      string
+	Status     string
+	PatronType string
+	Page       int
+	PerPage    int
+}
+
+type UserListResponse struct {
+	Users      []UserResponse `json:"users"`
+	Page       int            `json:"page"`
+	PerPage    int            `json:"per_page"`
+	Total      int            `json:"total"`
+	TotalPages int            `json:"total_pages"`
+}
+
+type UserDetailResponse struct {
+	User                  *UserResponse `json:"user"`
+	Loans                 []LoanSummary `json:"loans"`
+	Holds                 []LoanSummary `json:"holds"`
+	OutstandingFinesCents int           `json:"outstanding_fines_cents"`
+}
+
+type SuspensionResponse struct {
+	Reason         string     `json:"reason"`
+	SuspendedAt    time.Time  `json:"suspended_at"`
+	SuspendedUntil *time.Time `json:"suspended_until,omitempty"`
 }
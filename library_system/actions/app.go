@@ -1,17 +1,27 @@
 package actions
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/envy"
 	"github.com/gobuffalo/packr/v2"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gorilla/sessions"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
+	"library-system/auth"
 	"library-system/controllers"
+	"library-system/graph"
+	"library-system/mailers"
+	"library-system/models"
 	"library-system/repositories/repository"
+	"library-system/rpc"
 	"library-system/services"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
 )
 
@@ -19,6 +29,7 @@ var (
 	app          *buffalo.App
 	appOnce      sync.Once
 	sessionStore sessions.Store
+	grpcServer   *grpc.Server
 	ENV          = envy.Get("GO_ENV", "development")
 )
 
@@ -28,12 +39,31 @@ func init() {
 	}
 }
 
+// initSessionStore signs and encrypts session cookies with the keys derived from
+// SESSION_SECRETS. Outside production a missing secret falls back to a random one,
+// which signs everyone out whenever the server restarts.
 func initSessionStore() {
-	sessionKey := []byte("12345678901234567890123456789012")
-	store := sessions.NewCookieStore(sessionKey)
+	secrets := auth.SessionSecretsFromEnv()
+	if len(secrets) == 0 {
+		if ENV == "production" {
+			log.Fatalf("SESSION_SECRETS must be set in production")
+		}
+		log.Printf("Warning: SESSION_SECRETS is not set. Using a random key for this process")
+		random := make([]byte, auth.MinSessionSecretLength)
+		if _, err := rand.Read(random); err != nil {
+			log.Fatalf("Unable to generate a session secret: %v", err)
+		}
+		secrets = []string{hex.EncodeToString(random)}
+	}
+
+	keyPairs, err := auth.SessionKeyPairs(secrets)
+	if err != nil {
+		log.Fatalf("Invalid session secret: %v", err)
+	}
+	store := sessions.NewCookieStore(keyPairs...)
 	store.Options = &sessions.Options{
 		Path:     "/",
-		MaxAge:   86400 * 30,
+		MaxAge:   int(services.SessionLifetime.Seconds()),
 		HttpOnly: true,
 		Secure:   ENV == "production",
 		SameSite: http.SameSiteLaxMode,
@@ -41,6 +71,31 @@ func initSessionStore() {
 	sessionStore = store
 }
 
+// fineBlockThresholdCents reads the outstanding fines, in cents, above which patrons
+// are blocked from borrowing. Zero disables the block.
+func fineBlockThresholdCents() int {
+	threshold, err := strconv.Atoi(envy.Get("FINE_BLOCK_THRESHOLD_CENTS", "1000"))
+	if err != nil || threshold < 0 {
+		log.Printf("Warning: invalid FINE_BLOCK_THRESHOLD_CENTS, using 1000")
+		return 1000
+	}
+	return threshold
+}
+
+// loginAttemptRepository picks the store for failed-login counters. The database store
+// is shared by every instance; LOGIN_THROTTLE_STORE=memory suits a single instance.
+func loginAttemptRepository(db *pop.Connection) repository.LoginAttemptRepository {
+	switch store := envy.Get("LOGIN_THROTTLE_STORE", "database"); store {
+	case "memory":
+		return repository.NewMemoryLoginAttemptRepository()
+	case "database":
+		return repository.NewLoginAttemptRepository(db)
+	default:
+		log.Fatalf("Unknown LOGIN_THROTTLE_STORE %q", store)
+		return nil
+	}
+}
+
 func App() *buffalo.App {
 	appOnce.Do(func() {
 		initSessionStore()
@@ -52,86 +107,245 @@ func App() *buffalo.App {
 			Addr:         ":3000",
 		})
 
-		// Apply CORS middleware
-		app.Use(CORS)
+		cors, err := CORSConfigFromEnv()
+		if err != nil {
+			log.Fatalf("Unable to configure CORS: %v", err)
+		}
+		app.PreWares = append(app.PreWares, CORS(cors))
 
 		app.Use(SecurityHeaders)
-		app.Use(SetCurrentUser)
+		app.Use(RenderProblems)
+		HandleErrorsAsProblems(app)
 
 		db := pop.Connections[ENV]
 
 		userRepo := repository.NewUserRepository(db)
 		bookRepo := repository.NewBookRepository(db)
 		loanRepo := repository.NewLoanRepository(db)
+		bookStatusRepo := repository.NewBookStatusRepository(db)
+		apiTokenRepo := repository.NewAPITokenRepository(db)
+		userTokenRepo := repository.NewUserTokenRepository(db)
+		fineRepo := repository.NewFineRepository(db)
+		userIdentityRepo := repository.NewUserIdentityRepository(db)
+		userSessionRepo := repository.NewUserSessionRepository(db)
+		auditEventRepo := repository.NewAuditEventRepository(db)
+
+		mailer, err := mailers.New()
+		if err != nil {
+			log.Fatalf("Unable to configure mailer: %v", err)
+		}
+
+		tokenService := &services.TokenServices{
+			TokenRepo: apiTokenRepo,
+			UserRepo:  userRepo,
+		}
+
+		sessionService := &services.SessionServices{
+			SessionRepo: userSessionRepo,
+			UserRepo:    userRepo,
+		}
+
+		loginThrottle := &services.LoginThrottleServices{
+			AttemptRepo:   loginAttemptRepository(db),
+			AuditRepo:     auditEventRepo,
+			UserRepo:      userRepo,
+			IdentityRepo:  userIdentityRepo,
+			AccountPolicy: services.DefaultAccountThrottle,
+			AddressPolicy: services.DefaultAddressThrottle,
+		}
+
+		app.Use(SetCurrentUser(sessionService))
+		app.Use(SetTokenUser(tokenService))
+		app.Use(VerifyCSRF)
 
 		userService := &services.UserServices{
-			UserRepo: userRepo,
-			BookRepo: bookRepo,
-			LoanRepo: loanRepo,
+			UserRepo:                userRepo,
+			BookRepo:                bookRepo,
+			LoanRepo:                loanRepo,
+			FineRepo:                fineRepo,
+			IdentityRepo:            userIdentityRepo,
+			FineBlockThresholdCents: fineBlockThresholdCents(),
+		}
+		verificationService := &services.VerificationServices{
+			UserRepo:  userRepo,
+			TokenRepo: userTokenRepo,
+			Mailer:    mailer,
+			BaseURL:   envy.Get("APP_URL", "http://127.0.0.1:3000"),
+		}
+		passwordResetService := &services.PasswordResetServices{
+			UserRepo:  userRepo,
+			TokenRepo: userTokenRepo,
+			Mailer:    mailer,
+			BaseURL:   envy.Get("APP_URL", "http://127.0.0.1:3000"),
+		}
+		exportService := &services.ExportServices{
+			UserRepo:  userRepo,
+			LoanRepo:  loanRepo,
+			BookRepo:  bookRepo,
+			FineRepo:  fineRepo,
+			TokenRepo: apiTokenRepo,
 		}
 		bookService := &services.BookServices{
-			BookRepo: bookRepo,
+			BookRepo:   bookRepo,
+			StatusRepo: bookStatusRepo,
+			LoanRepo:   loanRepo,
 		}
 
-		userController := controllers.NewUserController(userService, sessionStore)
+		userController := controllers.NewUserController(userService, verificationService, sessionService, loginThrottle, sessionStore)
 		bookController := controllers.NewBookController(bookService)
+		graphQLController := controllers.NewGraphQLController(graph.New(bookService, userService))
+		grpcServer = rpc.NewServer(bookService, userService, tokenService)
+		tokenController := controllers.NewTokenController(tokenService)
+		passwordController := controllers.NewPasswordController(passwordResetService)
+		exportController := controllers.NewExportController(exportService)
+		sessionController := controllers.NewSessionController(sessionService)
+		twoFactorController := controllers.NewTwoFactorController(&services.TwoFactorServices{
+			UserRepo:  userRepo,
+			TokenRepo: userTokenRepo,
+			Issuer:    envy.Get("TWO_FACTOR_ISSUER", "Library System"),
+		}, sessionService, loginThrottle)
 
 		bookGroup := app.Group("/books")
-		bookGroup.GET("/", bookController.GetAllBooks)
-		bookGroup.OPTIONS("/", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		bookGroup.POST("/add", bookController.AddBook)
-		bookGroup.OPTIONS("/add", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		bookGroup.DELETE("/remove/{id}", bookController.RemoveBook)
-		bookGroup.OPTIONS("/remove/{id}", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		bookGroup.GET("/search", bookController.SearchBook)
-		bookGroup.OPTIONS("/search", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		bookGroup.PUT("/update", bookController.UpdateBook)
-		bookGroup.OPTIONS("/update", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		bookGroup.GET("/getBookById/{id}", bookController.GetBookByID)
-		bookGroup.OPTIONS("/getBookById/{id}", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
+		bookGroup.GET("/", Deprecated("/api/v1/books")(bookController.GetAllBooks))
+		bookGroup.GET("/search", Deprecated("/api/v1/books")(bookController.SearchBook))
+		bookGroup.GET("/getBookById/{id}", Deprecated("/api/v1/books/{id}")(bookController.GetBookByID))
+		bookGroup.GET("/statusHistory/{id}", Deprecated("/api/v1/books/{id}/statusHistory")(bookController.GetBookStatusHistory))
+
+		catalogueGroup := bookGroup.Group("/")
+		catalogueGroup.Use(RequireRole(models.RoleLibrarian, models.RoleAdmin))
+		catalogueGroup.Use(RequireScope(models.ScopeBooksWrite))
+		catalogueGroup.Use(RequireTwoFactor)
+		catalogueGroup.POST("/add", Deprecated("/api/v1/books")(bookController.AddBook))
+		catalogueGroup.DELETE("/remove/{id}", Deprecated("/api/v1/books/{id}")(bookController.RemoveBook))
+		catalogueGroup.PUT("/update", Deprecated("/api/v1/books")(bookController.UpdateBook))
+		catalogueGroup.PUT("/status/{id}", Deprecated("/api/v1/books/{id}/status")(bookController.ChangeBookStatus))
 
 		userGroup := app.Group("/users")
 		userGroup.POST("/register", userController.RegisterUser)
-		userGroup.OPTIONS("/register", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
+
+		userGroup.GET("/verify", userController.VerifyEmail)
+		userGroup.POST("/verify/resend", userController.ResendVerification)
+		userGroup.POST("/password/forgot", passwordController.ForgotPassword)
+		userGroup.POST("/password/reset", passwordController.ResetPassword)
+		if oidcConfig, ok := auth.OIDCConfigFromEnv(); ok {
+			oidcProvider, err := auth.NewOIDCProvider(context.Background(), oidcConfig)
+			if err != nil {
+				log.Fatalf("Unable to configure single sign-on: %v", err)
+			}
+			ssoController := controllers.NewSSOController(oidcProvider, &services.SSOServices{
+				UserRepo:     userRepo,
+				IdentityRepo: userIdentityRepo,
+			}, sessionService)
+			userGroup.GET("/oidc/login", ssoController.StartLogin)
+			userGroup.GET("/oidc/callback", ssoController.Callback)
+		}
+		if ldapConfig, ok := auth.LDAPConfigFromEnv(); ok {
+			ldapAuthenticator, err := auth.NewLDAPAuthenticator(ldapConfig)
+			if err != nil {
+				log.Fatalf("Unable to configure directory sign-in: %v", err)
+			}
+			directoryController := controllers.NewDirectoryController(ldapAuthenticator, &services.SSOServices{
+				UserRepo:     userRepo,
+				IdentityRepo: userIdentityRepo,
+			}, sessionService, loginThrottle)
+			userGroup.POST("/ldap/login", directoryController.Login)
+		}
+
+		userGroup.GET("/csrf", controllers.GetCSRFToken)
+
+		userGroup.POST("/login", userController.Login)
+		userGroup.POST("/login/twoFactor", twoFactorController.CompleteLogin)
+		userGroup.POST("/logout", userController.Logout)
 
 		protectedGroup := userGroup.Group("/")
-		//protectedGroup.Use(Authorize)
-		protectedGroup.POST("/checkout", userController.CheckoutBook)
-		protectedGroup.OPTIONS("/checkout", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		protectedGroup.POST("/return", userController.ReturnBook)
-		protectedGroup.OPTIONS("/return", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
-		protectedGroup.POST("/reserve", userController.ReserveBook)
-		protectedGroup.OPTIONS("/reserve", func(c buffalo.Context) error {
-			c.Response().WriteHeader(http.StatusOK)
-			return nil
-		})
+		protectedGroup.Use(Authorize)
+		protectedGroup.Use(RequireScope(models.ScopeLoansWrite))
+		protectedGroup.POST("/checkout", Deprecated("/api/v1/loans")(userController.CheckoutBook))
+		protectedGroup.POST("/return", Deprecated("/api/v1/loans")(userController.ReturnBook))
+		protectedGroup.POST("/reserve", Deprecated("/api/v1/holds")(userController.ReserveBook))
+
+		profileGroup := userGroup.Group("/me")
+		profileGroup.Use(Authorize)
+		profileGroup.Use(SessionOnly)
+		profileGroup.GET("/", userController.GetProfile)
+		profileGroup.PUT("/", userController.UpdateProfile)
+		profileGroup.DELETE("/", userController.CloseAccount)
+		profileGroup.GET("/export", exportController.ExportMyData)
+		profileGroup.POST("/twoFactor", twoFactorController.BeginEnrolment)
+		profileGroup.DELETE("/twoFactor", twoFactorController.Disable)
+		profileGroup.POST("/twoFactor/confirm", twoFactorController.ConfirmEnrolment)
+		profileGroup.POST("/twoFactor/recoveryCodes", twoFactorController.RegenerateRecoveryCodes)
+		profileGroup.GET("/sessions", sessionController.ListMySessions)
+		profileGroup.DELETE("/sessions", sessionController.RevokeOtherSessions)
+		profileGroup.DELETE("/sessions/{id}", sessionController.RevokeSession)
+
+		tokenGroup := userGroup.Group("/tokens")
+		tokenGroup.Use(Authorize)
+		tokenGroup.Use(SessionOnly)
+		tokenGroup.GET("/", tokenController.ListTokens)
+		tokenGroup.POST("/", tokenController.CreateToken)
+		tokenGroup.DELETE("/{id}", tokenController.RevokeToken)
+
+		deskGroup := userGroup.Group("/")
+		deskGroup.Use(RequireRole(models.RoleLibrarian, models.RoleAdmin))
+		deskGroup.Use(RequireScope(models.ScopeLoansWrite))
+		deskGroup.Use(RequireTwoFactor)
+		deskGroup.PUT("/suspension/{id}", userController.SuspendUser)
+		deskGroup.DELETE("/suspension/{id}", userController.ReinstateUser)
+		deskGroup.PUT("/patronType/{id}", userController.ChangePatronType)
+		deskGroup.GET("/", userController.ListUsers)
+		// Registered after the fixed /users paths so they take precedence.
+		deskGroup.GET("/{id}", userController.GetUserDetail)
+
+		adminGroup := userGroup.Group("/")
+		adminGroup.Use(RequireRole(models.RoleAdmin))
+		adminGroup.Use(RequireScope(models.ScopeUsersAdmin))
+		adminGroup.Use(RequireTwoFactor)
+		adminGroup.PUT("/role/{id}", userController.ChangeUserRole)
+		adminGroup.GET("/export/{id}", exportController.ExportUserData)
+		adminGroup.DELETE("/lockout/{id}", userController.UnlockUser)
+		adminGroup.GET("/sessions/{id}", sessionController.ListUserSessions)
+		adminGroup.DELETE("/sessions/{id}", sessionController.ForceLogout)
+
+		// Resource-oriented API. The RPC-style /books and /users routes above still work
+		// but answer with Deprecation headers pointing here.
+		apiGroup := app.Group("/api/v1")
+
+		apiBookGroup := apiGroup.Group("/books")
+		apiBookGroup.GET("/", bookController.ListBooks)
+		apiBookGroup.GET("/{id}", bookController.GetBookByID)
+		apiBookGroup.GET("/{id}/statusHistory", bookController.GetBookStatusHistory)
+
+		apiCatalogueGroup := apiBookGroup.Group("/")
+		apiCatalogueGroup.Use(RequireRole(models.RoleLibrarian, models.RoleAdmin))
+		apiCatalogueGroup.Use(RequireScope(models.ScopeBooksWrite))
+		apiCatalogueGroup.Use(RequireTwoFactor)
+		apiCatalogueGroup.POST("/", bookController.AddBook)
+		apiCatalogueGroup.PATCH("/{id}", bookController.PatchBook)
+		apiCatalogueGroup.DELETE("/{id}", bookController.RemoveBook)
+		apiCatalogueGroup.PUT("/{id}/status", bookController.ChangeBookStatus)
+
+		apiLoanGroup := apiGroup.Group("/loans")
+		apiLoanGroup.Use(Authorize)
+		apiLoanGroup.Use(RequireScope(models.ScopeLoansWrite))
+		apiLoanGroup.GET("/", userController.ListLoans)
+		apiLoanGroup.POST("/", userController.CheckoutBook)
+		apiLoanGroup.DELETE("/{id}", userController.ReturnLoan)
+
+		apiHoldGroup := apiGroup.Group("/holds")
+		apiHoldGroup.Use(Authorize)
+		apiHoldGroup.Use(RequireScope(models.ScopeLoansWrite))
+		apiHoldGroup.GET("/", userController.ListHolds)
+		apiHoldGroup.POST("/", userController.ReserveBook)
+
+		app.POST("/graphql", graphQLController.Query)
+
+		// Registered ahead of the file server, which otherwise answers every
+		// unmatched path under "/".
+		app.GET("/openapi.json", OpenAPIHandler)
+		app.GET("/docs", APIDocsHandler)
+		app.GET("/reset-password", ResetPasswordHandler)
+		app.GET("/two-factor", TwoFactorHandler)
 
 		app.ServeFiles("/", packr.New("public", "../public"))
 		app.GET("/", HomeHandler)
@@ -142,3 +356,9 @@ func App() *buffalo.App {
 
 	return app
 }
+
+// GRPCServer returns the gRPC server built alongside the HTTP app, sharing its services.
+func GRPCServer() *grpc.Server {
+	App()
+	return grpcServer
+}
@@ -0,0 +1,261 @@
+package actions
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/envy"
+)
+
+// CORSPolicy decides which cross-origin callers may use a set of routes. An allowed
+// origin is either exact, such as "https://catalogue.example.edu", a pattern with one
+// "*" standing for any subdomain, such as "https://*.example.edu", or "*" for any origin.
+type CORSPolicy struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORSConfig is the default policy plus overrides keyed by path prefix. The longest
+// matching prefix wins, so "/api/v1/books" can differ from the rest of "/api/v1".
+type CORSConfig struct {
+	Default CORSPolicy
+	Routes  map[string]CORSPolicy
+}
+
+// DefaultCORSPolicy is used for anything CORS_* does not set. The origin is the one the
+// bundled front end is served from during development.
+var DefaultCORSPolicy = CORSPolicy{
+	AllowedOrigins:   []string{"http://localhost:63342"},
+	AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete},
+	AllowedHeaders:   []string{"Content-Type", "Authorization", "X-CSRF-Token"},
+	ExposedHeaders:   []string{"X-CSRF-Token", "X-Request-ID", "Deprecation", "Link", "Retry-After"},
+	AllowCredentials: true,
+	MaxAge:           5 * time.Minute,
+}
+
+// CORSConfigFromEnv reads the policy from CORS_ALLOWED_ORIGINS, CORS_ALLOW_CREDENTIALS,
+// CORS_MAX_AGE and CORS_ROUTE_ORIGINS. Route overrides replace only the allowed origins.
+func CORSConfigFromEnv() (CORSConfig, error) {
+	policy := DefaultCORSPolicy
+	if raw := envy.Get("CORS_ALLOWED_ORIGINS", ""); raw != "" {
+		policy.AllowedOrigins = splitList(raw)
+	}
+	if raw := envy.Get("CORS_ALLOW_CREDENTIALS", ""); raw != "" {
+		allow, err := strconv.ParseBool(raw)
+		if err != nil {
+			return CORSConfig{}, fmt.Errorf("invalid CORS_ALLOW_CREDENTIALS %q", raw)
+		}
+		policy.AllowCredentials = allow
+	}
+	if raw := envy.Get("CORS_MAX_AGE", ""); raw != "" {
+		seconds, err := strconv.Atoi(raw)
+		if err != nil || seconds < 0 {
+			return CORSConfig{}, fmt.Errorf("invalid CORS_MAX_AGE %q", raw)
+		}
+		policy.MaxAge = time.Duration(seconds) * time.Second
+	}
+
+	config := CORSConfig{Default: policy, Routes: map[string]CORSPolicy{}}
+	for _, entry := range strings.Split(envy.Get("CORS_ROUTE_ORIGINS", ""), ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		prefix, origins, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimSpace(prefix)
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return CORSConfig{}, fmt.Errorf("invalid CORS_ROUTE_ORIGINS entry %q", entry)
+		}
+		override := policy
+		override.AllowedOrigins = splitList(origins)
+		config.Routes[prefix] = override
+	}
+
+	if err := config.validate(); err != nil {
+		return CORSConfig{}, err
+	}
+	return config, nil
+}
+
+func (config CORSConfig) validate() error {
+	policies := map[string]CORSPolicy{"default": config.Default}
+	for prefix, policy := range config.Routes {
+		policies[prefix] = policy
+	}
+	for name, policy := range policies {
+		for _, origin := range policy.AllowedOrigins {
+			if origin == "*" {
+				// Echoing any origin with credentials would let every site act as the user.
+				if policy.AllowCredentials {
+					return fmt.Errorf("CORS policy for %s allows any origin with credentials", name)
+				}
+				continue
+			}
+			if strings.Count(origin, "*") > 1 || !strings.Contains(origin, "://") {
+				return fmt.Errorf("invalid CORS origin %q for %s", origin, name)
+			}
+			// A wildcard must sit at the start of the host and be followed by a fixed
+			// domain, otherwise "https://*" would admit every https site with credentials.
+			if before, after, ok := strings.Cut(origin, "*"); ok &&
+				(!strings.HasSuffix(before, "://") || !strings.HasPrefix(after, ".") || strings.Trim(after, ".") == "") {
+				return fmt.Errorf("invalid CORS origin %q for %s", origin, name)
+			}
+		}
+	}
+	return nil
+}
+
+func splitList(raw string) []string {
+	var items []string
+	for _, item := range strings.FieldsFunc(raw, func(r rune) bool { return r == ',' || r == ' ' }) {
+		items = append(items, strings.TrimSuffix(item, "/"))
+	}
+	return items
+}
+
+// policyFor returns the policy of the longest route prefix that covers path.
+func (config CORSConfig) policyFor(path string) CORSPolicy {
+	prefixes := make([]string, 0, len(config.Routes))
+	for prefix := range config.Routes {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Slice(prefixes, func(i, j int) bool { return len(prefixes[i]) > len(prefixes[j]) })
+
+	for _, prefix := range prefixes {
+		trimmed := strings.TrimSuffix(prefix, "/")
+		if path == trimmed || strings.HasPrefix(path, trimmed+"/") {
+			return config.Routes[prefix]
+		}
+	}
+	return config.Default
+}
+
+// allowOrigin reports whether origin may call routes under policy, and the value to
+// send back as Access-Control-Allow-Origin.
+func (policy CORSPolicy) allowOrigin(origin string) (string, bool) {
+	origin = strings.ToLower(origin)
+	for _, allowed := range policy.AllowedOrigins {
+		allowed = strings.ToLower(allowed)
+		if allowed == "*" {
+			return "*", true
+		}
+		if allowed == origin {
+			return origin, true
+		}
+		if before, after, ok := strings.Cut(allowed, "*"); ok &&
+			strings.HasPrefix(origin, before) && strings.HasSuffix(origin, after) &&
+			len(origin) > len(before)+len(after) && isSubdomain(origin[len(before):len(origin)-len(after)]) {
+			return origin, true
+		}
+	}
+	return "", false
+}
+
+// isSubdomain keeps a wildcard from matching anything but host labels, so
+// "https://*.example.edu" does not admit "https://evil.test/.example.edu".
+func isSubdomain(labels string) bool {
+	for _, r := range labels {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-' || r == '.') {
+			return false
+		}
+	}
+	return !strings.HasPrefix(labels, ".")
+}
+
+func (policy CORSPolicy) allowsMethod(method string) bool {
+	for _, allowed := range policy.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func (policy CORSPolicy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = strings.TrimSpace(header)
+		if header == "" {
+			continue
+		}
+		allowed := false
+		for _, candidate := range policy.AllowedHeaders {
+			if strings.EqualFold(candidate, header) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+	return true
+}
+
+// CORS applies config ahead of routing. It answers preflight requests itself, so
+// routes need no OPTIONS handlers, and adds the CORS headers to every other response
+// before the router sees the request.
+func CORS(config CORSConfig) buffalo.PreWare {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := w.Header()
+			// Responses differ by Origin whether or not it is allowed, so caches must
+			// key on it either way.
+			header.Add("Vary", "Origin")
+
+			preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+			if preflight {
+				header.Add("Vary", "Access-Control-Request-Method")
+				header.Add("Vary", "Access-Control-Request-Headers")
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			policy := config.policyFor(r.URL.Path)
+			allowOrigin, ok := policy.allowOrigin(origin)
+
+			if !preflight {
+				if ok {
+					setCORSOrigin(header, policy, allowOrigin)
+					if len(policy.ExposedHeaders) > 0 {
+						header.Set("Access-Control-Expose-Headers", strings.Join(policy.ExposedHeaders, ", "))
+					}
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// A refused preflight gets no CORS headers, which is how the browser learns
+			// that the real request must not be sent.
+			if ok && policy.allowsMethod(r.Header.Get("Access-Control-Request-Method")) &&
+				policy.allowsHeaders(r.Header.Get("Access-Control-Request-Headers")) {
+				setCORSOrigin(header, policy, allowOrigin)
+				header.Set("Access-Control-Allow-Methods", strings.Join(policy.AllowedMethods, ", "))
+				if len(policy.AllowedHeaders) > 0 {
+					header.Set("Access-Control-Allow-Headers", strings.Join(policy.AllowedHeaders, ", "))
+				}
+				if policy.MaxAge > 0 {
+					header.Set("Access-Control-Max-Age", strconv.Itoa(int(policy.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
+
+func setCORSOrigin(header http.Header, policy CORSPolicy, allowOrigin string) {
+	header.Set("Access-Control-Allow-Origin", allowOrigin)
+	if policy.AllowCredentials && allowOrigin != "*" {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
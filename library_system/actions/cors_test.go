@@ -0,0 +1,133 @@
+package actions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gobuffalo/envy"
+	"github.com/stretchr/testify/assert"
+)
+
+// corsTestApp registers no OPTIONS routes, so every preflight answer comes from CORS.
+func corsTestApp(config CORSConfig) *buffalo.App {
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.PreWares = append(app.PreWares, CORS(config))
+	ok := func(c buffalo.Context) error {
+		return c.Render(http.StatusOK, render.String("ok"))
+	}
+	app.GET("/api/v1/books", ok)
+	app.POST("/api/v1/loans", ok)
+	app.POST("/graphql", ok)
+	return app
+}
+
+func corsRequest(app http.Handler, method, path, origin string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	res := httptest.NewRecorder()
+	app.ServeHTTP(res, req)
+	return res
+}
+
+func TestCORSPreflight(t *testing.T) {
+	config := CORSConfig{Default: DefaultCORSPolicy}
+	config.Default.AllowedOrigins = []string{"https://catalogue.example.edu", "https://*.library.example.edu"}
+	app := corsTestApp(config)
+	preflight := map[string]string{"Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "content-type, x-csrf-token"}
+
+	res := corsRequest(app, http.MethodOptions, "/api/v1/loans", "https://branch.library.example.edu", preflight)
+	assert.Equal(t, http.StatusNoContent, res.Code)
+	assert.Equal(t, "https://branch.library.example.edu", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", res.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST, PUT, PATCH, DELETE", res.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "300", res.Header().Get("Access-Control-Max-Age"))
+	assert.Equal(t, []string{"Origin", "Access-Control-Request-Method", "Access-Control-Request-Headers"}, res.Header().Values("Vary"))
+
+	refused := []struct {
+		name    string
+		origin  string
+		headers map[string]string
+	}{
+		{"unknown origin", "https://evil.test", preflight},
+		{"wildcard outside host", "https://evil.test/.library.example.edu", preflight},
+		{"method", "https://catalogue.example.edu", map[string]string{"Access-Control-Request-Method": "TRACE"}},
+		{"header", "https://catalogue.example.edu", map[string]string{"Access-Control-Request-Method": "POST", "Access-Control-Request-Headers": "X-Debug"}},
+	}
+	for _, tt := range refused {
+		t.Run(tt.name, func(t *testing.T) {
+			res := corsRequest(app, http.MethodOptions, "/api/v1/loans", tt.origin, tt.headers)
+			assert.Equal(t, http.StatusNoContent, res.Code)
+			assert.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+			assert.Empty(t, res.Header().Get("Access-Control-Allow-Methods"))
+		})
+	}
+}
+
+func TestCORSActualRequest(t *testing.T) {
+	app := corsTestApp(CORSConfig{Default: DefaultCORSPolicy})
+
+	res := corsRequest(app, http.MethodGet, "/api/v1/books", "http://localhost:63342", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "http://localhost:63342", res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, res.Header().Get("Access-Control-Expose-Headers"), "X-CSRF-Token")
+	assert.Equal(t, []string{"Origin"}, res.Header().Values("Vary"))
+
+	res = corsRequest(app, http.MethodGet, "/api/v1/books", "https://evil.test", nil)
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, []string{"Origin"}, res.Header().Values("Vary"))
+}
+
+func TestCORSRouteOverride(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("CORS_ALLOWED_ORIGINS", "https://catalogue.example.edu")
+		envy.Set("CORS_ALLOW_CREDENTIALS", "false")
+		envy.Set("CORS_ROUTE_ORIGINS", "/graphql=*; /api/v1/books=https://catalogue.example.edu https://partner.example.org")
+		config, err := CORSConfigFromEnv()
+		if !assert.NoError(t, err) {
+			return
+		}
+		app := corsTestApp(config)
+
+		res := corsRequest(app, http.MethodPost, "/graphql", "https://anywhere.test", nil)
+		assert.Equal(t, "*", res.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, res.Header().Get("Access-Control-Allow-Credentials"))
+
+		res = corsRequest(app, http.MethodGet, "/api/v1/books/", "https://partner.example.org", nil)
+		assert.Equal(t, "https://partner.example.org", res.Header().Get("Access-Control-Allow-Origin"))
+
+		res = corsRequest(app, http.MethodPost, "/api/v1/loans", "https://partner.example.org", nil)
+		assert.Empty(t, res.Header().Get("Access-Control-Allow-Origin"))
+	})
+}
+
+func TestCORSConfigFromEnvRejectsUnsafePolicies(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("CORS_ALLOWED_ORIGINS", "*")
+		_, err := CORSConfigFromEnv()
+		assert.EqualError(t, err, "CORS policy for default allows any origin with credentials")
+
+		for _, origin := range []string{"https://*", "https://*.", "https://*example.edu", "https://library.*.edu"} {
+			envy.Set("CORS_ALLOWED_ORIGINS", origin)
+			_, err = CORSConfigFromEnv()
+			assert.EqualError(t, err, "invalid CORS origin \""+origin+"\" for default")
+		}
+
+		envy.Set("CORS_ALLOWED_ORIGINS", "*")
+		envy.Set("CORS_ALLOW_CREDENTIALS", "false")
+		_, err = CORSConfigFromEnv()
+		assert.NoError(t, err)
+
+		envy.Set("CORS_ROUTE_ORIGINS", "graphql=*")
+		_, err = CORSConfigFromEnv()
+		assert.Error(t, err)
+	})
+}
@@ -1,11 +1,11 @@
 package actions
 
 import (
+	"fmt"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/pop/v6"
 	"library-system/repositories/repository"
 	"library-system/services"
-	"log"
 	"net/http"
 )
 
@@ -13,8 +13,7 @@ func HomeHandler(c buffalo.Context) error {
 	bookService := services.NewBookServices(repository.NewBookRepository(pop.Connections[ENV]))
 	books, err := bookService.GetAllBooks()
 	if err != nil {
-		log.Println("Error fetching books:", err)
-		return c.Render(http.StatusInternalServerError, r.JSON(map[string]string{"error": "Failed to fetch books"}))
+		return fmt.Errorf("failed to fetch books: %w", err)
 	}
 
 	c.Set("books", books)
@@ -36,3 +35,18 @@ func LibrarianDashboardHandler(c buffalo.Context) error {
 func UserDashboardHandler(c buffalo.Context) error {
 	return c.Render(http.StatusOK, r.HTML("pages/user-dashboard.plush.html"))
 }
+
+// ResetPasswordHandler serves the page the password reset email links to. The page posts
+// the token from the link, with the new password, to /users/password/reset.
+func ResetPasswordHandler(c buffalo.Context) error {
+	c.Set("title", "Reset Your Password")
+	c.Set("token", c.Param("token"))
+	return c.Render(http.StatusOK, r.HTML("account/reset-password.plush.html", "account/layout.plush.html"))
+}
+
+// TwoFactorHandler serves the code entry page that browser sign-ins, such as single
+// sign-on, send users to while their sign-in waits for a second factor.
+func TwoFactorHandler(c buffalo.Context) error {
+	c.Set("title", "Two-Factor Sign-In")
+	return c.Render(http.StatusOK, r.HTML("account/two-factor.plush.html", "account/layout.plush.html"))
+}
@@ -1,6 +1,12 @@
 package actions
 
-import "net/http"
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
 
 func (as *ActionSuite) Test_HomeHandler() {
 	res := as.HTML("/").Get()
@@ -8,3 +14,21 @@ func (as *ActionSuite) Test_HomeHandler() {
 	as.Equal(http.StatusOK, res.Code)
 	as.Contains(res.Body.String(), "Welcome to Buffalo")
 }
+
+func TestResetPasswordPageCarriesToken(t *testing.T) {
+	res := httptest.NewRecorder()
+	App().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/reset-password?token=abc%3Cdef", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), `value="abc&lt;def"`)
+	assert.Contains(t, res.Body.String(), "/users/password/reset")
+}
+
+func TestTwoFactorPagePostsTheCode(t *testing.T) {
+	res := httptest.NewRecorder()
+	App().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/two-factor", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Contains(t, res.Body.String(), "/users/login/twoFactor")
+	assert.Contains(t, res.Body.String(), `name="csrf-token"`)
+}
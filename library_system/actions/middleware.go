@@ -1,68 +1,221 @@
 package actions
 
 import (
+	"crypto/subtle"
+	"fmt"
 	"github.com/gobuffalo/buffalo"
-	"github.com/gobuffalo/buffalo/render"
 	"github.com/gofrs/uuid"
+	"library-system/controllers"
+	"library-system/problem"
+	"library-system/services"
 	"log"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const sessionName = "_library_session"
 
-func SetCurrentUser(next buffalo.Handler) buffalo.Handler {
-	return func(c buffalo.Context) error {
-		session := c.Session()
-		if userIDStr := session.Get("current_user_id"); userIDStr != nil {
-			userID, err := uuid.FromString(userIDStr.(string))
-			if err == nil {
-				c.Set("current_user", userID)
-				log.Printf("User authenticated: %s", userID)
+// SetCurrentUser loads the signed-in user from the session cookie. The cookie names a
+// server-side session, which must still be active; otherwise the cookie is cleared.
+func SetCurrentUser(sessions *services.SessionServices) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			session := c.Session()
+			userIDStr, ok := session.Get("current_user_id").(string)
+			if !ok {
+				return next(c)
+			}
+
+			userID, _ := uuid.FromString(userIDStr)
+			sessionIDStr, _ := session.Get("session_id").(string)
+			sessionID, _ := uuid.FromString(sessionIDStr)
+			user, record, err := sessions.Authenticate(sessionID, userID)
+			if err != nil {
+				log.Printf("Discarding invalid session for user: %s", userIDStr)
+				session.Clear()
+				session.Save()
+				return next(c)
+			}
+
+			c.Set("current_user_id", user.ID)
+			c.Set("current_user", user)
+			c.Set("current_session_id", record.ID)
+			log.Printf("User authenticated: %s", user.ID)
+			return next(c)
+		}
+	}
+}
+
+// SetTokenUser authenticates programmatic clients that send an
+// "Authorization: Bearer <token>" header. It runs after SetCurrentUser and takes
+// precedence over the session; a bearer token that does not check out is rejected
+// outright rather than silently falling back to anonymous access.
+func SetTokenUser(tokens *services.TokenServices) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			raw, ok := bearerToken(c.Request())
+			if !ok {
+				return next(c)
+			}
+
+			user, token, err := tokens.Authenticate(raw)
+			if err != nil {
+				c.Response().Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				return problem.New(http.StatusUnauthorized, "Invalid or expired token")
 			}
+
+			c.Set("current_user_id", user.ID)
+			c.Set("current_user", user)
+			c.Set("current_token", token)
+			return next(c)
+		}
+	}
+}
+
+// RequireScope checks the scope of token-authenticated requests. Session requests are
+// governed by the user's role alone and pass straight through.
+func RequireScope(scope string) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			if token := controllers.CurrentToken(c); token != nil && !token.HasScope(scope) {
+				return problem.New(http.StatusForbidden, "Token is missing the "+scope+" scope")
+			}
+			return next(c)
+		}
+	}
+}
+
+// SessionOnly refuses token-authenticated requests, e.g. so a token cannot mint more tokens.
+func SessionOnly(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if controllers.CurrentToken(c) != nil {
+			return problem.New(http.StatusForbidden, "This action requires an interactive session")
 		}
 		return next(c)
 	}
 }
 
+func bearerToken(req *http.Request) (string, bool) {
+	header := req.Header.Get("Authorization")
+	if len(header) < 7 || !strings.EqualFold(header[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(header[7:])
+	return token, token != ""
+}
+
 func Authorize(next buffalo.Handler) buffalo.Handler {
 	return func(c buffalo.Context) error {
-		session := c.Session()
-		userID := session.Get("current_user_id")
+		if controllers.CurrentUser(c) == nil {
+			return problem.New(http.StatusUnauthorized, "Authentication required")
+		}
+		return next(c)
+	}
+}
 
-		if userID == nil {
-			return c.Render(http.StatusUnauthorized, render.JSON(map[string]string{
-				"error": "Authentication required",
-			}))
+// RequireRole only lets the request through when the signed-in user holds one of the given roles.
+func RequireRole(roles ...string) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			user := controllers.CurrentUser(c)
+			if user == nil {
+				return problem.New(http.StatusUnauthorized, "Authentication required")
+			}
+			if !user.HasRole(roles...) {
+				return problem.New(http.StatusForbidden, "You do not have permission to perform this action")
+			}
+			return next(c)
 		}
+	}
+}
 
-		c.Set("current_user", userID)
+// RequireTwoFactor keeps staff who have not enabled two-factor authentication out of
+// privileged endpoints. They can still sign in and enrol from their profile.
+func RequireTwoFactor(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if user := controllers.CurrentUser(c); user != nil && user.RequiresTwoFactor() && !user.TwoFactorEnabled() {
+			return &problem.Error{
+				Status: http.StatusForbidden,
+				Type:   problem.TypeTwoFactorRequired,
+				Detail: "Two-factor authentication must be enabled for your role",
+				Code:   "two_factor_required",
+			}
+		}
 		return next(c)
 	}
 }
 
-func SecurityHeaders(next buffalo.Handler) buffalo.Handler {
+// VerifyCSRF stops other sites from riding on the session cookie. Every browser
+// session gets a token, sent back on each response in the X-CSRF-Token header, and
+// state-changing requests from a signed-in session must echo it in that header or the
+// authenticity_token form field. Bearer-token clients send no cookie and are skipped.
+func VerifyCSRF(next buffalo.Handler) buffalo.Handler {
 	return func(c buffalo.Context) error {
-		c.Response().Header().Set("X-Frame-Options", "DENY")
-		c.Response().Header().Set("X-Content-Type-Options", "nosniff")
-		c.Response().Header().Set("X-XSS-Protection", "1; mode=block")
+		if controllers.CurrentToken(c) != nil {
+			return next(c)
+		}
+
+		token, err := controllers.CSRFToken(c)
+		if err != nil {
+			return fmt.Errorf("failed to issue CSRF token: %w", err)
+		}
+		c.Set(controllers.CSRFFormField, token)
+		c.Response().Header().Set(controllers.CSRFHeader, token)
+
+		switch c.Request().Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return next(c)
+		}
+
+		// Anonymous requests have nothing for a forged request to borrow.
+		if controllers.CurrentUser(c) == nil && !controllers.HasPendingSignIn(c) {
+			return next(c)
+		}
+
+		sent := c.Request().Header.Get(controllers.CSRFHeader)
+		if sent == "" {
+			sent = c.Request().PostFormValue(controllers.CSRFFormField)
+		}
+		if subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+			return &problem.Error{
+				Status: http.StatusForbidden,
+				Type:   problem.TypeCSRF,
+				Detail: "Invalid or missing CSRF token",
+				Code:   "csrf_invalid",
+			}
+		}
 		return next(c)
 	}
 }
 
-func CORS(next buffalo.Handler) buffalo.Handler {
-	return func(c buffalo.Context) error {
-		// Set CORS headers for all responses
-		c.Response().Header().Set("Access-Control-Allow-Origin", "http://localhost:63342")
-		c.Response().Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Response().Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-CSRF-Token")
-		c.Response().Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Response().Header().Set("Access-Control-Max-Age", "300")
-
-		// Handle preflight requests
-		if c.Request().Method == http.MethodOptions {
-			return c.Render(http.StatusOK, render.String(""))
+// legacyAPIDeprecatedAt is when the RPC-style routes were superseded by /api/v1.
+var legacyAPIDeprecatedAt = time.Date(2026, time.October, 19, 0, 0, 0, 0, time.UTC)
+
+// Deprecated marks a legacy route with the Deprecation header (RFC 9745) and links to
+// the /api/v1 route that replaces it. A {id} in the successor is filled from the path.
+func Deprecated(successor string) buffalo.MiddlewareFunc {
+	return func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			link := successor
+			if id := c.Param("id"); id != "" {
+				link = strings.Replace(link, "{id}", url.PathEscape(id), 1)
+			}
+			header := c.Response().Header()
+			header.Set("Deprecation", "@"+strconv.FormatInt(legacyAPIDeprecatedAt.Unix(), 10))
+			header.Set("Link", "<"+link+`>; rel="successor-version"`)
+			return next(c)
 		}
+	}
+}
 
+func SecurityHeaders(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		c.Response().Header().Set("X-Frame-Options", "DENY")
+		c.Response().Header().Set("X-Content-Type-Options", "nosniff")
+		c.Response().Header().Set("X-XSS-Protection", "1; mode=block")
 		return next(c)
 	}
 }
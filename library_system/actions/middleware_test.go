@@ -0,0 +1,181 @@
+package actions
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/problem"
+)
+
+// csrfTestApp runs VerifyCSRF behind a stand-in for SetCurrentUser that trusts a
+// "current_user_id" session value, so the test needs no database.
+func csrfTestApp() *buffalo.App {
+	app := buffalo.New(buffalo.Options{
+		Env:          "test",
+		SessionStore: sessions.NewCookieStore([]byte("csrf-test-secret-0123456789abcdef")),
+		SessionName:  "_library_system_session",
+	})
+	app.Use(RenderProblems)
+	app.Use(func(next buffalo.Handler) buffalo.Handler {
+		return func(c buffalo.Context) error {
+			if id, ok := c.Session().Get("current_user_id").(string); ok {
+				c.Set("current_user", &models.User{Email: id})
+			}
+			if strings.HasPrefix(c.Request().Header.Get("Authorization"), "Bearer ") {
+				c.Set("current_token", &models.APIToken{})
+			}
+			return next(c)
+		}
+	})
+	app.Use(VerifyCSRF)
+
+	ok := func(c buffalo.Context) error {
+		return c.Render(http.StatusOK, render.String("ok"))
+	}
+	app.POST("/login", func(c buffalo.Context) error {
+		c.Session().Set("current_user_id", "meenah20@gmail.com")
+		return ok(c)
+	})
+	app.GET("/books", ok)
+	app.POST("/checkout", ok)
+	return app
+}
+
+func TestVerifyCSRF(t *testing.T) {
+	server := httptest.NewServer(csrfTestApp())
+	defer server.Close()
+	jar, _ := cookiejar.New(nil)
+	client := &http.Client{Jar: jar}
+
+	post := func(path, token, authorization string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader("{}"))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("X-CSRF-Token", token)
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		res, err := client.Do(req)
+		assert.NoError(t, err)
+		res.Body.Close()
+		return res
+	}
+
+	// Anonymous mutations such as logging in need no token.
+	res := post("/login", "", "")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	res, err := client.Get(server.URL + "/books")
+	assert.NoError(t, err)
+	res.Body.Close()
+	token := res.Header.Get("X-CSRF-Token")
+	assert.NotEmpty(t, token)
+
+	res = post("/checkout", "", "")
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+	assert.Equal(t, "application/problem+json", res.Header.Get("Content-Type"))
+	assert.Equal(t, http.StatusForbidden, post("/checkout", "forged", "").StatusCode)
+	assert.Equal(t, http.StatusOK, post("/checkout", token, "").StatusCode)
+	assert.Equal(t, http.StatusOK, post("/checkout", "", "Bearer lib_example").StatusCode)
+}
+
+func TestDeprecated(t *testing.T) {
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	ok := func(c buffalo.Context) error {
+		return c.Render(http.StatusOK, render.String("ok"))
+	}
+	app.GET("/books/getBookById/{id}", Deprecated("/api/v1/books/{id}")(ok))
+	app.GET("/books/search", Deprecated("/api/v1/books")(ok))
+	app.GET("/api/v1/books", ok)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		return res
+	}
+
+	res := get("/books/getBookById/42")
+	assert.Equal(t, http.StatusOK, res.Code)
+	assert.Equal(t, "@1792368000", res.Header().Get("Deprecation"))
+	assert.Equal(t, `</api/v1/books/42>; rel="successor-version"`, res.Header().Get("Link"))
+
+	res = get("/books/search?query=dune")
+	assert.Equal(t, `</api/v1/books>; rel="successor-version"`, res.Header().Get("Link"))
+
+	res = get("/api/v1/books")
+	assert.Empty(t, res.Header().Get("Deprecation"))
+}
+
+func TestRenderProblems(t *testing.T) {
+	app := buffalo.New(buffalo.Options{Env: "test"})
+	app.Use(RenderProblems)
+	app.GET("/books/{id}", func(c buffalo.Context) error {
+		return apperrors.NotFound("book not found")
+	})
+	app.GET("/broken", func(c buffalo.Context) error {
+		return errors.New("connection refused")
+	})
+
+	get := func(path string) (*httptest.ResponseRecorder, problem.Details) {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest(http.MethodGet, path, nil))
+		var details problem.Details
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &details))
+		return res, details
+	}
+
+	res, details := get("/books/42")
+	assert.Equal(t, http.StatusNotFound, res.Code)
+	assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"))
+	assert.Equal(t, "/problems/not-found", details.Type)
+	assert.Equal(t, "Not found", details.Title)
+	assert.Equal(t, http.StatusNotFound, details.Status)
+	assert.Equal(t, "book not found", details.Detail)
+	assert.Equal(t, "/books/42/", details.Instance)
+	assert.NotEmpty(t, details.RequestID)
+	assert.Equal(t, details.RequestID, res.Header().Get("X-Request-ID"))
+
+	res, details = get("/broken")
+	assert.Equal(t, http.StatusInternalServerError, res.Code)
+	assert.Equal(t, "An unexpected error occurred", details.Detail)
+}
+
+func TestRoutingErrorsAreProblems(t *testing.T) {
+	app := buffalo.New(buffalo.Options{Env: "development"})
+	app.Use(RenderProblems)
+	HandleErrorsAsProblems(app)
+	app.GET("/books", func(c buffalo.Context) error {
+		return c.Render(http.StatusOK, render.String("ok"))
+	})
+
+	for _, tt := range []struct {
+		method, path string
+		status       int
+		typ          string
+	}{
+		{http.MethodGet, "/nowhere", http.StatusNotFound, "/problems/not-found"},
+		{http.MethodDelete, "/books", http.StatusMethodNotAllowed, "/problems/method-not-allowed"},
+	} {
+		res := httptest.NewRecorder()
+		app.ServeHTTP(res, httptest.NewRequest(tt.method, tt.path, nil))
+
+		assert.Equal(t, tt.status, res.Code)
+		assert.Equal(t, "application/problem+json", res.Header().Get("Content-Type"))
+		var details problem.Details
+		assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &details))
+		assert.Equal(t, tt.typ, details.Type)
+		assert.Equal(t, tt.status, details.Status)
+	}
+}
@@ -0,0 +1,142 @@
+package actions
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"library-system/Dto"
+	"library-system/openapi"
+	"net/http"
+	"sync"
+)
+
+var (
+	apiSpec     *openapi.Spec
+	apiSpecOnce sync.Once
+)
+
+// APISpec describes every route registered in App. TestOpenAPICoversRoutes fails when a
+// route is added without an entry here.
+func APISpec() *openapi.Spec {
+	apiSpecOnce.Do(func() {
+		spec := openapi.New(openapi.Info{
+			Title:   "Library System API",
+			Version: "1.0.0",
+			Description: "Browser clients authenticate with the session cookie set by login and must send the " +
+				"X-CSRF-Token header on state-changing requests. Programmatic clients send an API token as " +
+				"\"Authorization: Bearer <token>\"; token routes also need the matching scope.",
+		})
+		spec.SecurityScheme("session", &openapi.SecurityScheme{Type: "apiKey", In: "cookie", Name: "_library_system_session"})
+		spec.SecurityScheme("bearerToken", &openapi.SecurityScheme{Type: "http", Scheme: "bearer"})
+
+		for _, route := range apiRoutes() {
+			spec.Add(route)
+		}
+		apiSpec = spec
+	})
+	return apiSpec
+}
+
+func apiRoutes() []openapi.Route {
+	// Example values for the envelope fields most handlers render.
+	status := "success"
+	message := "Human-readable outcome"
+	ok := openapi.Object{"status": status}
+	bookList := []Dto.BookResponse{}
+	bookQuery := map[string]string{"q": "Matches title, author or ISBN; lists every book when empty"}
+
+	return []openapi.Route{
+		// Catalogue
+		{Method: "GET", Path: "/api/v1/books", Tag: "Books", Summary: "List or search the catalogue", Query: bookQuery, Response: bookList},
+		{Method: "POST", Path: "/api/v1/books", Tag: "Books", Summary: "Add a book", Request: Dto.BookRequest{}, Status: http.StatusCreated, Response: Dto.BookResponse{}, Secured: true},
+		{Method: "GET", Path: "/api/v1/books/{id}", Tag: "Books", Summary: "Show a book", Response: Dto.BookResponse{}},
+		{Method: "PATCH", Path: "/api/v1/books/{id}", Tag: "Books", Summary: "Change a book's title or author", Request: Dto.BookPatchRequest{}, Response: Dto.BookResponse{}, Secured: true},
+		{Method: "DELETE", Path: "/api/v1/books/{id}", Tag: "Books", Summary: "Remove a book", Response: Dto.BookResponse{}, Secured: true},
+		{Method: "PUT", Path: "/api/v1/books/{id}/status", Tag: "Books", Summary: "Change a book's status with a reason", Description: "Borrowed and reserved are set by checkouts and holds only, and a book with an open loan or hold cannot be moved off them.", Request: Dto.BookStatusRequest{}, Response: Dto.BookResponse{}, Secured: true},
+		{Method: "GET", Path: "/api/v1/books/{id}/statusHistory", Tag: "Books", Summary: "List a book's status changes", Response: []Dto.BookStatusChangeResponse{}},
+
+		// Loans and holds
+		{Method: "GET", Path: "/api/v1/loans", Tag: "Loans", Summary: "List your loans", Response: openapi.Object{"status": status, "loans": []Dto.LoanSummary{}}, Secured: true},
+		{Method: "POST", Path: "/api/v1/loans", Tag: "Loans", Summary: "Check a book out", Request: Dto.BookActionRequest{}, Response: openapi.Object{"status": status, "checkout": Dto.BookActionResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/api/v1/loans/{id}", Tag: "Loans", Summary: "Return the book on a loan", Response: openapi.Object{"status": status, "return": Dto.BookActionResponse{}}, Secured: true},
+		{Method: "GET", Path: "/api/v1/holds", Tag: "Loans", Summary: "List your holds", Response: openapi.Object{"status": status, "holds": []Dto.LoanSummary{}}, Secured: true},
+		{Method: "POST", Path: "/api/v1/holds", Tag: "Loans", Summary: "Place a hold on a book", Request: Dto.BookActionRequest{}, Response: openapi.Object{"status": status, "reservation": Dto.BookActionResponse{}}, Secured: true},
+
+		// GraphQL
+		{Method: "POST", Path: "/graphql", Tag: "GraphQL", Summary: "Run a GraphQL query or mutation", Description: "The schema is in graph/schema.graphql and can be introspected. Anonymous requests may read the catalogue; mutations need a session or a token with the loans:write scope.", Request: Dto.GraphQLRequest{}, Response: Dto.GraphQLResponse{}},
+
+		// Deprecated RPC-style routes
+		{Method: "GET", Path: "/books", Tag: "Deprecated", Summary: "List the catalogue; use GET /api/v1/books", Response: bookList, Deprecated: true},
+		{Method: "GET", Path: "/books/search", Tag: "Deprecated", Summary: "Search the catalogue; use GET /api/v1/books?q=", Query: map[string]string{"query": "Matches title, author or ISBN"}, Response: bookList, Deprecated: true},
+		{Method: "GET", Path: "/books/getBookById/{id}", Tag: "Deprecated", Summary: "Show a book; use GET /api/v1/books/{id}", Response: Dto.BookResponse{}, Deprecated: true},
+		{Method: "GET", Path: "/books/statusHistory/{id}", Tag: "Deprecated", Summary: "List a book's status changes; use GET /api/v1/books/{id}/statusHistory", Response: []Dto.BookStatusChangeResponse{}, Deprecated: true},
+		{Method: "POST", Path: "/books/add", Tag: "Deprecated", Summary: "Add a book; use POST /api/v1/books", Request: Dto.BookRequest{}, Status: http.StatusCreated, Response: Dto.BookResponse{}, Secured: true, Deprecated: true},
+		{Method: "DELETE", Path: "/books/remove/{id}", Tag: "Deprecated", Summary: "Remove a book; use DELETE /api/v1/books/{id}", Response: Dto.BookResponse{}, Secured: true, Deprecated: true},
+		{Method: "PUT", Path: "/books/update", Tag: "Deprecated", Summary: "Update the book with the ISBN in the body; use PATCH /api/v1/books/{id}", Description: "The status cannot be changed here; a status other than the current one is rejected.", Request: Dto.BookRequest{}, Response: Dto.BookResponse{}, Secured: true, Deprecated: true},
+		{Method: "PUT", Path: "/books/status/{id}", Tag: "Deprecated", Summary: "Change a book's status; use PUT /api/v1/books/{id}/status", Request: Dto.BookStatusRequest{}, Response: Dto.BookResponse{}, Secured: true, Deprecated: true},
+		{Method: "POST", Path: "/users/checkout", Tag: "Deprecated", Summary: "Check a book out; use POST /api/v1/loans", Request: Dto.BookActionRequest{}, Response: openapi.Object{"status": status, "checkout": Dto.BookActionResponse{}}, Secured: true, Deprecated: true},
+		{Method: "POST", Path: "/users/return", Tag: "Deprecated", Summary: "Return a book; use DELETE /api/v1/loans/{id}", Request: Dto.BookActionRequest{}, Response: openapi.Object{"status": status, "return": Dto.BookActionResponse{}}, Secured: true, Deprecated: true},
+		{Method: "POST", Path: "/users/reserve", Tag: "Deprecated", Summary: "Place a hold; use POST /api/v1/holds", Request: Dto.BookActionRequest{}, Response: openapi.Object{"status": status, "reservation": Dto.BookActionResponse{}}, Secured: true, Deprecated: true},
+
+		// Accounts and sign-in
+		{Method: "POST", Path: "/users/register", Tag: "Accounts", Summary: "Create a patron account and sign in", Request: Dto.UserRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}, "verification_sent": true}},
+		{Method: "GET", Path: "/users/verify", Tag: "Accounts", Summary: "Verify an email address from the emailed link", Query: map[string]string{"token": "Token from the verification email"}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}},
+		{Method: "POST", Path: "/users/verify/resend", Tag: "Accounts", Summary: "Send another verification email", Description: "At most three emails an hour are sent per address; further requests are accepted but ignored.", Request: Dto.EmailRequest{}, Status: http.StatusAccepted, Response: openapi.Object{"status": status, "message": message}},
+		{Method: "POST", Path: "/users/password/forgot", Tag: "Accounts", Summary: "Email a password reset link", Request: Dto.EmailRequest{}, Status: http.StatusAccepted, Response: openapi.Object{"status": status, "message": message}},
+		{Method: "POST", Path: "/users/password/reset", Tag: "Accounts", Summary: "Set a new password with a reset token", Request: Dto.PasswordResetRequest{}, Response: openapi.Object{"status": status, "message": message}},
+		{Method: "GET", Path: "/users/csrf", Tag: "Accounts", Summary: "Fetch the CSRF token for the browser session", Response: openapi.Object{"status": status, "csrf_token": ""}},
+		{Method: "POST", Path: "/users/login", Tag: "Accounts", Summary: "Sign in with email and password", Description: "Answers {\"status\": \"two_factor_required\"} when the account has two-factor authentication; finish with /users/login/twoFactor.", Request: Dto.LoginRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}},
+		{Method: "POST", Path: "/users/login/twoFactor", Tag: "Accounts", Summary: "Finish signing in with a two-factor or recovery code", Request: Dto.TwoFactorCodeRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}},
+		{Method: "POST", Path: "/users/ldap/login", Tag: "Accounts", Summary: "Sign staff in against the directory", Description: "Only registered when LDAP_URL is set.", Request: Dto.DirectoryLoginRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}},
+		{Method: "GET", Path: "/users/oidc/login", Tag: "Accounts", Summary: "Start single sign-on", Description: "Redirects to the identity provider. Only registered when OIDC_ISSUER_URL is set.", Status: http.StatusFound},
+		{Method: "GET", Path: "/users/oidc/callback", Tag: "Accounts", Summary: "Finish single sign-on", Description: "Redirects to the dashboard for the user's role, or to /two-factor when the account has two-factor authentication enabled.", Query: map[string]string{"code": "Authorization code", "state": "State issued by /users/oidc/login"}, Status: http.StatusFound},
+		{Method: "POST", Path: "/users/logout", Tag: "Accounts", Summary: "Sign out", Response: ok},
+
+		// Signed-in user
+		{Method: "GET", Path: "/users/me", Tag: "Profile", Summary: "Show your profile", Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+		{Method: "PUT", Path: "/users/me", Tag: "Profile", Summary: "Change your name or email", Request: Dto.ProfileUpdateRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/me", Tag: "Profile", Summary: "Close your account", Response: openapi.Object{"status": status, "message": message}, Secured: true},
+		{Method: "GET", Path: "/users/me/export", Tag: "Profile", Summary: "Download a copy of your data", Query: map[string]string{"format": "json (default) or zip"}, Response: openapi.Object{"status": status, "export": Dto.UserDataExport{}}, Secured: true},
+		{Method: "POST", Path: "/users/me/twoFactor", Tag: "Profile", Summary: "Start two-factor enrolment", Response: openapi.Object{"status": status, "enrolment": Dto.TwoFactorEnrolmentResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/me/twoFactor", Tag: "Profile", Summary: "Turn two-factor authentication off", Request: Dto.TwoFactorCodeRequest{}, Response: ok, Secured: true},
+		{Method: "POST", Path: "/users/me/twoFactor/confirm", Tag: "Profile", Summary: "Confirm two-factor enrolment with a code", Request: Dto.TwoFactorCodeRequest{}, Response: openapi.Object{"status": status, "recovery_codes": []string{}}, Secured: true},
+		{Method: "POST", Path: "/users/me/twoFactor/recoveryCodes", Tag: "Profile", Summary: "Replace your recovery codes", Request: Dto.TwoFactorCodeRequest{}, Response: openapi.Object{"status": status, "recovery_codes": []string{}}, Secured: true},
+		{Method: "GET", Path: "/users/me/sessions", Tag: "Profile", Summary: "List your active sessions", Response: openapi.Object{"status": status, "sessions": []Dto.SessionResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/me/sessions", Tag: "Profile", Summary: "Sign out every other session", Response: ok, Secured: true},
+		{Method: "DELETE", Path: "/users/me/sessions/{id}", Tag: "Profile", Summary: "Sign one of your sessions out", Response: ok, Secured: true},
+		{Method: "GET", Path: "/users/tokens", Tag: "Profile", Summary: "List your API tokens", Response: openapi.Object{"status": status, "tokens": []Dto.APITokenResponse{}}, Secured: true},
+		{Method: "POST", Path: "/users/tokens", Tag: "Profile", Summary: "Issue an API token", Description: "The token is only shown in this response.", Request: Dto.APITokenRequest{}, Status: http.StatusCreated, Response: openapi.Object{"status": status, "token": Dto.APITokenCreatedResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/tokens/{id}", Tag: "Profile", Summary: "Revoke an API token", Response: ok, Secured: true},
+
+		// Circulation desk
+		{Method: "GET", Path: "/users", Tag: "Users", Summary: "Search patrons and staff", Query: map[string]string{"q": "Matches name, email or card number", "status": "active, unverified, suspended or closed", "patron_type": "Patron type", "page": "Page number", "per_page": "Results per page"}, Response: Dto.UserListResponse{}, Secured: true},
+		{Method: "GET", Path: "/users/{id}", Tag: "Users", Summary: "Show a user with their loans, holds and fines", Response: Dto.UserDetailResponse{}, Secured: true},
+		{Method: "PUT", Path: "/users/suspension/{id}", Tag: "Users", Summary: "Suspend a user", Request: Dto.SuspensionRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/suspension/{id}", Tag: "Users", Summary: "Lift a suspension", Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+		{Method: "PUT", Path: "/users/patronType/{id}", Tag: "Users", Summary: "Change a patron's type", Request: Dto.PatronTypeRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+
+		// Administration
+		{Method: "PUT", Path: "/users/role/{id}", Tag: "Administration", Summary: "Change a user's role", Request: Dto.UserRoleRequest{}, Response: openapi.Object{"status": status, "user": Dto.UserResponse{}}, Secured: true},
+		{Method: "GET", Path: "/users/export/{id}", Tag: "Administration", Summary: "Export a user's data for a subject access request", Query: map[string]string{"format": "json (default) or zip"}, Response: openapi.Object{"status": status, "export": Dto.UserDataExport{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/lockout/{id}", Tag: "Administration", Summary: "Lift a login lockout", Description: "Clears password, directory and two-factor lockouts alike.", Response: ok, Secured: true},
+		{Method: "GET", Path: "/users/sessions/{id}", Tag: "Administration", Summary: "List a user's active sessions", Response: openapi.Object{"status": status, "sessions": []Dto.SessionResponse{}}, Secured: true},
+		{Method: "DELETE", Path: "/users/sessions/{id}", Tag: "Administration", Summary: "Sign a user out everywhere", Response: ok, Secured: true},
+
+		// Pages and documentation
+		{Method: "GET", Path: "/", Tag: "Pages", Summary: "Landing page", ContentType: "text/html", Response: ""},
+		{Method: "GET", Path: "/user-dashboard", Tag: "Pages", Summary: "Patron dashboard", ContentType: "text/html", Response: ""},
+		{Method: "GET", Path: "/librarian-dashboard", Tag: "Pages", Summary: "Librarian dashboard", ContentType: "text/html", Response: ""},
+		{Method: "GET", Path: "/reset-password", Tag: "Pages", Summary: "Choose a new password from the emailed link", Query: map[string]string{"token": "Token from the password reset email"}, ContentType: "text/html", Response: ""},
+		{Method: "GET", Path: "/two-factor", Tag: "Pages", Summary: "Enter the second factor of a browser sign-in", ContentType: "text/html", Response: ""},
+		{Method: "GET", Path: "/openapi.json", Tag: "Documentation", Summary: "This document", Response: openapi.Object{}},
+		{Method: "GET", Path: "/docs", Tag: "Documentation", Summary: "Interactive API documentation", ContentType: "text/html", Response: ""},
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document.
+func OpenAPIHandler(c buffalo.Context) error {
+	return c.Render(http.StatusOK, r.JSON(APISpec().Document()))
+}
+
+// APIDocsHandler serves an interactive viewer for the OpenAPI document.
+func APIDocsHandler(c buffalo.Context) error {
+	return c.Render(http.StatusOK, r.HTML("docs/index.plush.html", "docs/layout.plush.html"))
+}
@@ -0,0 +1,43 @@
+package actions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAPICoversRoutes(t *testing.T) {
+	spec := APISpec()
+	for _, route := range App().Routes() {
+		if route.Method == http.MethodOptions {
+			continue
+		}
+		path := strings.TrimSuffix(route.Path, "/")
+		if path == "" {
+			path = "/"
+		}
+		assert.True(t, spec.Has(route.Method, path), "%s %s has no entry in apiRoutes", route.Method, path)
+	}
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	res := httptest.NewRecorder()
+	App().ServeHTTP(res, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, res.Code)
+	var doc struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	assert.NoError(t, json.Unmarshal(res.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths["/api/v1/books/{id}"], "patch")
+	assert.Contains(t, doc.Components.Schemas, "BookResponse")
+}
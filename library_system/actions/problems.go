@@ -0,0 +1,57 @@
+package actions
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/problem"
+)
+
+// RenderProblems turns the errors returned by handlers and the middleware below it into
+// application/problem+json responses. It is the only place API errors are rendered.
+func RenderProblems(next buffalo.Handler) buffalo.Handler {
+	return func(c buffalo.Context) error {
+		if err := next(c); err != nil {
+			return renderProblem(c, err)
+		}
+		return nil
+	}
+}
+
+// HandleErrorsAsProblems replaces buffalo's error pages, which carry stack traces in
+// development, for the errors raised outside any handler: paths nothing is routed to,
+// methods a path does not accept, files missing under ServeFiles, and panics.
+func HandleErrorsAsProblems(app *buffalo.App) {
+	app.ErrorHandlers[http.StatusNotFound] = func(status int, err error, c buffalo.Context) error {
+		return renderProblem(c, problem.New(status, "Nothing is served at this address"))
+	}
+	app.ErrorHandlers[http.StatusMethodNotAllowed] = func(status int, err error, c buffalo.Context) error {
+		return renderProblem(c, problem.New(status, c.Request().Method+" is not allowed at this address"))
+	}
+	app.ErrorHandlers[http.StatusInternalServerError] = func(status int, err error, c buffalo.Context) error {
+		return renderProblem(c, err)
+	}
+}
+
+// renderProblem renders err as problem details.
+func renderProblem(c buffalo.Context, err error) error {
+	details := problem.From(err)
+	if details.Status >= http.StatusInternalServerError {
+		log.Printf("%s %s failed: %v", c.Request().Method, c.Request().URL.Path, err)
+	}
+	// ServeFiles strips its "/" prefix before a missing file reaches the 404 handler.
+	details.Instance = "/" + strings.TrimPrefix(c.Request().URL.Path, "/")
+	if requestID, ok := c.Value("request_id").(string); ok {
+		details.RequestID = requestID
+		c.Response().Header().Set("X-Request-ID", requestID)
+	}
+
+	return c.Render(details.Status, r.Func(problem.ContentType, func(w io.Writer, _ render.Data) error {
+		return json.NewEncoder(w).Encode(details)
+	}))
+}
@@ -0,0 +1,74 @@
+// Package apperrors defines the error kinds shared by repositories, services
+// and controllers. Handlers pick status codes from the kind of an error with
+// errors.Is, never from its message, so rewording a message cannot change the
+// response a client receives.
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound reports that the requested record does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict reports that the request clashes with existing state, such as a duplicate ISBN.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation reports that the request itself is malformed or incomplete.
+	ErrValidation = errors.New("validation error")
+	// ErrUnavailable reports that a resource exists but cannot be used right now, such as a book on loan.
+	ErrUnavailable = errors.New("unavailable")
+	// ErrForbidden reports that the caller may not perform the action.
+	ErrForbidden = errors.New("forbidden")
+	// ErrUnauthorized reports that the caller's credentials or token were rejected.
+	ErrUnauthorized = errors.New("unauthorized")
+)
+
+// Error pairs a client-facing message with one of the kinds above.
+type Error struct {
+	Kind error
+	err  error
+}
+
+// New builds an error of the given kind. The format follows fmt.Errorf, so %w
+// keeps the underlying cause reachable through errors.Is and errors.As.
+func New(kind error, format string, args ...interface{}) error {
+	return &Error{Kind: kind, err: fmt.Errorf(format, args...)}
+}
+
+func (e *Error) Error() string {
+	return e.err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.err
+}
+
+// Is matches the error's kind as well as anything it wraps.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func NotFound(format string, args ...interface{}) error {
+	return New(ErrNotFound, format, args...)
+}
+
+func Conflict(format string, args ...interface{}) error {
+	return New(ErrConflict, format, args...)
+}
+
+func Validation(format string, args ...interface{}) error {
+	return New(ErrValidation, format, args...)
+}
+
+func Unavailable(format string, args ...interface{}) error {
+	return New(ErrUnavailable, format, args...)
+}
+
+func Forbidden(format string, args ...interface{}) error {
+	return New(ErrForbidden, format, args...)
+}
+
+func Unauthorized(format string, args ...interface{}) error {
+	return New(ErrUnauthorized, format, args...)
+}
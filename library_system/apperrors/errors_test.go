@@ -0,0 +1,31 @@
+package apperrors
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKeepsMessageAndKind(t *testing.T) {
+	err := NotFound("book with ISBN %s not found", "123")
+
+	assert.EqualError(t, err, "book with ISBN 123 not found")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrConflict))
+}
+
+func TestErrorKindSurvivesWrapping(t *testing.T) {
+	err := fmt.Errorf("failed to find book: %w", NotFound("book not found"))
+
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
+
+func TestErrorWrapsCause(t *testing.T) {
+	cause := errors.New("bad transition")
+	err := Validation("validation error: %w", cause)
+
+	assert.True(t, errors.Is(err, ErrValidation))
+	assert.True(t, errors.Is(err, cause))
+}
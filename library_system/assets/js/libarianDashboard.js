@@ -26,6 +26,13 @@ const fetchConfig = {
     }
 };
 
+// The server rejects POST, PUT, PATCH and DELETE requests without the session's CSRF token,
+// which the dashboard template puts in a meta tag.
+function mutationHeaders() {
+    const token = document.querySelector("meta[name='csrf-token']").content;
+    return { ...fetchConfig.headers, 'X-CSRF-Token': token };
+}
+
 async function addBook() {
     const title = document.getElementById('title').value;
     const author = document.getElementById('author').value;
@@ -45,6 +52,7 @@ async function addBook() {
         const response = await fetch(`${BASE_URL}/books/add`, {
             ...fetchConfig,
             method: 'POST',
+            headers: mutationHeaders(),
             body: JSON.stringify({ title, author, isbn })
         });
 
@@ -83,6 +91,7 @@ async function updateBook() {
         const response = await fetch(`${BASE_URL}/books/update`, {
             ...fetchConfig,
             method: 'PUT',
+            headers: mutationHeaders(),
             body: JSON.stringify({ title, author, isbn }) // No status included
         });
 
@@ -143,7 +152,8 @@ async function deleteBook(id) {
     try {
         const response = await fetch(`${BASE_URL}/books/remove/${id}`, {
             ...fetchConfig,
-            method: 'DELETE'
+            method: 'DELETE',
+            headers: mutationHeaders()
         });
 
         if (response.ok) {
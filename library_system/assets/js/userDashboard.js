@@ -28,6 +28,8 @@ document.addEventListener('DOMContentLoaded', function () {
                 method: 'POST',
                 headers: {
                     'Content-Type': 'application/json',
+                    // Sign-in protected POSTs are refused without the session's CSRF token.
+                    'X-CSRF-Token': document.querySelector("meta[name='csrf-token']").content,
                 },
                 body: JSON.stringify(formData),
                 credentials: 'include'
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+// ExternalIdentity is who an external identity provider says the user is. Services
+// link it to a models.User by provider and subject, falling back to a verified email.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+	// Username is the name the user signed in with, for providers that take one.
+	Username string
+	// Role is set by providers that manage roles, such as the staff directory, and
+	// overrides the local role on every sign-in. Empty leaves the local role alone.
+	Role string
+}
+
+// ErrInvalidCredentials is returned by a PasswordAuthenticator for a wrong username or
+// password, so callers can answer without revealing which one was wrong.
+var ErrInvalidCredentials = errors.New("invalid username or password")
+
+// PasswordAuthenticator checks a username and password against an external account
+// store such as an LDAP directory.
+type PasswordAuthenticator interface {
+	Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error)
+}
@@ -0,0 +1,202 @@
+package authtest
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jimlambrt/gldap"
+)
+
+// LDAPEntry is a user in the stand-in directory. Password is checked on bind and never
+// returned from searches.
+type LDAPEntry struct {
+	DN         string
+	Password   string
+	Attributes map[string][]string
+}
+
+// LDAPServer is an in-process LDAP directory that supports simple binds and searches
+// with AND filters of equality terms, which is what a search-then-bind login needs.
+// Like a real server it accepts an empty-password bind as anonymous.
+type LDAPServer struct {
+	BindDN       string
+	BindPassword string
+
+	server  *gldap.Server
+	addr    string
+	mu      sync.Mutex
+	entries []LDAPEntry
+	bound   map[int]string
+}
+
+func NewLDAPServer(bindDN, bindPassword string, entries ...LDAPEntry) (*LDAPServer, error) {
+	s := &LDAPServer{
+		BindDN:       bindDN,
+		BindPassword: bindPassword,
+		entries:      entries,
+		bound:        map[int]string{},
+	}
+
+	server, err := gldap.NewServer()
+	if err != nil {
+		return nil, err
+	}
+	mux, err := gldap.NewMux()
+	if err != nil {
+		return nil, err
+	}
+	if err := mux.Bind(s.bind); err != nil {
+		return nil, err
+	}
+	if err := mux.Search(s.search); err != nil {
+		return nil, err
+	}
+	if err := server.Router(mux); err != nil {
+		return nil, err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s.addr = listener.Addr().String()
+	listener.Close()
+
+	s.server = server
+	go func() { _ = server.Run(s.addr) }()
+	for deadline := time.Now().Add(5 * time.Second); !server.Ready(); {
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("LDAP test server did not start on %s", s.addr)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return s, nil
+}
+
+func (s *LDAPServer) URL() string {
+	return "ldap://" + s.addr
+}
+
+func (s *LDAPServer) Close() {
+	_ = s.server.Stop()
+}
+
+func (s *LDAPServer) bind(w *gldap.ResponseWriter, r *gldap.Request) {
+	resp := r.NewBindResponse(gldap.WithResponseCode(gldap.ResultInvalidCredentials))
+	defer func() { _ = w.Write(resp) }()
+
+	m, err := r.GetSimpleBindMessage()
+	if err != nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.bound, r.ConnectionID())
+
+	password := string(m.Password)
+	if password == "" {
+		resp.SetResultCode(gldap.ResultSuccess)
+		return
+	}
+	if strings.EqualFold(m.UserName, s.BindDN) && password == s.BindPassword {
+		s.bound[r.ConnectionID()] = s.BindDN
+		resp.SetResultCode(gldap.ResultSuccess)
+		return
+	}
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, m.UserName) && entry.Password != "" && entry.Password == password {
+			s.bound[r.ConnectionID()] = entry.DN
+			resp.SetResultCode(gldap.ResultSuccess)
+			return
+		}
+	}
+}
+
+var equalityTerm = regexp.MustCompile(`\(([A-Za-z][A-Za-z0-9-]*)=([^()]*)\)`)
+
+func (s *LDAPServer) search(w *gldap.ResponseWriter, r *gldap.Request) {
+	done := r.NewSearchDoneResponse(gldap.WithResponseCode(gldap.ResultSuccess))
+	defer func() { _ = w.Write(done) }()
+
+	m, err := r.GetSearchMessage()
+	if err != nil {
+		done.SetResultCode(gldap.ResultUnwillingToPerform)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bound[r.ConnectionID()] == "" {
+		done.SetResultCode(gldap.ResultInsufficientAccessRights)
+		return
+	}
+
+	terms := equalityTerm.FindAllStringSubmatch(m.Filter, -1)
+	for _, entry := range s.entries {
+		if !strings.HasSuffix(strings.ToLower(entry.DN), strings.ToLower(m.BaseDN)) || !matchesAll(entry, terms) {
+			continue
+		}
+		result := r.NewSearchResponseEntry(entry.DN)
+		for name, values := range entry.Attributes {
+			if wanted(m.Attributes, name) {
+				result.AddAttribute(name, values)
+			}
+		}
+		_ = w.Write(result)
+	}
+}
+
+func matchesAll(entry LDAPEntry, terms [][]string) bool {
+	for _, term := range terms {
+		value := unescapeFilterValue(term[2])
+		found := false
+		for name, values := range entry.Attributes {
+			if !strings.EqualFold(name, term[1]) {
+				continue
+			}
+			for _, v := range values {
+				if value == "*" || strings.EqualFold(v, value) {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+func wanted(requested []string, name string) bool {
+	if len(requested) == 0 {
+		return true
+	}
+	for _, r := range requested {
+		if r == "*" || strings.EqualFold(r, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// unescapeFilterValue reverses the RFC 4515 \XX escaping applied by ldap.EscapeFilter.
+func unescapeFilterValue(value string) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+2 < len(value) {
+			if n, err := strconv.ParseUint(value[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
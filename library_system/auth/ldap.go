@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/gobuffalo/envy"
+	"library-system/models"
+)
+
+const ProviderLDAP = "ldap"
+
+type LDAPConfig struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+	UserBaseDN   string
+	// UserFilter selects the user entry; %s is replaced with the escaped username.
+	UserFilter     string
+	IDAttribute    string
+	EmailAttribute string
+	NameAttribute  string
+	GroupAttribute string
+	// GroupRoles maps group DNs to roles. Users in several groups get the most
+	// privileged role; users in none get DefaultRole, or are refused if it is empty.
+	GroupRoles  map[string]string
+	DefaultRole string
+	Timeout     time.Duration
+}
+
+// LDAPConfigFromEnv reads the directory settings. The second result is false when
+// LDAP_URL is unset, meaning directory sign-in is turned off.
+func LDAPConfigFromEnv() (*LDAPConfig, bool) {
+	serverURL := envy.Get("LDAP_URL", "")
+	if serverURL == "" {
+		return nil, false
+	}
+
+	groupRoles := map[string]string{}
+	for role, key := range map[string]string{models.RoleAdmin: "LDAP_ADMIN_GROUPS", models.RoleLibrarian: "LDAP_LIBRARIAN_GROUPS"} {
+		for _, group := range strings.Split(envy.Get(key, ""), ";") {
+			if group = strings.TrimSpace(group); group != "" {
+				groupRoles[group] = role
+			}
+		}
+	}
+
+	return &LDAPConfig{
+		URL:            serverURL,
+		StartTLS:       envy.Get("LDAP_START_TLS", "false") == "true",
+		BindDN:         envy.Get("LDAP_BIND_DN", ""),
+		BindPassword:   envy.Get("LDAP_BIND_PASSWORD", ""),
+		UserBaseDN:     envy.Get("LDAP_USER_BASE_DN", ""),
+		UserFilter:     envy.Get("LDAP_USER_FILTER", "(&(objectClass=person)(uid=%s))"),
+		IDAttribute:    envy.Get("LDAP_ID_ATTRIBUTE", "entryUUID"),
+		EmailAttribute: envy.Get("LDAP_EMAIL_ATTRIBUTE", "mail"),
+		NameAttribute:  envy.Get("LDAP_NAME_ATTRIBUTE", "cn"),
+		GroupAttribute: envy.Get("LDAP_GROUP_ATTRIBUTE", "memberOf"),
+		GroupRoles:     groupRoles,
+		DefaultRole:    envy.Get("LDAP_DEFAULT_ROLE", ""),
+		Timeout:        10 * time.Second,
+	}, true
+}
+
+// LDAPAuthenticator signs users in with a search-then-bind: it finds the user's entry
+// with the service account, then binds as that entry with the supplied password.
+type LDAPAuthenticator struct {
+	Config *LDAPConfig
+}
+
+func NewLDAPAuthenticator(config *LDAPConfig) (*LDAPAuthenticator, error) {
+	if config.UserBaseDN == "" {
+		return nil, errors.New("LDAP user base DN is required")
+	}
+	if !strings.Contains(config.UserFilter, "%s") {
+		return nil, errors.New("LDAP user filter must contain %s")
+	}
+	if config.DefaultRole != "" && !models.IsValidRole(config.DefaultRole) {
+		return nil, fmt.Errorf("invalid LDAP default role %q", config.DefaultRole)
+	}
+	for group, role := range config.GroupRoles {
+		if !models.IsValidRole(role) {
+			return nil, fmt.Errorf("invalid role %q for LDAP group %s", role, group)
+		}
+	}
+	return &LDAPAuthenticator{Config: config}, nil
+}
+
+func (a *LDAPAuthenticator) Authenticate(ctx context.Context, username, password string) (*ExternalIdentity, error) {
+	username = strings.TrimSpace(username)
+	// An empty password is an unauthenticated bind, which most servers accept.
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := a.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if a.Config.BindDN != "" {
+		if err := conn.Bind(a.Config.BindDN, a.Config.BindPassword); err != nil {
+			return nil, fmt.Errorf("error binding LDAP service account: %w", err)
+		}
+	}
+
+	entry, err := a.findUser(conn, username)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("error binding LDAP user: %w", err)
+	}
+
+	role, err := a.roleFor(entry.GetAttributeValues(a.Config.GroupAttribute))
+	if err != nil {
+		return nil, err
+	}
+
+	subject := entry.GetAttributeValue(a.Config.IDAttribute)
+	if subject == "" {
+		subject = strings.ToLower(entry.DN)
+	}
+	return &ExternalIdentity{
+		Provider: ProviderLDAP,
+		Subject:  subject,
+		Email:    strings.ToLower(strings.TrimSpace(entry.GetAttributeValue(a.Config.EmailAttribute))),
+		// The directory is the system of record for staff addresses.
+		EmailVerified: true,
+		Name:          entry.GetAttributeValue(a.Config.NameAttribute),
+		Username:      username,
+		Role:          role,
+	}, nil
+}
+
+func (a *LDAPAuthenticator) dial(ctx context.Context) (*ldap.Conn, error) {
+	dialer := &net.Dialer{Timeout: a.Config.Timeout}
+	if deadline, ok := ctx.Deadline(); ok {
+		dialer.Deadline = deadline
+	}
+	conn, err := ldap.DialURL(a.Config.URL, ldap.DialWithDialer(dialer))
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to LDAP server: %w", err)
+	}
+	if a.Config.Timeout > 0 {
+		conn.SetTimeout(a.Config.Timeout)
+	}
+	if a.Config.StartTLS {
+		serverURL, _ := url.Parse(a.Config.URL)
+		if err := conn.StartTLS(&tls.Config{ServerName: serverURL.Hostname(), MinVersion: tls.VersionTLS12}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("error starting TLS with LDAP server: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (a *LDAPAuthenticator) findUser(conn *ldap.Conn, username string) (*ldap.Entry, error) {
+	request := ldap.NewSearchRequest(
+		a.Config.UserBaseDN,
+		ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.Config.Timeout.Seconds()), false,
+		fmt.Sprintf(a.Config.UserFilter, ldap.EscapeFilter(username)),
+		[]string{a.Config.IDAttribute, a.Config.EmailAttribute, a.Config.NameAttribute, a.Config.GroupAttribute},
+		nil,
+	)
+	result, err := conn.Search(request)
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultNoSuchObject) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("error searching LDAP directory: %w", err)
+	}
+	switch len(result.Entries) {
+	case 0:
+		return nil, ErrInvalidCredentials
+	case 1:
+		return result.Entries[0], nil
+	}
+	return nil, fmt.Errorf("LDAP user filter matched %d entries for %s", len(result.Entries), username)
+}
+
+// ErrNoDirectoryRole is returned when a directory user is in none of the mapped groups
+// and no default role is configured.
+var ErrNoDirectoryRole = errors.New("directory account is not authorised to sign in")
+
+var rolePrivilege = map[string]int{
+	models.RolePatron:    1,
+	models.RoleLibrarian: 2,
+	models.RoleAdmin:     3,
+}
+
+func (a *LDAPAuthenticator) roleFor(groups []string) (string, error) {
+	role := ""
+	for _, group := range groups {
+		for mapped, groupRole := range a.Config.GroupRoles {
+			if strings.EqualFold(normalizeDN(mapped), normalizeDN(group)) && rolePrivilege[groupRole] > rolePrivilege[role] {
+				role = groupRole
+			}
+		}
+	}
+	if role == "" {
+		role = a.Config.DefaultRole
+	}
+	if role == "" {
+		return "", ErrNoDirectoryRole
+	}
+	return role, nil
+}
+
+// normalizeDN lets "cn=Librarians, ou=Groups" match "cn=librarians,ou=groups".
+func normalizeDN(dn string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil {
+		return strings.ToLower(strings.TrimSpace(dn))
+	}
+	parts := make([]string, 0, len(parsed.RDNs))
+	for _, rdn := range parsed.RDNs {
+		attrs := make([]string, 0, len(rdn.Attributes))
+		for _, attr := range rdn.Attributes {
+			attrs = append(attrs, strings.ToLower(attr.Type)+"="+strings.ToLower(attr.Value))
+		}
+		parts = append(parts, strings.Join(attrs, "+"))
+	}
+	return strings.Join(parts, ",")
+}
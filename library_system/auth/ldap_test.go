@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"library-system/auth/authtest"
+	"library-system/models"
+)
+
+const (
+	librariansGroup = "cn=Librarians,ou=Groups,dc=library,dc=test"
+	adminsGroup     = "cn=Admins,ou=Groups,dc=library,dc=test"
+)
+
+func setupLDAP(t *testing.T, defaultRole string) *LDAPAuthenticator {
+	person := func(uid, password, uuid string, groups ...string) authtest.LDAPEntry {
+		return authtest.LDAPEntry{
+			DN:       "uid=" + uid + ",ou=People,dc=library,dc=test",
+			Password: password,
+			Attributes: map[string][]string{
+				"objectClass": {"person"},
+				"uid":         {uid},
+				"entryUUID":   {uuid},
+				"mail":        {uid + "@Library.test"},
+				"cn":          {"Staff " + uid},
+				"memberOf":    groups,
+			},
+		}
+	}
+	directory, err := authtest.NewLDAPServer("cn=service,dc=library,dc=test", "service-secret",
+		person("ada", "ada-pass", "uuid-ada", "cn=librarians, ou=groups,dc=library,dc=test"),
+		person("grace", "grace-pass", "uuid-grace", librariansGroup, adminsGroup),
+		person("linus", "linus-pass", "uuid-linus"),
+	)
+	assert.NoError(t, err)
+	t.Cleanup(directory.Close)
+
+	authenticator, err := NewLDAPAuthenticator(&LDAPConfig{
+		URL:            directory.URL(),
+		BindDN:         "cn=service,dc=library,dc=test",
+		BindPassword:   "service-secret",
+		UserBaseDN:     "ou=People,dc=library,dc=test",
+		UserFilter:     "(&(objectClass=person)(uid=%s))",
+		IDAttribute:    "entryUUID",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupAttribute: "memberOf",
+		GroupRoles:     map[string]string{librariansGroup: models.RoleLibrarian, adminsGroup: models.RoleAdmin},
+		DefaultRole:    defaultRole,
+	})
+	assert.NoError(t, err)
+	return authenticator
+}
+
+func TestLDAPAuthenticator_MapsGroupToRole(t *testing.T) {
+	authenticator := setupLDAP(t, "")
+
+	identity, err := authenticator.Authenticate(context.Background(), "ada", "ada-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderLDAP, identity.Provider)
+	assert.Equal(t, "uuid-ada", identity.Subject)
+	assert.Equal(t, "ada@library.test", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Staff ada", identity.Name)
+	assert.Equal(t, models.RoleLibrarian, identity.Role)
+}
+
+func TestLDAPAuthenticator_MostPrivilegedGroupWins(t *testing.T) {
+	authenticator := setupLDAP(t, "")
+
+	identity, err := authenticator.Authenticate(context.Background(), "grace", "grace-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, identity.Role)
+}
+
+func TestLDAPAuthenticator_InvalidCredentials(t *testing.T) {
+	authenticator := setupLDAP(t, "")
+
+	for name, creds := range map[string][2]string{
+		"wrong password":  {"ada", "nope"},
+		"empty password":  {"ada", ""},
+		"unknown user":    {"nobody", "ada-pass"},
+		"empty username":  {"", "ada-pass"},
+		"filter injected": {"*)(uid=*", "ada-pass"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			identity, err := authenticator.Authenticate(context.Background(), creds[0], creds[1])
+			assert.ErrorIs(t, err, ErrInvalidCredentials)
+			assert.Nil(t, identity)
+		})
+	}
+}
+
+func TestLDAPAuthenticator_UnmappedUser(t *testing.T) {
+	identity, err := setupLDAP(t, "").Authenticate(context.Background(), "linus", "linus-pass")
+	assert.ErrorIs(t, err, ErrNoDirectoryRole)
+	assert.Nil(t, identity)
+
+	identity, err = setupLDAP(t, models.RolePatron).Authenticate(context.Background(), "linus", "linus-pass")
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePatron, identity.Role)
+}
+
+func TestNewLDAPAuthenticator_ValidatesConfig(t *testing.T) {
+	_, err := NewLDAPAuthenticator(&LDAPConfig{UserBaseDN: "dc=library", UserFilter: "(uid=ada)"})
+	assert.Error(t, err)
+
+	_, err = NewLDAPAuthenticator(&LDAPConfig{
+		UserBaseDN: "dc=library",
+		UserFilter: "(uid=%s)",
+		GroupRoles: map[string]string{librariansGroup: "superuser"},
+	})
+	assert.Error(t, err)
+}
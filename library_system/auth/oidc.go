@@ -0,0 +1,161 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gobuffalo/envy"
+	"golang.org/x/oauth2"
+)
+
+const ProviderOIDC = "oidc"
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// OIDCConfigFromEnv reads the provider settings. The second result is false when
+// OIDC_ISSUER_URL is unset, meaning single sign-on is turned off.
+func OIDCConfigFromEnv() (*OIDCConfig, bool) {
+	issuer := envy.Get("OIDC_ISSUER_URL", "")
+	if issuer == "" {
+		return nil, false
+	}
+	return &OIDCConfig{
+		IssuerURL:    issuer,
+		ClientID:     envy.Get("OIDC_CLIENT_ID", ""),
+		ClientSecret: envy.Get("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  envy.Get("OIDC_REDIRECT_URL", envy.Get("APP_URL", "http://127.0.0.1:3000")+"/users/oidc/callback"),
+		Scopes:       strings.Fields(envy.Get("OIDC_SCOPES", "openid email profile")),
+	}, true
+}
+
+// OIDCProvider runs the authorization code flow with PKCE against one issuer.
+type OIDCProvider struct {
+	oauth    oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCProvider fetches the issuer's discovery document, so it fails fast when the
+// identity provider is unreachable or misconfigured.
+func NewOIDCProvider(ctx context.Context, config *OIDCConfig) (*OIDCProvider, error) {
+	if config.ClientID == "" {
+		return nil, errors.New("OIDC client ID is required")
+	}
+	provider, err := oidc.NewProvider(ctx, config.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("error discovering OIDC provider: %w", err)
+	}
+
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "email", "profile"}
+	}
+	return &OIDCProvider{
+		oauth: oauth2.Config{
+			ClientID:     config.ClientID,
+			ClientSecret: config.ClientSecret,
+			RedirectURL:  config.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		verifier: provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+	}, nil
+}
+
+// OIDCLogin is a started sign-in. State, Nonce and CodeVerifier must be kept
+// server-side, e.g. in the session, until the callback.
+type OIDCLogin struct {
+	URL          string
+	State        string
+	Nonce        string
+	CodeVerifier string
+}
+
+func (p *OIDCProvider) StartLogin() (*OIDCLogin, error) {
+	state, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	nonce, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	return &OIDCLogin{
+		URL:          p.oauth.AuthCodeURL(state, oidc.Nonce(nonce), oauth2.S256ChallengeOption(verifier)),
+		State:        state,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+	}, nil
+}
+
+type oidcClaims struct {
+	Email         string      `json:"email"`
+	EmailVerified interface{} `json:"email_verified"`
+	Name          string      `json:"name"`
+}
+
+// FinishLogin exchanges the authorization code and verifies the ID token's signature,
+// issuer, audience, expiry and nonce before trusting any of its claims.
+func (p *OIDCProvider) FinishLogin(ctx context.Context, code, codeVerifier, nonce string) (*ExternalIdentity, error) {
+	token, err := p.oauth.Exchange(ctx, code, oauth2.VerifierOption(codeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("error exchanging authorization code: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response did not include an ID token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("error verifying ID token: %w", err)
+	}
+	if idToken.Nonce != nonce {
+		return nil, errors.New("ID token nonce does not match")
+	}
+
+	var claims oidcClaims
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("error reading ID token claims: %w", err)
+	}
+
+	return &ExternalIdentity{
+		Provider:      ProviderOIDC,
+		Subject:       idToken.Subject,
+		Email:         strings.ToLower(strings.TrimSpace(claims.Email)),
+		EmailVerified: isTrue(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
+
+// isTrue accepts email_verified as a JSON boolean or, as some providers send it, a string.
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true")
+	}
+	return false
+}
+
+func randomToken() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("error generating random token: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
@@ -0,0 +1,98 @@
+package auth
+
+import (
+	"context"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"library-system/auth/authtest"
+)
+
+func setupOIDC(t *testing.T, user authtest.OIDCUser) (*OIDCProvider, *authtest.OIDCProvider) {
+	idp := authtest.NewOIDCProvider("library", "secret", user)
+	t.Cleanup(idp.Close)
+
+	provider, err := NewOIDCProvider(context.Background(), &OIDCConfig{
+		IssuerURL:    idp.URL(),
+		ClientID:     "library",
+		ClientSecret: "secret",
+		RedirectURL:  "http://library.test/users/oidc/callback",
+	})
+	assert.NoError(t, err)
+	return provider, idp
+}
+
+func TestOIDCProvider_LoginFlow(t *testing.T) {
+	provider, idp := setupOIDC(t, authtest.OIDCUser{
+		Subject:       "campus-123",
+		Email:         "Meenah20@University.edu",
+		EmailVerified: true,
+		Name:          "Aminat Usman",
+	})
+
+	login, err := provider.StartLogin()
+	assert.NoError(t, err)
+	loginURL, _ := url.Parse(login.URL)
+	assert.Equal(t, "S256", loginURL.Query().Get("code_challenge_method"))
+	assert.Equal(t, login.Nonce, loginURL.Query().Get("nonce"))
+	assert.Empty(t, loginURL.Query().Get("code_verifier"))
+
+	code, state, err := idp.Authorize(login.URL)
+	assert.NoError(t, err)
+	assert.Equal(t, login.State, state)
+
+	identity, err := provider.FinishLogin(context.Background(), code, login.CodeVerifier, login.Nonce)
+	assert.NoError(t, err)
+	assert.Equal(t, ProviderOIDC, identity.Provider)
+	assert.Equal(t, "campus-123", identity.Subject)
+	assert.Equal(t, "meenah20@university.edu", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Aminat Usman", identity.Name)
+}
+
+func TestOIDCProvider_RejectsWrongCodeVerifier(t *testing.T) {
+	provider, idp := setupOIDC(t, authtest.OIDCUser{Subject: "campus-123", Email: "meenah20@university.edu"})
+
+	login, err := provider.StartLogin()
+	assert.NoError(t, err)
+	code, _, err := idp.Authorize(login.URL)
+	assert.NoError(t, err)
+
+	other, _ := provider.StartLogin()
+	identity, err := provider.FinishLogin(context.Background(), code, other.CodeVerifier, login.Nonce)
+	assert.Error(t, err)
+	assert.Nil(t, identity)
+}
+
+func TestOIDCProvider_RejectsWrongNonce(t *testing.T) {
+	provider, idp := setupOIDC(t, authtest.OIDCUser{Subject: "campus-123", Email: "meenah20@university.edu"})
+
+	login, err := provider.StartLogin()
+	assert.NoError(t, err)
+	code, _, err := idp.Authorize(login.URL)
+	assert.NoError(t, err)
+
+	identity, err := provider.FinishLogin(context.Background(), code, login.CodeVerifier, "replayed")
+	assert.Error(t, err)
+	assert.Nil(t, identity)
+	assert.Equal(t, "ID token nonce does not match", err.Error())
+}
+
+func TestOIDCProvider_CodeCanOnlyBeUsedOnce(t *testing.T) {
+	provider, idp := setupOIDC(t, authtest.OIDCUser{Subject: "campus-123", Email: "meenah20@university.edu"})
+
+	login, _ := provider.StartLogin()
+	code, _, _ := idp.Authorize(login.URL)
+
+	_, err := provider.FinishLogin(context.Background(), code, login.CodeVerifier, login.Nonce)
+	assert.NoError(t, err)
+	_, err = provider.FinishLogin(context.Background(), code, login.CodeVerifier, login.Nonce)
+	assert.Error(t, err)
+}
+
+func TestNewOIDCProvider_RequiresClientID(t *testing.T) {
+	provider, err := NewOIDCProvider(context.Background(), &OIDCConfig{IssuerURL: "http://127.0.0.1:1"})
+	assert.Error(t, err)
+	assert.Nil(t, provider)
+}
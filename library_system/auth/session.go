@@ -0,0 +1,56 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gobuffalo/envy"
+)
+
+// MinSessionSecretLength is the shortest secret accepted for signing session cookies.
+const MinSessionSecretLength = 32
+
+// SessionSecretsFromEnv reads SESSION_SECRETS, a comma-separated list with the current
+// secret first followed by secrets being rotated out. SESSION_SECRET is accepted for a
+// single secret.
+func SessionSecretsFromEnv() []string {
+	raw := envy.Get("SESSION_SECRETS", "")
+	if raw == "" {
+		raw = envy.Get("SESSION_SECRET", "")
+	}
+
+	var secrets []string
+	for _, secret := range strings.Split(raw, ",") {
+		if secret = strings.TrimSpace(secret); secret != "" {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
+// SessionKeyPairs turns session secrets into the hash and block key pairs taken by
+// gorilla's cookie store. The first secret signs and encrypts new cookies; the others
+// only decode cookies issued before a rotation and can be dropped once those expire.
+func SessionKeyPairs(secrets []string) ([][]byte, error) {
+	if len(secrets) == 0 {
+		return nil, errors.New("at least one session secret is required")
+	}
+
+	keyPairs := make([][]byte, 0, 2*len(secrets))
+	for i, secret := range secrets {
+		if len(secret) < MinSessionSecretLength {
+			return nil, fmt.Errorf("session secret %d must be at least %d characters", i+1, MinSessionSecretLength)
+		}
+		// Separate keys are derived so the same secret never signs and encrypts.
+		hashKey := hmac.New(sha512.New, []byte(secret))
+		hashKey.Write([]byte("library-system session signing"))
+		blockKey := hmac.New(sha256.New, []byte(secret))
+		blockKey.Write([]byte("library-system session encryption"))
+		keyPairs = append(keyPairs, hashKey.Sum(nil), blockKey.Sum(nil))
+	}
+	return keyPairs, nil
+}
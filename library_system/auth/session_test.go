@@ -0,0 +1,65 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gobuffalo/envy"
+	"github.com/gorilla/sessions"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	oldSessionSecret = "old-secret-0123456789abcdefghijklmnop"
+	newSessionSecret = "new-secret-0123456789abcdefghijklmnop"
+)
+
+func sessionCookie(t *testing.T, secrets ...string) *http.Cookie {
+	keyPairs, err := SessionKeyPairs(secrets)
+	assert.NoError(t, err)
+	store := sessions.NewCookieStore(keyPairs...)
+
+	recorder := httptest.NewRecorder()
+	session, _ := store.New(httptest.NewRequest(http.MethodGet, "/", nil), "library")
+	session.Values["current_user_id"] = "user-1"
+	assert.NoError(t, session.Save(httptest.NewRequest(http.MethodGet, "/", nil), recorder))
+	return recorder.Result().Cookies()[0]
+}
+
+func readSession(t *testing.T, cookie *http.Cookie, secrets ...string) (*sessions.Session, error) {
+	keyPairs, err := SessionKeyPairs(secrets)
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	request.AddCookie(cookie)
+	return sessions.NewCookieStore(keyPairs...).Get(request, "library")
+}
+
+func TestSessionKeyPairs_RotatesSecrets(t *testing.T) {
+	cookie := sessionCookie(t, oldSessionSecret)
+	assert.NotContains(t, cookie.Value, "user-1")
+
+	session, err := readSession(t, cookie, newSessionSecret, oldSessionSecret)
+	assert.NoError(t, err)
+	assert.Equal(t, "user-1", session.Values["current_user_id"])
+
+	_, err = readSession(t, cookie, newSessionSecret)
+	assert.Error(t, err)
+}
+
+func TestSessionKeyPairs_RejectsWeakSecrets(t *testing.T) {
+	_, err := SessionKeyPairs(nil)
+	assert.Error(t, err)
+
+	_, err = SessionKeyPairs([]string{newSessionSecret, "short"})
+	assert.Error(t, err)
+	assert.True(t, strings.Contains(err.Error(), "session secret 2"))
+}
+
+func TestSessionSecretsFromEnv(t *testing.T) {
+	envy.Temp(func() {
+		envy.Set("SESSION_SECRETS", " "+newSessionSecret+" ,, "+oldSessionSecret)
+		assert.Equal(t, []string{newSessionSecret, oldSessionSecret}, SessionSecretsFromEnv())
+	})
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"library-system/actions"
 	"log"
+	"net"
 
 	"github.com/gobuffalo/envy"
 )
@@ -15,6 +16,23 @@ func main() {
 		log.Fatal("Failed to initialize application")
 	}
 
+	// GRPC_ADDR set to an empty string turns the gRPC server off. The server speaks
+	// plaintext, so by default it only listens on the loopback interface.
+	if addr := envy.Get("GRPC_ADDR", "127.0.0.1:50051"); addr != "" {
+		grpcServer := actions.GRPCServer()
+		lis, err := net.Listen("tcp", addr)
+		if err != nil {
+			log.Fatal("Error starting the gRPC server: ", err)
+		}
+		log.Printf("Starting gRPC server on %s", addr)
+		go func() {
+			if err := grpcServer.Serve(lis); err != nil {
+				log.Printf("gRPC server stopped: %v", err)
+			}
+		}()
+		defer grpcServer.GracefulStop()
+	}
+
 	log.Printf("Starting %s server on :3000", env)
 
 	if err := app.Serve(); err != nil {
@@ -5,8 +5,9 @@ import (
 	"github.com/gobuffalo/buffalo/render"
 	"github.com/gofrs/uuid"
 	"library-system/Dto"
-	"library-system/models"
+	"library-system/problem"
 	"library-system/services"
+	"library-system/validation"
 	"net/http"
 	"strings"
 )
@@ -21,11 +22,6 @@ type BookController struct {
 	BookService *services.BookServices
 }
 
-type ErrorResponse struct {
-	Error   string `json:"error"`
-	Details string `json:"details,omitempty"`
-}
-
 func NewBookController(bookService *services.BookServices) *BookController {
 	return &BookController{BookService: bookService}
 }
@@ -33,26 +29,16 @@ func NewBookController(bookService *services.BookServices) *BookController {
 func (bc *BookController) AddBook(c buffalo.Context) error {
 	var request Dto.BookRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error:   "Invalid request format",
-			Details: err.Error(),
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
-	if strings.TrimSpace(request.ISBN) == "" {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error: "ISBN is required",
-		}))
+	if err := validation.Struct(request); err != nil {
+		return err
 	}
 
 	book, err := bc.BookService.AddBook(request)
 	if err != nil {
-		if strings.Contains(err.Error(), "duplicate ISBN") {
-			return c.Render(http.StatusConflict, r.JSON(ErrorResponse{
-				Error: err.Error(),
-			}))
-		}
-		return handleError(c, err)
+		return err
 	}
 
 	return c.Render(http.StatusCreated, r.JSON(book))
@@ -61,15 +47,12 @@ func (bc *BookController) AddBook(c buffalo.Context) error {
 func (bc *BookController) RemoveBook(c buffalo.Context) error {
 	bookID, err := parseUUID(c.Param("id")) // Get ID from the URL path
 	if err != nil {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error:   "Invalid book ID format",
-			Details: err.Error(),
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid book ID format")
 	}
 
 	book, err := bc.BookService.RemoveBook(bookID)
 	if err != nil {
-		return handleError(c, err)
+		return err
 	}
 
 	return c.Render(http.StatusOK, r.JSON(book))
@@ -78,40 +61,91 @@ func (bc *BookController) RemoveBook(c buffalo.Context) error {
 func (bc *BookController) UpdateBook(c buffalo.Context) error {
 	var request Dto.BookRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error:   "Invalid request format",
-			Details: err.Error(),
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
-	if strings.TrimSpace(request.ISBN) == "" {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error: "ISBN is required",
-		}))
+	if err := validation.Struct(request); err != nil {
+		return err
 	}
 
-	request.Status = models.StatusBorrowed
-
 	book, err := bc.BookService.UpdateBookByISBN(request)
 	if err != nil {
-		return handleError(c, err)
+		return err
+	}
+
+	return c.Render(http.StatusOK, r.JSON(book))
+}
+
+// PatchBook updates the title or author of the book named in the path.
+func (bc *BookController) PatchBook(c buffalo.Context) error {
+	bookID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid book ID format")
+	}
+
+	var request Dto.BookPatchRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	book, err := bc.BookService.UpdateBook(bookID, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, r.JSON(book))
+}
+
+func (bc *BookController) ChangeBookStatus(c buffalo.Context) error {
+	bookID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid book ID format")
+	}
+
+	var request Dto.BookStatusRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	book, err := bc.BookService.ChangeBookStatus(bookID, request)
+	if err != nil {
+		return err
 	}
 
 	return c.Render(http.StatusOK, r.JSON(book))
 }
 
+func (bc *BookController) GetBookStatusHistory(c buffalo.Context) error {
+	bookID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid book ID format")
+	}
+
+	history, err := bc.BookService.GetBookStatusHistory(bookID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, r.JSON(history))
+}
+
 func (bc *BookController) GetBookByID(c buffalo.Context) error {
 	bookID, err := parseUUID(c.Param("id"))
 	if err != nil {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error:   "Invalid book ID format",
-			Details: err.Error(),
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid book ID format")
 	}
 
 	book, err := bc.BookService.GetBookByID(bookID)
 	if err != nil {
-		return handleError(c, err)
+		return err
 	}
 
 	return c.Render(http.StatusOK, r.JSON(book))
@@ -120,14 +154,27 @@ func (bc *BookController) GetBookByID(c buffalo.Context) error {
 func (bc *BookController) SearchBook(c buffalo.Context) error {
 	query := c.Param("query")
 	if strings.TrimSpace(query) == "" {
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error: "Search query cannot be empty",
-		}))
+		return problem.New(http.StatusBadRequest, "Search query cannot be empty")
 	}
 
 	books, err := bc.BookService.SearchBook(query)
 	if err != nil {
-		return handleError(c, err)
+		return err
+	}
+
+	return c.Render(http.StatusOK, r.JSON(books))
+}
+
+// ListBooks lists the catalogue, or searches it when a q parameter is given.
+func (bc *BookController) ListBooks(c buffalo.Context) error {
+	query := strings.TrimSpace(c.Param("q"))
+	if query == "" {
+		return bc.GetAllBooks(c)
+	}
+
+	books, err := bc.BookService.SearchBook(query)
+	if err != nil {
+		return err
 	}
 
 	return c.Render(http.StatusOK, r.JSON(books))
@@ -136,10 +183,7 @@ func (bc *BookController) SearchBook(c buffalo.Context) error {
 func (bc *BookController) GetAllBooks(c buffalo.Context) error {
 	books, err := bc.BookService.GetAllBooks()
 	if err != nil {
-		return c.Render(http.StatusInternalServerError, r.JSON(ErrorResponse{
-			Error:   "Failed to fetch books",
-			Details: err.Error(),
-		}))
+		return err
 	}
 
 	return c.Render(http.StatusOK, r.JSON(books))
@@ -148,27 +192,3 @@ func (bc *BookController) GetAllBooks(c buffalo.Context) error {
 func parseUUID(id string) (uuid.UUID, error) {
 	return uuid.FromString(id)
 }
-
-func handleError(c buffalo.Context, err error) error {
-	errLower := strings.ToLower(err.Error())
-
-	switch {
-	case strings.Contains(errLower, "not found"):
-		return c.Render(http.StatusNotFound, r.JSON(ErrorResponse{
-			Error: err.Error(),
-		}))
-	case strings.Contains(errLower, "validation"):
-		return c.Render(http.StatusBadRequest, r.JSON(ErrorResponse{
-			Error: err.Error(),
-		}))
-	case strings.Contains(errLower, "duplicate isbn"):
-		return c.Render(http.StatusConflict, r.JSON(ErrorResponse{
-			Error: err.Error(),
-		}))
-	default:
-		return c.Render(http.StatusInternalServerError, r.JSON(ErrorResponse{
-			Error:   "Internal server error",
-			Details: err.Error(),
-		}))
-	}
-}
@@ -0,0 +1,53 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/problem"
+	"net/http"
+)
+
+const (
+	csrfTokenKey = "csrf_token"
+	// CSRFHeader carries the token on requests and hands it out on responses.
+	CSRFHeader = "X-CSRF-Token"
+	// CSRFFormField carries the token in HTML form posts.
+	CSRFFormField = "authenticity_token"
+)
+
+// CSRFToken returns the token bound to the browser session, issuing one when the
+// session has none. The session is saved at the end of the request.
+func CSRFToken(c buffalo.Context) (string, error) {
+	if token, ok := c.Session().Get(csrfTokenKey).(string); ok && token != "" {
+		return token, nil
+	}
+	return issueCSRFToken(c)
+}
+
+// issueCSRFToken gives the session a fresh token, e.g. after sign-in replaces the
+// session, and advertises it on the response.
+func issueCSRFToken(c buffalo.Context) (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(buf)
+	c.Session().Set(csrfTokenKey, token)
+	c.Response().Header().Set(CSRFHeader, token)
+	return token, nil
+}
+
+// GetCSRFToken lets script clients fetch the token before their first mutation.
+func GetCSRFToken(c buffalo.Context) error {
+	token, err := CSRFToken(c)
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to issue CSRF token")
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status":     "success",
+		"csrf_token": token,
+	}))
+}
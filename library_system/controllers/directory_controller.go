@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/Dto"
+	"library-system/auth"
+	"library-system/problem"
+	"library-system/services"
+	"library-system/validation"
+	"log"
+	"net/http"
+)
+
+type DirectoryController struct {
+	Authenticator  auth.PasswordAuthenticator
+	SSOService     *services.SSOServices
+	SessionService *services.SessionServices
+	LoginThrottle  *services.LoginThrottleServices
+}
+
+func NewDirectoryController(authenticator auth.PasswordAuthenticator, ssoService *services.SSOServices, sessionService *services.SessionServices, loginThrottle *services.LoginThrottleServices) *DirectoryController {
+	return &DirectoryController{
+		Authenticator:  authenticator,
+		SSOService:     ssoService,
+		SessionService: sessionService,
+		LoginThrottle:  loginThrottle,
+	}
+}
+
+// Login signs staff in with their directory username and password. The account is
+// linked and its role refreshed the same way as a single sign-on login.
+func (dc *DirectoryController) Login(c buffalo.Context) error {
+	var request Dto.DirectoryLoginRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	account := services.DirectoryThrottleAccount(request.Username)
+	ipAddress := clientIP(c.Request())
+	if err := dc.LoginThrottle.Check(account, ipAddress); err != nil {
+		return loginLocked(c, err)
+	}
+
+	identity, err := dc.Authenticator.Authenticate(c.Request().Context(), request.Username, request.Password)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidCredentials) {
+			dc.LoginThrottle.RecordFailure(account, ipAddress)
+			return problem.New(http.StatusUnauthorized, "Invalid username or password")
+		}
+		if errors.Is(err, auth.ErrNoDirectoryRole) {
+			return problem.New(http.StatusForbidden, "Your directory account is not allowed to sign in")
+		}
+		log.Printf("Directory login for %s failed: %v", request.Username, err)
+		return problem.New(http.StatusServiceUnavailable, "Directory sign-in is unavailable")
+	}
+
+	dc.LoginThrottle.RecordSuccess(account)
+
+	user, err := dc.SSOService.SignIn(identity)
+	if err != nil {
+		log.Printf("Directory sign-in for %s refused: %v", request.Username, err)
+		return problem.New(http.StatusForbidden, err.Error())
+	}
+
+	needsCode, err := beginSignIn(c, dc.SessionService, user.ID, user.TwoFactorEnabled())
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to start session")
+	}
+	if needsCode {
+		return renderTwoFactorRequired(c)
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   services.NewUserResponse(user),
+	}))
+}
@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"errors"
+	"library-system/problem"
+	"library-system/services"
+	"net/http"
+)
+
+// borrowingBlocked reports a blocked patron as its own problem type carrying the
+// block's code, so clients can tell it apart from a request they may not make at all.
+// Other errors pass through unchanged.
+func borrowingBlocked(err error) error {
+	var blocked *services.BorrowingBlockedError
+	if errors.As(err, &blocked) {
+		return &problem.Error{
+			Status: http.StatusForbidden,
+			Type:   problem.TypeBorrowingBlocked,
+			Detail: blocked.Message,
+			Code:   blocked.Code,
+		}
+	}
+	return err
+}
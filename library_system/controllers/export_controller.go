@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gofrs/uuid"
+	"library-system/problem"
+	"library-system/services"
+	"log"
+	"net/http"
+	"strings"
+)
+
+type ExportController struct {
+	ExportService *services.ExportServices
+}
+
+func NewExportController(exportService *services.ExportServices) *ExportController {
+	return &ExportController{ExportService: exportService}
+}
+
+// ExportMyData hands the signed-in user a copy of their personal data.
+func (ec *ExportController) ExportMyData(c buffalo.Context) error {
+	return ec.renderExport(c, CurrentUser(c).ID)
+}
+
+// ExportUserData lets an admin answer a subject access request on a patron's behalf.
+func (ec *ExportController) ExportUserData(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+	return ec.renderExport(c, userID)
+}
+
+func (ec *ExportController) renderExport(c buffalo.Context, userID uuid.UUID) error {
+	format := strings.ToLower(c.Param("format"))
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		return problem.New(http.StatusBadRequest, "Format must be json or zip")
+	}
+
+	export, err := ec.ExportService.ExportUserData(userID)
+	if err != nil {
+		return err
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	if format == "json" {
+		return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+			"status": "success",
+			"export": export,
+		}))
+	}
+
+	var archive bytes.Buffer
+	if err := services.WriteExportArchive(&archive, export); err != nil {
+		log.Printf("Failed to build data export for %s: %v", userID, err)
+		return problem.New(http.StatusInternalServerError, "Failed to build data export")
+	}
+
+	name := fmt.Sprintf("library-data-%s-%s.zip", userID, export.GeneratedAt.Format("20060102"))
+	return c.Render(http.StatusOK, render.Download(c, name, &archive))
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/Dto"
+	"library-system/graph"
+	"library-system/problem"
+	"net/http"
+	"strings"
+)
+
+type GraphQLController struct {
+	Graph *graph.Service
+}
+
+func NewGraphQLController(service *graph.Service) *GraphQLController {
+	return &GraphQLController{Graph: service}
+}
+
+// Query runs a GraphQL query or mutation as the signed-in user, or anonymously. Errors
+// inside the operation are reported in the body with a 200, as GraphQL clients expect.
+func (gc *GraphQLController) Query(c buffalo.Context) error {
+	var request Dto.GraphQLRequest
+	if err := c.Bind(&request); err != nil || strings.TrimSpace(request.Query) == "" {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	viewer := graph.Viewer{User: CurrentUser(c), Token: CurrentToken(c)}
+	response := gc.Graph.Exec(c, viewer, request.Query, request.OperationName, request.Variables)
+	return c.Render(http.StatusOK, render.JSON(response))
+}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/problem"
+	"library-system/services"
+	"library-system/validation"
+	"log"
+	"net/http"
+)
+
+type PasswordController struct {
+	PasswordResetService *services.PasswordResetServices
+}
+
+func NewPasswordController(passwordResetService *services.PasswordResetServices) *PasswordController {
+	return &PasswordController{PasswordResetService: passwordResetService}
+}
+
+func (pc *PasswordController) ForgotPassword(c buffalo.Context) error {
+	var request Dto.EmailRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := pc.PasswordResetService.RequestReset(request.Email); err != nil {
+		if errors.Is(err, apperrors.ErrValidation) {
+			return err
+		}
+		log.Printf("Password reset request failed: %v", err)
+	}
+
+	return c.Render(http.StatusAccepted, render.JSON(map[string]string{
+		"status":  "success",
+		"message": "If the address is registered, a password reset email has been sent",
+	}))
+}
+
+func (pc *PasswordController) ResetPassword(c buffalo.Context) error {
+	var request Dto.PasswordResetRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := pc.PasswordResetService.ResetPassword(request); err != nil {
+		return err
+	}
+
+	session := c.Session()
+	session.Clear()
+	session.Save()
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status":  "success",
+		"message": "Password updated. Please sign in again",
+	}))
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gofrs/uuid"
+	"library-system/problem"
+	"library-system/services"
+	"net/http"
+)
+
+type SessionController struct {
+	SessionService *services.SessionServices
+}
+
+func NewSessionController(sessionService *services.SessionServices) *SessionController {
+	return &SessionController{SessionService: sessionService}
+}
+
+func (sc *SessionController) ListMySessions(c buffalo.Context) error {
+	return sc.renderSessions(c, CurrentUser(c).ID, CurrentSessionID(c))
+}
+
+func (sc *SessionController) ListUserSessions(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+	return sc.renderSessions(c, userID, CurrentSessionID(c))
+}
+
+func (sc *SessionController) RevokeSession(c buffalo.Context) error {
+	sessionID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid session ID format")
+	}
+
+	if err := sc.SessionService.RevokeSession(CurrentUser(c), sessionID); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+// RevokeOtherSessions signs the user out on every other device, keeping this one.
+func (sc *SessionController) RevokeOtherSessions(c buffalo.Context) error {
+	if err := sc.SessionService.RevokeOtherSessions(CurrentUser(c).ID, CurrentSessionID(c)); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+func (sc *SessionController) ForceLogout(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	if err := sc.SessionService.ForceLogout(userID); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+func (sc *SessionController) renderSessions(c buffalo.Context, userID, currentID uuid.UUID) error {
+	sessions, err := sc.SessionService.ListSessions(userID, currentID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status":   "success",
+		"sessions": sessions,
+	}))
+}
@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"crypto/subtle"
+	"github.com/gobuffalo/buffalo"
+	"library-system/auth"
+	"library-system/problem"
+	"library-system/services"
+	"log"
+	"net/http"
+)
+
+const (
+	oidcStateKey    = "oidc_state"
+	oidcNonceKey    = "oidc_nonce"
+	oidcVerifierKey = "oidc_code_verifier"
+)
+
+type SSOController struct {
+	Provider       *auth.OIDCProvider
+	SSOService     *services.SSOServices
+	SessionService *services.SessionServices
+}
+
+func NewSSOController(provider *auth.OIDCProvider, ssoService *services.SSOServices, sessionService *services.SessionServices) *SSOController {
+	return &SSOController{Provider: provider, SSOService: ssoService, SessionService: sessionService}
+}
+
+// StartLogin sends the browser to the identity provider. The PKCE verifier, state and
+// nonce stay in the session cookie so the callback can check them.
+func (sc *SSOController) StartLogin(c buffalo.Context) error {
+	login, err := sc.Provider.StartLogin()
+	if err != nil {
+		log.Printf("Failed to start OIDC login: %v", err)
+		return problem.New(http.StatusInternalServerError, "Failed to start single sign-on")
+	}
+
+	session := c.Session()
+	session.Set(oidcStateKey, login.State)
+	session.Set(oidcNonceKey, login.Nonce)
+	session.Set(oidcVerifierKey, login.CodeVerifier)
+	if err := session.Save(); err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to start session")
+	}
+
+	return c.Redirect(http.StatusFound, login.URL)
+}
+
+func (sc *SSOController) Callback(c buffalo.Context) error {
+	session := c.Session()
+	state, _ := session.Get(oidcStateKey).(string)
+	nonce, _ := session.Get(oidcNonceKey).(string)
+	verifier, _ := session.Get(oidcVerifierKey).(string)
+	session.Delete(oidcStateKey)
+	session.Delete(oidcNonceKey)
+	session.Delete(oidcVerifierKey)
+	_ = session.Save()
+
+	if providerError := c.Param("error"); providerError != "" {
+		log.Printf("OIDC provider returned an error: %s %s", providerError, c.Param("error_description"))
+		return problem.New(http.StatusUnauthorized, "Single sign-on was cancelled or refused")
+	}
+
+	if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(c.Param("state"))) != 1 {
+		return problem.New(http.StatusBadRequest, "Invalid or expired sign-in attempt")
+	}
+
+	identity, err := sc.Provider.FinishLogin(c.Request().Context(), c.Param("code"), verifier, nonce)
+	if err != nil {
+		log.Printf("OIDC login failed: %v", err)
+		return problem.New(http.StatusUnauthorized, "Single sign-on failed")
+	}
+
+	user, err := sc.SSOService.SignIn(identity)
+	if err != nil {
+		log.Printf("OIDC sign-in for %s refused: %v", identity.Email, err)
+		return problem.New(http.StatusForbidden, err.Error())
+	}
+
+	needsCode, err := beginSignIn(c, sc.SessionService, user.ID, user.TwoFactorEnabled())
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to start session")
+	}
+	if needsCode {
+		// The pending sign-in stays in the session for the code entry page to finish.
+		return c.Redirect(http.StatusFound, "/two-factor")
+	}
+
+	if services.CanManageCatalogue(user) {
+		return c.Redirect(http.StatusFound, "/librarian-dashboard")
+	}
+	return c.Redirect(http.StatusFound, "/user-dashboard")
+}
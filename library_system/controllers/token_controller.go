@@ -0,0 +1,78 @@
+package controllers
+
+import (
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/problem"
+	"library-system/services"
+	"library-system/validation"
+	"net/http"
+)
+
+const currentTokenKey = "current_token"
+
+type TokenController struct {
+	TokenService *services.TokenServices
+}
+
+func NewTokenController(tokenService *services.TokenServices) *TokenController {
+	return &TokenController{TokenService: tokenService}
+}
+
+func (tc *TokenController) ListTokens(c buffalo.Context) error {
+	user := CurrentUser(c)
+	tokens, err := tc.TokenService.ListTokens(user.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"tokens": tokens,
+	}))
+}
+
+func (tc *TokenController) CreateToken(c buffalo.Context) error {
+	var request Dto.APITokenRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	token, err := tc.TokenService.CreateToken(CurrentUser(c), request)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusCreated, render.JSON(map[string]interface{}{
+		"status": "success",
+		"token":  token,
+	}))
+}
+
+func (tc *TokenController) RevokeToken(c buffalo.Context) error {
+	tokenID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid token ID format")
+	}
+
+	if err := tc.TokenService.RevokeToken(CurrentUser(c), tokenID); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+// CurrentToken returns the API token the request was authenticated with, or nil for
+// session-authenticated and anonymous requests.
+func CurrentToken(c buffalo.Context) *models.APIToken {
+	token, _ := c.Value(currentTokenKey).(*models.APIToken)
+	return token
+}
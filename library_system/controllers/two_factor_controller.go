@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"errors"
+	"github.com/gobuffalo/buffalo"
+	"github.com/gobuffalo/buffalo/render"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/problem"
+	"library-system/services"
+	"library-system/validation"
+	"net/http"
+	"time"
+)
+
+// pendingSignInLifetime is how long a user has to enter their code after the password.
+const pendingSignInLifetime = 5 * time.Minute
+
+type TwoFactorController struct {
+	TwoFactorService *services.TwoFactorServices
+	SessionService   *services.SessionServices
+	LoginThrottle    *services.LoginThrottleServices
+}
+
+func NewTwoFactorController(twoFactorService *services.TwoFactorServices, sessionService *services.SessionServices, loginThrottle *services.LoginThrottleServices) *TwoFactorController {
+	return &TwoFactorController{
+		TwoFactorService: twoFactorService,
+		SessionService:   sessionService,
+		LoginThrottle:    loginThrottle,
+	}
+}
+
+func (tc *TwoFactorController) BeginEnrolment(c buffalo.Context) error {
+	enrolment, err := tc.TwoFactorService.BeginEnrolment(CurrentUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status":    "success",
+		"enrolment": enrolment,
+	}))
+}
+
+func (tc *TwoFactorController) ConfirmEnrolment(c buffalo.Context) error {
+	var request Dto.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	codes, err := tc.TwoFactorService.ConfirmEnrolment(CurrentUser(c).ID, request.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status":         "success",
+		"recovery_codes": codes.RecoveryCodes,
+	}))
+}
+
+func (tc *TwoFactorController) Disable(c buffalo.Context) error {
+	var request Dto.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := tc.TwoFactorService.Disable(CurrentUser(c).ID, request.Code); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+func (tc *TwoFactorController) RegenerateRecoveryCodes(c buffalo.Context) error {
+	var request Dto.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	codes, err := tc.TwoFactorService.RegenerateRecoveryCodes(CurrentUser(c).ID, request.Code)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status":         "success",
+		"recovery_codes": codes.RecoveryCodes,
+	}))
+}
+
+// CompleteLogin finishes a sign-in parked by beginSignIn once the second factor checks out.
+func (tc *TwoFactorController) CompleteLogin(c buffalo.Context) error {
+	var request Dto.TwoFactorCodeRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	session := c.Session()
+	userIDStr, _ := session.Get(pendingUserIDKey).(string)
+	startedAt, _ := session.Get(pendingStartedKey).(int64)
+	userID, err := uuid.FromString(userIDStr)
+	if err != nil || time.Since(time.Unix(startedAt, 0)) > pendingSignInLifetime {
+		session.Clear()
+		_ = session.Save()
+		return problem.New(http.StatusUnauthorized, "Two-factor sign-in has expired. Log in again")
+	}
+
+	account := services.TwoFactorThrottleAccount(userID)
+	ipAddress := clientIP(c.Request())
+	if err := tc.LoginThrottle.Check(account, ipAddress); err != nil {
+		return loginLocked(c, err)
+	}
+
+	user, err := tc.TwoFactorService.Verify(userID, request.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidTwoFactorCode) {
+			tc.LoginThrottle.RecordFailure(account, ipAddress)
+			return problem.New(http.StatusUnauthorized, err.Error())
+		}
+		return err
+	}
+	tc.LoginThrottle.RecordSuccess(account)
+
+	if err := startSession(c, tc.SessionService, user.ID); err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to start session")
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
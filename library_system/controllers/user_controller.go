@@ -1,57 +1,100 @@
 package controllers
 
 import (
+	"errors"
 	"github.com/gobuffalo/buffalo"
 	"github.com/gobuffalo/buffalo/render"
+	"github.com/gofrs/uuid"
 	"github.com/gorilla/sessions"
 	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/problem"
 	"library-system/services"
+	"library-system/validation"
 	"log"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 )
 
 const (
-	sessionName = "_library_session"
-	userIDKey   = "current_user_id"
+	sessionName         = "_library_session"
+	userIDKey           = "current_user_id"
+	sessionIDKey        = "session_id"
+	pendingUserIDKey    = "two_factor_user_id"
+	pendingStartedKey   = "two_factor_started_at"
+	currentUserKey      = "current_user"
+	currentSessionIDKey = "current_session_id"
 )
 
 type UserController struct {
-	UserService  *services.UserServices
-	SessionStore sessions.Store
+	UserService         *services.UserServices
+	VerificationService *services.VerificationServices
+	SessionService      *services.SessionServices
+	LoginThrottle       *services.LoginThrottleServices
+	SessionStore        sessions.Store
 }
 
-func NewUserController(userService *services.UserServices, sessionStore sessions.Store) *UserController {
+func NewUserController(userService *services.UserServices, verificationService *services.VerificationServices, sessionService *services.SessionServices, loginThrottle *services.LoginThrottleServices, sessionStore sessions.Store) *UserController {
 	return &UserController{
-		UserService:  userService,
-		SessionStore: sessionStore,
+		UserService:         userService,
+		VerificationService: verificationService,
+		SessionService:      sessionService,
+		LoginThrottle:       loginThrottle,
+		SessionStore:        sessionStore,
 	}
 }
 
 func (uc *UserController) RegisterUser(c buffalo.Context) error {
 	var request Dto.UserRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, render.JSON(map[string]string{
-			"error": "Invalid request format",
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
 	request.Email = normalizeEmail(request.Email)
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
 	user, err := uc.UserService.RegisterUser(request)
 	if err != nil {
-		if err.Error() == "email already registered" {
-			return c.Render(http.StatusConflict, render.JSON(map[string]string{
-				"error": "Email already registered",
-			}))
+		if errors.Is(err, apperrors.ErrConflict) {
+			return problem.New(http.StatusConflict, "Email already registered")
 		}
-		return c.Render(http.StatusBadRequest, render.JSON(map[string]string{
-			"error": err.Error(),
-		}))
+		return err
 	}
 
-	session := c.Session()
-	session.Set(userIDKey, user.ID.String())
-	session.Save()
+	verificationSent := true
+	if err := uc.VerificationService.SendVerification(user.ID); err != nil {
+		log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+		verificationSent = false
+	}
+
+	if err := startSession(c, uc.SessionService, user.ID); err != nil {
+		log.Printf("Failed to start session for %s: %v", user.Email, err)
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status":            "success",
+		"user":              user,
+		"verification_sent": verificationSent,
+	}))
+}
+
+func (uc *UserController) VerifyEmail(c buffalo.Context) error {
+	token := strings.TrimSpace(c.Param("token"))
+	if token == "" {
+		return problem.New(http.StatusBadRequest, "Verification token is required")
+	}
+
+	user, err := uc.VerificationService.VerifyEmail(token)
+	if err != nil {
+		return err
+	}
 
 	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
 		"status": "success",
@@ -59,27 +102,104 @@ func (uc *UserController) RegisterUser(c buffalo.Context) error {
 	}))
 }
 
+func (uc *UserController) ResendVerification(c buffalo.Context) error {
+	var request Dto.EmailRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := uc.VerificationService.ResendVerification(request.Email); err != nil {
+		log.Printf("Failed to resend verification email: %v", err)
+	}
+
+	return c.Render(http.StatusAccepted, render.JSON(map[string]string{
+		"status":  "success",
+		"message": "If the address is registered and unverified, a new verification email has been sent",
+	}))
+}
+
+func (uc *UserController) Login(c buffalo.Context) error {
+	var request Dto.LoginRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	request.Email = normalizeEmail(request.Email)
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	ipAddress := clientIP(c.Request())
+	if err := uc.LoginThrottle.Check(request.Email, ipAddress); err != nil {
+		return loginLocked(c, err)
+	}
+
+	user, err := uc.UserService.Login(request)
+	if err != nil {
+		if errors.Is(err, apperrors.ErrUnauthorized) {
+			uc.LoginThrottle.RecordFailure(request.Email, ipAddress)
+			return problem.New(http.StatusUnauthorized, "Invalid email or password")
+		}
+		return err
+	}
+	uc.LoginThrottle.RecordSuccess(request.Email)
+
+	needsCode, err := beginSignIn(c, uc.SessionService, user.ID, user.TwoFactor)
+	if err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to start session")
+	}
+	if needsCode {
+		return renderTwoFactorRequired(c)
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+func (uc *UserController) Logout(c buffalo.Context) error {
+	if sessionID := CurrentSessionID(c); sessionID != uuid.Nil {
+		if err := uc.SessionService.EndSession(sessionID); err != nil {
+			log.Printf("Failed to end session %s: %v", sessionID, err)
+		}
+	}
+
+	session := c.Session()
+	session.Clear()
+	if err := session.Save(); err != nil {
+		return problem.New(http.StatusInternalServerError, "Failed to end session")
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
 func (uc *UserController) CheckoutBook(c buffalo.Context) error {
 	var request Dto.BookActionRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, render.JSON(map[string]string{
-			"error": "Invalid request format",
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
 	request.Email = normalizeEmail(request.Email)
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := uc.UserService.AuthorizeLoanAction(CurrentUser(c), &request); err != nil {
+		return err
+	}
 	log.Printf("Processing checkout - Book ID: %s, Email: %s", request.BookID, request.Email)
 
 	response, err := uc.UserService.CheckOutBook(request)
 	if err != nil {
 		log.Printf("Checkout failed: %v", err)
-		statusCode := http.StatusBadRequest
-		if strings.Contains(err.Error(), "not available") {
-			statusCode = http.StatusConflict
-		}
-		return c.Render(statusCode, render.JSON(map[string]string{
-			"error": err.Error(),
-		}))
+		return borrowingBlocked(err)
 	}
 
 	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
@@ -91,24 +211,23 @@ func (uc *UserController) CheckoutBook(c buffalo.Context) error {
 func (uc *UserController) ReturnBook(c buffalo.Context) error {
 	var request Dto.BookActionRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, render.JSON(map[string]string{
-			"error": "Invalid request format",
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
 	request.Email = normalizeEmail(request.Email)
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := uc.UserService.AuthorizeLoanAction(CurrentUser(c), &request); err != nil {
+		return err
+	}
 	log.Printf("Processing return - Book ID: %s, Email: %s", request.BookID, request.Email)
 
 	response, err := uc.UserService.ReturnBook(request)
 	if err != nil {
 		log.Printf("Return failed: %v", err)
-		statusCode := http.StatusBadRequest
-		if strings.Contains(err.Error(), "No active loan found") {
-			statusCode = http.StatusNotFound
-		}
-		return c.Render(statusCode, render.JSON(map[string]string{
-			"error": err.Error(),
-		}))
+		return err
 	}
 
 	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
@@ -120,24 +239,23 @@ func (uc *UserController) ReturnBook(c buffalo.Context) error {
 func (uc *UserController) ReserveBook(c buffalo.Context) error {
 	var request Dto.BookActionRequest
 	if err := c.Bind(&request); err != nil {
-		return c.Render(http.StatusBadRequest, render.JSON(map[string]string{
-			"error": "Invalid request format",
-		}))
+		return problem.New(http.StatusBadRequest, "Invalid request format")
 	}
 
 	request.Email = normalizeEmail(request.Email)
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	if err := uc.UserService.AuthorizeLoanAction(CurrentUser(c), &request); err != nil {
+		return err
+	}
 	log.Printf("Processing reservation - Book ID: %s, Email: %s", request.BookID, request.Email)
 
 	response, err := uc.UserService.ReserveBook(request)
 	if err != nil {
 		log.Printf("Reservation failed: %v", err)
-		statusCode := http.StatusBadRequest
-		if strings.Contains(err.Error(), "not available") {
-			statusCode = http.StatusConflict
-		}
-		return c.Render(statusCode, render.JSON(map[string]string{
-			"error": err.Error(),
-		}))
+		return borrowingBlocked(err)
 	}
 
 	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
@@ -146,6 +264,329 @@ func (uc *UserController) ReserveBook(c buffalo.Context) error {
 	}))
 }
 
+// ListLoans lists the signed-in user's loans, including returned ones.
+func (uc *UserController) ListLoans(c buffalo.Context) error {
+	return uc.renderLoans(c, models.LoanKindLoan, "loans")
+}
+
+// ListHolds lists the signed-in user's holds.
+func (uc *UserController) ListHolds(c buffalo.Context) error {
+	return uc.renderLoans(c, models.LoanKindHold, "holds")
+}
+
+func (uc *UserController) renderLoans(c buffalo.Context, kind, key string) error {
+	loans, err := uc.UserService.ListLoans(CurrentUser(c).ID, kind)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		key:      loans,
+	}))
+}
+
+// ReturnLoan returns the book on the loan named in the path.
+func (uc *UserController) ReturnLoan(c buffalo.Context) error {
+	loanID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid loan ID format")
+	}
+
+	response, err := uc.UserService.ReturnLoan(CurrentUser(c), loanID)
+	if err != nil {
+		log.Printf("Return failed: %v", err)
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"return": response,
+	}))
+}
+
+func (uc *UserController) GetProfile(c buffalo.Context) error {
+	user, err := uc.UserService.GetProfile(CurrentUser(c).ID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+func (uc *UserController) UpdateProfile(c buffalo.Context) error {
+	var request Dto.ProfileUpdateRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	user, emailChanged, err := uc.UserService.UpdateProfile(CurrentUser(c).ID, request)
+	if err != nil {
+		return err
+	}
+
+	response := map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}
+	if emailChanged {
+		verificationSent := true
+		if err := uc.VerificationService.SendVerification(user.ID); err != nil {
+			log.Printf("Failed to send verification email to %s: %v", user.Email, err)
+			verificationSent = false
+		}
+		response["verification_sent"] = verificationSent
+	}
+
+	return c.Render(http.StatusOK, render.JSON(response))
+}
+
+func (uc *UserController) CloseAccount(c buffalo.Context) error {
+	if err := uc.UserService.CloseAccount(CurrentUser(c).ID); err != nil {
+		return err
+	}
+
+	session := c.Session()
+	session.Clear()
+	session.Save()
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status":  "success",
+		"message": "Your account has been closed",
+	}))
+}
+
+func (uc *UserController) ChangeUserRole(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	var request Dto.UserRoleRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	user, err := uc.UserService.ChangeUserRole(userID, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+func (uc *UserController) ListUsers(c buffalo.Context) error {
+	page, _ := strconv.Atoi(c.Param("page"))
+	perPage, _ := strconv.Atoi(c.Param("per_page"))
+
+	users, err := uc.UserService.ListUsers(Dto.UserSearchRequest{
+		Query:      c.Param("q"),
+		Status:     c.Param("status"),
+		PatronType: c.Param("patron_type"),
+		Page:       page,
+		PerPage:    perPage,
+	})
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(users))
+}
+
+func (uc *UserController) GetUserDetail(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	detail, err := uc.UserService.GetUserDetail(userID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(detail))
+}
+
+func (uc *UserController) ChangePatronType(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	var request Dto.PatronTypeRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	user, err := uc.UserService.ChangePatronType(userID, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+func (uc *UserController) SuspendUser(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	var request Dto.SuspensionRequest
+	if err := c.Bind(&request); err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid request format")
+	}
+
+	if err := validation.Struct(request); err != nil {
+		return err
+	}
+
+	user, err := uc.UserService.SuspendUser(CurrentUser(c), userID, request)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+func (uc *UserController) ReinstateUser(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	user, err := uc.UserService.ReinstateUser(CurrentUser(c), userID)
+	if err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]interface{}{
+		"status": "success",
+		"user":   user,
+	}))
+}
+
+// UnlockUser lifts a login lockout on the user's account.
+func (uc *UserController) UnlockUser(c buffalo.Context) error {
+	userID, err := parseUUID(c.Param("id"))
+	if err != nil {
+		return problem.New(http.StatusBadRequest, "Invalid user ID format")
+	}
+
+	if err := uc.LoginThrottle.Unlock(CurrentUser(c), userID); err != nil {
+		return err
+	}
+
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status": "success",
+	}))
+}
+
+// CurrentUser returns the signed-in user loaded by the SetCurrentUser middleware, or nil.
+func CurrentUser(c buffalo.Context) *models.User {
+	user, _ := c.Value(currentUserKey).(*models.User)
+	return user
+}
+
+// CurrentSessionID returns the server-side session the request was authenticated with,
+// or uuid.Nil for token-authenticated and anonymous requests.
+func CurrentSessionID(c buffalo.Context) uuid.UUID {
+	sessionID, _ := c.Value(currentSessionIDKey).(uuid.UUID)
+	return sessionID
+}
+
+// HasPendingSignIn reports whether the session is parked between the password and the
+// two-factor code, which CSRF protection must cover like a full sign-in.
+func HasPendingSignIn(c buffalo.Context) bool {
+	return c.Session().Get(pendingUserIDKey) != nil
+}
+
+// startSession replaces whatever the session held with a fresh sign-in for the user,
+// recorded server-side so it can be listed and revoked.
+func startSession(c buffalo.Context, sessions *services.SessionServices, userID uuid.UUID) error {
+	record, err := sessions.StartSession(userID, c.Request().UserAgent(), clientIP(c.Request()))
+	if err != nil {
+		return err
+	}
+
+	session := c.Session()
+	session.Clear()
+	session.Set(userIDKey, userID.String())
+	session.Set(sessionIDKey, record.ID.String())
+	if _, err := issueCSRFToken(c); err != nil {
+		return err
+	}
+	return session.Save()
+}
+
+// beginSignIn starts a session once the first factor has been checked. Users with
+// two-factor enabled are parked instead until a code is posted to CompleteLogin; the
+// result says whether that is needed.
+func beginSignIn(c buffalo.Context, sessions *services.SessionServices, userID uuid.UUID, twoFactor bool) (bool, error) {
+	if !twoFactor {
+		return false, startSession(c, sessions, userID)
+	}
+
+	session := c.Session()
+	session.Clear()
+	session.Set(pendingUserIDKey, userID.String())
+	session.Set(pendingStartedKey, time.Now().Unix())
+	if _, err := issueCSRFToken(c); err != nil {
+		return true, err
+	}
+	return true, session.Save()
+}
+
+func renderTwoFactorRequired(c buffalo.Context) error {
+	return c.Render(http.StatusOK, render.JSON(map[string]string{
+		"status":  "two_factor_required",
+		"message": "Enter the code from your authenticator app or a recovery code",
+	}))
+}
+
+// loginLocked answers a throttled login with 429 and a Retry-After header.
+func loginLocked(c buffalo.Context, err error) error {
+	var locked *services.LoginLockedError
+	if errors.As(err, &locked) {
+		c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+	}
+	return problem.New(http.StatusTooManyRequests, err.Error())
+}
+
+// clientIP is the address the connection came from. Forwarding headers are ignored
+// because any client can set them.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 func normalizeEmail(email string) string {
 	return strings.ToLower(strings.TrimSpace(email))
 }
@@ -0,0 +1,114 @@
+// Package graph serves the catalogue and circulation as a GraphQL schema, so pages can
+// fetch a book with its current loan and holder in one round trip. Resolvers go through
+// the same services as the REST controllers and apply the same access rules.
+package graph
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"log"
+
+	"github.com/graph-gophers/graphql-go"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/services"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// maxDepth stops cyclic queries such as book { currentLoan { holder { loans { book ... } } } }
+// from fanning out without bound.
+const maxDepth = 8
+
+// Viewer is who the request is made as. Token is set for bearer-token requests, whose
+// scopes limit what mutations they may run.
+type Viewer struct {
+	User  *models.User
+	Token *models.APIToken
+}
+
+type Service struct {
+	schema *graphql.Schema
+	books  *services.BookServices
+	users  *services.UserServices
+}
+
+func New(books *services.BookServices, users *services.UserServices) *Service {
+	root := &resolver{books: books, users: users}
+	return &Service{
+		schema: graphql.MustParseSchema(schemaSDL, root, graphql.UseStringDescriptions(), graphql.MaxDepth(maxDepth)),
+		books:  books,
+		users:  users,
+	}
+}
+
+// Exec runs one GraphQL operation. Each call gets its own loaders, so nothing cached for
+// one viewer is ever served to another.
+func (s *Service) Exec(ctx context.Context, viewer Viewer, query, operationName string, variables map[string]interface{}) *graphql.Response {
+	ctx = context.WithValue(ctx, viewerKey{}, viewer)
+	ctx = context.WithValue(ctx, loadersKey{}, newLoaders(s.books, s.users))
+	return s.schema.Exec(ctx, query, operationName, variables)
+}
+
+type viewerKey struct{}
+
+type loadersKey struct{}
+
+func viewerFrom(ctx context.Context) Viewer {
+	viewer, _ := ctx.Value(viewerKey{}).(Viewer)
+	return viewer
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+// resolverError carries a machine-readable code in the error's extensions, mirroring the
+// HTTP status the REST API would have used.
+type resolverError struct {
+	err  error
+	code string
+}
+
+func (e *resolverError) Error() string {
+	return e.err.Error()
+}
+
+func (e *resolverError) Unwrap() error {
+	return e.err
+}
+
+func (e *resolverError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+var errUnexpected = errors.New("An unexpected error occurred")
+
+// wrapError classifies a service error for the response.
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var blocked *services.BorrowingBlockedError
+	if errors.As(err, &blocked) {
+		return &resolverError{err: err, code: blocked.Code}
+	}
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		return &resolverError{err: err, code: "NOT_FOUND"}
+	case errors.Is(err, apperrors.ErrConflict), errors.Is(err, apperrors.ErrUnavailable):
+		return &resolverError{err: err, code: "CONFLICT"}
+	case errors.Is(err, apperrors.ErrValidation):
+		return &resolverError{err: err, code: "BAD_REQUEST"}
+	case errors.Is(err, apperrors.ErrForbidden):
+		return &resolverError{err: err, code: "FORBIDDEN"}
+	case errors.Is(err, apperrors.ErrUnauthorized):
+		return &resolverError{err: err, code: "UNAUTHENTICATED"}
+	default:
+		// Like the REST API, keep the cause, which may name tables or hosts, in the log.
+		log.Printf("GraphQL resolver failed: %v", err)
+		return &resolverError{err: errUnexpected, code: "INTERNAL"}
+	}
+}
@@ -0,0 +1,211 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/models"
+	"library-system/repositories/mock"
+	"library-system/services"
+)
+
+// countingLoanRepository records how often the loaders hit the loan repository.
+type countingLoanRepository struct {
+	*mock.MockLoanRepository
+	activeByBooksCalls int
+}
+
+func (r *countingLoanRepository) GetActiveLoansByBooks(bookIDs []uuid.UUID) ([]models.Loan, error) {
+	r.activeByBooksCalls++
+	return r.MockLoanRepository.GetActiveLoansByBooks(bookIDs)
+}
+
+type fixture struct {
+	service   *Service
+	loanRepo  *countingLoanRepository
+	bookRepo  *mock.MockBookRepository
+	patron    *models.User
+	other     *models.User
+	librarian *models.User
+	books     []models.Book
+}
+
+func setupGraph() *fixture {
+	verifiedAt := time.Now()
+	f := &fixture{
+		patron: &models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com", Role: models.RolePatron, EmailVerifiedAt: &verifiedAt},
+		other:  &models.User{ID: uuid.Must(uuid.NewV4()), Name: "bola ade", Email: "bola@example.com", Role: models.RolePatron, EmailVerifiedAt: &verifiedAt},
+		librarian: &models.User{ID: uuid.Must(uuid.NewV4()), Name: "chidi okeke", Email: "chidi@example.com", Role: models.RoleLibrarian,
+			EmailVerifiedAt: &verifiedAt, TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabledAt: &verifiedAt},
+	}
+	f.books = []models.Book{
+		{ID: uuid.Must(uuid.NewV4()), Title: "Dune", Author: "Frank Herbert", ISBN: "0-441-17271-7", Status: models.StatusBorrowed},
+		{ID: uuid.Must(uuid.NewV4()), Title: "Emma", Author: "Jane Austen", ISBN: "0-14-143958-7", Status: models.StatusBorrowed},
+		{ID: uuid.Must(uuid.NewV4()), Title: "Ulysses", Author: "James Joyce", ISBN: "0-394-74312-9", Status: models.StatusAvailable},
+	}
+	loans := []models.Loan{
+		{ID: uuid.Must(uuid.NewV4()), BookID: f.books[0].ID, UserID: f.patron.ID, Email: f.patron.Email, Kind: models.LoanKindLoan, LoanDate: time.Now()},
+		{ID: uuid.Must(uuid.NewV4()), BookID: f.books[1].ID, UserID: f.other.ID, Email: f.other.Email, Kind: models.LoanKindLoan, LoanDate: time.Now()},
+	}
+
+	f.bookRepo = &mock.MockBookRepository{MockBooks: f.books}
+	f.loanRepo = &countingLoanRepository{MockLoanRepository: &mock.MockLoanRepository{MockLoans: loans}}
+	userRepo := &mock.MockUserRepo{MockUser: []models.User{*f.patron, *f.other, *f.librarian}}
+	f.service = New(
+		services.NewBookServices(f.bookRepo),
+		&services.UserServices{UserRepo: userRepo, BookRepo: f.bookRepo, LoanRepo: f.loanRepo, FineRepo: &mock.MockFineRepository{}},
+	)
+	return f
+}
+
+type result struct {
+	Data   map[string]interface{}
+	Errors []struct {
+		Message    string
+		Extensions map[string]interface{}
+	}
+}
+
+func (f *fixture) exec(t *testing.T, viewer Viewer, query string, variables map[string]interface{}) result {
+	t.Helper()
+	response := f.service.Exec(context.Background(), viewer, query, "", variables)
+	body, err := json.Marshal(response)
+	assert.NoError(t, err)
+	var r result
+	assert.NoError(t, json.Unmarshal(body, &r))
+	return r
+}
+
+const booksWithHolders = `{ books { title currentLoan { holder { name } } } }`
+
+func TestBooksBatchesLoanLookups(t *testing.T) {
+	f := setupGraph()
+
+	r := f.exec(t, Viewer{User: f.librarian}, booksWithHolders, nil)
+
+	assert.Empty(t, r.Errors)
+	books := r.Data["books"].([]interface{})
+	if assert.Len(t, books, 3) {
+		holders := map[string]interface{}{}
+		for _, b := range books {
+			book := b.(map[string]interface{})
+			if loan, ok := book["currentLoan"].(map[string]interface{}); ok {
+				holders[book["title"].(string)] = loan["holder"].(map[string]interface{})["name"]
+			}
+		}
+		assert.Equal(t, map[string]interface{}{"Dune": "aminat usman", "Emma": "bola ade"}, holders)
+	}
+	assert.Equal(t, 1, f.loanRepo.activeByBooksCalls)
+}
+
+func TestPatronsOnlySeeTheirOwnLoans(t *testing.T) {
+	f := setupGraph()
+
+	r := f.exec(t, Viewer{User: f.patron}, booksWithHolders, nil)
+	assert.Empty(t, r.Errors)
+	books := r.Data["books"].([]interface{})
+	assert.NotNil(t, books[0].(map[string]interface{})["currentLoan"])
+	assert.Nil(t, books[1].(map[string]interface{})["currentLoan"])
+
+	r = f.exec(t, Viewer{User: f.patron}, `query($id: ID!) { user(id: $id) { name } }`, map[string]interface{}{"id": f.other.ID.String()})
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "FORBIDDEN", r.Errors[0].Extensions["code"])
+	}
+
+	r = f.exec(t, Viewer{User: f.librarian}, `query($id: ID!) { user(id: $id) { name loans { book { title } } } }`, map[string]interface{}{"id": f.other.ID.String()})
+	assert.Empty(t, r.Errors)
+	assert.Equal(t, "bola ade", r.Data["user"].(map[string]interface{})["name"])
+}
+
+func TestStaffNeedTwoFactorAndScopeToSeePatrons(t *testing.T) {
+	f := setupGraph()
+	query := `query($id: ID!) { user(id: $id) { name } }`
+	variables := map[string]interface{}{"id": f.other.ID.String()}
+
+	withoutTwoFactor := *f.librarian
+	withoutTwoFactor.TOTPEnabledAt = nil
+	for name, viewer := range map[string]Viewer{
+		"two-factor disabled": {User: &withoutTwoFactor},
+		"token without scope": {User: f.librarian, Token: &models.APIToken{Scopes: models.ScopeBooksWrite}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			r := f.exec(t, viewer, query, variables)
+			if assert.Len(t, r.Errors, 1) {
+				assert.Equal(t, "FORBIDDEN", r.Errors[0].Extensions["code"])
+			}
+		})
+	}
+
+	r := f.exec(t, Viewer{User: f.librarian, Token: &models.APIToken{Scopes: models.ScopeLoansWrite}}, query, variables)
+	assert.Empty(t, r.Errors)
+}
+
+func TestMeIsNullWhenAnonymous(t *testing.T) {
+	f := setupGraph()
+
+	r := f.exec(t, Viewer{}, `{ me { name } }`, nil)
+
+	assert.Empty(t, r.Errors)
+	assert.Nil(t, r.Data["me"])
+}
+
+func TestCheckoutAndReturn(t *testing.T) {
+	f := setupGraph()
+	variables := map[string]interface{}{"book": f.books[2].ID.String()}
+
+	r := f.exec(t, Viewer{User: f.other}, `mutation($book: ID!) { checkout(bookId: $book) { id kind book { status } holder { email } } }`, variables)
+	assert.Empty(t, r.Errors)
+	loan := r.Data["checkout"].(map[string]interface{})
+	assert.Equal(t, models.LoanKindLoan, loan["kind"])
+	assert.Equal(t, models.StatusBorrowed, loan["book"].(map[string]interface{})["status"])
+	assert.Equal(t, f.other.Email, loan["holder"].(map[string]interface{})["email"])
+
+	r = f.exec(t, Viewer{User: f.other}, `mutation($id: ID!) { returnLoan(id: $id) { returnDate book { status } } }`, map[string]interface{}{"id": loan["id"]})
+	assert.Empty(t, r.Errors)
+	returned := r.Data["returnLoan"].(map[string]interface{})
+	assert.NotNil(t, returned["returnDate"])
+	assert.Equal(t, models.StatusAvailable, returned["book"].(map[string]interface{})["status"])
+}
+
+func TestMutationsCheckAccess(t *testing.T) {
+	f := setupGraph()
+	checkout := `mutation($book: ID!, $email: String) { checkout(bookId: $book, email: $email) { id } }`
+	variables := map[string]interface{}{"book": f.books[2].ID.String()}
+
+	r := f.exec(t, Viewer{}, checkout, variables)
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "UNAUTHENTICATED", r.Errors[0].Extensions["code"])
+	}
+
+	r = f.exec(t, Viewer{User: f.patron, Token: &models.APIToken{Scopes: models.ScopeBooksWrite}}, checkout, variables)
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "FORBIDDEN", r.Errors[0].Extensions["code"])
+	}
+
+	r = f.exec(t, Viewer{User: f.patron}, checkout, map[string]interface{}{"book": f.books[2].ID.String(), "email": f.other.Email})
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "FORBIDDEN", r.Errors[0].Extensions["code"])
+	}
+
+	r = f.exec(t, Viewer{User: f.patron}, checkout, map[string]interface{}{"book": f.books[0].ID.String()})
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "CONFLICT", r.Errors[0].Extensions["code"])
+	}
+}
+
+func TestUnexpectedErrorsHideTheirCause(t *testing.T) {
+	f := setupGraph()
+	f.bookRepo.GetAllBooksError = errors.New("dial tcp 10.0.0.5:3306: connection refused")
+
+	r := f.exec(t, Viewer{}, `{ books { title } }`, nil)
+
+	if assert.Len(t, r.Errors, 1) {
+		assert.Equal(t, "INTERNAL", r.Errors[0].Extensions["code"])
+		assert.Equal(t, "An unexpected error occurred", r.Errors[0].Message)
+	}
+}
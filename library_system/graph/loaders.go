@@ -0,0 +1,78 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/graph-gophers/dataloader/v7"
+	"library-system/Dto"
+	"library-system/services"
+)
+
+// loaders batch the lookups resolvers make for nested fields, so a list of books with
+// their current loans and holders costs one query per level instead of one per row.
+// They cache for the lifetime of a request and are created fresh for each one.
+type loaders struct {
+	books     *dataloader.Loader[uuid.UUID, *Dto.BookResponse]
+	users     *dataloader.Loader[uuid.UUID, *Dto.UserResponse]
+	userLoans *dataloader.Loader[uuid.UUID, []Dto.LoanResponse]
+	bookLoans *dataloader.Loader[uuid.UUID, []Dto.LoanResponse]
+}
+
+func newLoaders(books *services.BookServices, users *services.UserServices) *loaders {
+	return &loaders{
+		books: dataloader.NewBatchedLoader(func(_ context.Context, ids []uuid.UUID) []*dataloader.Result[*Dto.BookResponse] {
+			found, err := books.GetBooksByIDs(ids)
+			byID := map[uuid.UUID]*Dto.BookResponse{}
+			for i := range found {
+				byID[found[i].ID] = &found[i]
+			}
+			return results(ids, err, func(id uuid.UUID) *Dto.BookResponse { return byID[id] })
+		}),
+		users: dataloader.NewBatchedLoader(func(_ context.Context, ids []uuid.UUID) []*dataloader.Result[*Dto.UserResponse] {
+			found, err := users.GetUsersByIDs(ids)
+			byID := map[uuid.UUID]*Dto.UserResponse{}
+			for i := range found {
+				byID[found[i].ID] = &found[i]
+			}
+			return results(ids, err, func(id uuid.UUID) *Dto.UserResponse { return byID[id] })
+		}),
+		userLoans: dataloader.NewBatchedLoader(func(_ context.Context, ids []uuid.UUID) []*dataloader.Result[[]Dto.LoanResponse] {
+			found, err := users.ListLoansForUsers(ids)
+			byUser := map[uuid.UUID][]Dto.LoanResponse{}
+			for _, loan := range found {
+				byUser[loan.UserID] = append(byUser[loan.UserID], loan)
+			}
+			return results(ids, err, func(id uuid.UUID) []Dto.LoanResponse { return byUser[id] })
+		}),
+		bookLoans: dataloader.NewBatchedLoader(func(_ context.Context, ids []uuid.UUID) []*dataloader.Result[[]Dto.LoanResponse] {
+			found, err := users.ListActiveLoansForBooks(ids)
+			byBook := map[uuid.UUID][]Dto.LoanResponse{}
+			for _, loan := range found {
+				byBook[loan.BookID] = append(byBook[loan.BookID], loan)
+			}
+			return results(ids, err, func(id uuid.UUID) []Dto.LoanResponse { return byBook[id] })
+		}),
+	}
+}
+
+// results answers every key in a batch, in order, with its value or the batch's error.
+func results[V any](ids []uuid.UUID, err error, value func(uuid.UUID) V) []*dataloader.Result[V] {
+	out := make([]*dataloader.Result[V], len(ids))
+	for i, id := range ids {
+		if err != nil {
+			out[i] = &dataloader.Result[V]{Error: err}
+			continue
+		}
+		out[i] = &dataloader.Result[V]{Data: value(id)}
+	}
+	return out
+}
+
+// clear drops everything cached so far, after a mutation has changed it.
+func (l *loaders) clear() {
+	l.books.ClearAll()
+	l.users.ClearAll()
+	l.userLoans.ClearAll()
+	l.bookLoans.ClearAll()
+}
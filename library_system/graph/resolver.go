@@ -0,0 +1,200 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"github.com/graph-gophers/graphql-go"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/services"
+)
+
+// resolver is the root of both Query and Mutation.
+type resolver struct {
+	books *services.BookServices
+	users *services.UserServices
+}
+
+func (r *resolver) Books(ctx context.Context, args struct{ Search *string }) ([]*bookResolver, error) {
+	var books []Dto.BookResponse
+	var err error
+	if args.Search != nil && *args.Search != "" {
+		books, err = r.books.SearchBook(*args.Search)
+	} else {
+		books, err = r.books.GetAllBooks()
+	}
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	resolvers := make([]*bookResolver, len(books))
+	for i := range books {
+		resolvers[i] = &bookResolver{book: &books[i]}
+	}
+	return resolvers, nil
+}
+
+func (r *resolver) Book(ctx context.Context, args struct{ ID graphql.ID }) (*bookResolver, error) {
+	bookID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	return loadBook(ctx, bookID)
+}
+
+func (r *resolver) Me(ctx context.Context) (*userResolver, error) {
+	viewer := viewerFrom(ctx)
+	if viewer.User == nil {
+		return nil, nil
+	}
+	return loadUser(ctx, viewer.User.ID)
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	userID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !canSeeUser(viewerFrom(ctx), userID) {
+		return nil, wrapError(apperrors.Forbidden("You can only look up your own account"))
+	}
+	return loadUser(ctx, userID)
+}
+
+type loanActionArgs struct {
+	BookID     graphql.ID
+	Email      *string
+	CardNumber *string
+}
+
+func (r *resolver) Checkout(ctx context.Context, args loanActionArgs) (*loanResolver, error) {
+	request, err := r.loanActionRequest(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.users.CheckOutBook(*request)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return actionResolver(ctx, response, models.LoanKindLoan), nil
+}
+
+func (r *resolver) Reserve(ctx context.Context, args loanActionArgs) (*loanResolver, error) {
+	request, err := r.loanActionRequest(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.users.ReserveBook(*request)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return actionResolver(ctx, response, models.LoanKindHold), nil
+}
+
+func (r *resolver) ReturnLoan(ctx context.Context, args struct{ ID graphql.ID }) (*loanResolver, error) {
+	actor, err := loanActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	loanID, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	response, err := r.users.ReturnLoan(actor, loanID)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return actionResolver(ctx, response, models.LoanKindLoan), nil
+}
+
+// loanActionRequest applies the rules of the REST checkout and reserve endpoints: the
+// patron defaults to the viewer, and only desk staff may name someone else.
+func (r *resolver) loanActionRequest(ctx context.Context, args loanActionArgs) (*Dto.BookActionRequest, error) {
+	actor, err := loanActor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	bookID, err := parseID(args.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &Dto.BookActionRequest{BookID: bookID}
+	if args.Email != nil {
+		request.Email = *args.Email
+	}
+	if args.CardNumber != nil {
+		request.CardNumber = *args.CardNumber
+	}
+	if err := r.users.AuthorizeLoanAction(actor, request); err != nil {
+		return nil, wrapError(err)
+	}
+	return request, nil
+}
+
+// loanActor is the signed-in user, provided a bearer token carries the loans:write scope.
+func loanActor(ctx context.Context) (*models.User, error) {
+	viewer := viewerFrom(ctx)
+	if viewer.User == nil {
+		return nil, wrapError(apperrors.Unauthorized("Authentication required"))
+	}
+	if viewer.Token != nil && !viewer.Token.HasScope(models.ScopeLoansWrite) {
+		return nil, wrapError(apperrors.Forbidden("Token is missing the %s scope", models.ScopeLoansWrite))
+	}
+	return viewer.User, nil
+}
+
+// actionResolver describes the loan a mutation touched. The loaders are cleared first so
+// fields selected on the result, such as the book's status, reflect the change.
+func actionResolver(ctx context.Context, response *Dto.BookActionResponse, kind string) *loanResolver {
+	loadersFrom(ctx).clear()
+	return &loanResolver{loan: Dto.LoanResponse{
+		ID:         response.ID,
+		BookID:     response.BookID,
+		UserID:     response.UserID,
+		Kind:       kind,
+		LoanDate:   response.LoanDate,
+		ReturnDate: response.ReturnDate,
+	}}
+}
+
+// canSeeUser reports whether the viewer may see a user's details and loans: their own,
+// or anyone's for staff who pass the checks of the REST desk routes.
+func canSeeUser(viewer Viewer, userID uuid.UUID) bool {
+	if viewer.User == nil {
+		return false
+	}
+	return viewer.User.ID == userID || services.CanViewPatrons(viewer.User, viewer.Token)
+}
+
+func parseID(id graphql.ID) (uuid.UUID, error) {
+	parsed, err := uuid.FromString(string(id))
+	if err != nil {
+		return uuid.Nil, wrapError(apperrors.Validation("Invalid ID %q", string(id)))
+	}
+	return parsed, nil
+}
+
+func loadBook(ctx context.Context, bookID uuid.UUID) (*bookResolver, error) {
+	book, err := loadersFrom(ctx).books.Load(ctx, bookID)()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if book == nil {
+		return nil, nil
+	}
+	return &bookResolver{book: book}, nil
+}
+
+func loadUser(ctx context.Context, userID uuid.UUID) (*userResolver, error) {
+	user, err := loadersFrom(ctx).users.Load(ctx, userID)()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+	return &userResolver{user: user}, nil
+}
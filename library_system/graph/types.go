@@ -0,0 +1,155 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/graph-gophers/graphql-go"
+	"library-system/Dto"
+	"library-system/models"
+)
+
+type bookResolver struct {
+	book *Dto.BookResponse
+}
+
+func (b *bookResolver) ID() graphql.ID {
+	return graphql.ID(b.book.ID.String())
+}
+
+func (b *bookResolver) Title() string {
+	return b.book.Title
+}
+
+func (b *bookResolver) Author() string {
+	return b.book.Author
+}
+
+func (b *bookResolver) ISBN() string {
+	return b.book.ISBN
+}
+
+func (b *bookResolver) Status() string {
+	return b.book.Status
+}
+
+func (b *bookResolver) CurrentLoan(ctx context.Context) (*loanResolver, error) {
+	loans, err := b.visibleLoans(ctx, models.LoanKindLoan)
+	if err != nil || len(loans) == 0 {
+		return nil, err
+	}
+	return loans[0], nil
+}
+
+func (b *bookResolver) Holds(ctx context.Context) ([]*loanResolver, error) {
+	return b.visibleLoans(ctx, models.LoanKindHold)
+}
+
+// visibleLoans returns the open loans of the given kind that the viewer may see.
+func (b *bookResolver) visibleLoans(ctx context.Context, kind string) ([]*loanResolver, error) {
+	loans, err := loadersFrom(ctx).bookLoans.Load(ctx, b.book.ID)()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	viewer := viewerFrom(ctx)
+	resolvers := []*loanResolver{}
+	for _, loan := range loans {
+		if loan.Kind == kind && canSeeUser(viewer, loan.UserID) {
+			resolvers = append(resolvers, &loanResolver{loan: loan})
+		}
+	}
+	return resolvers, nil
+}
+
+type userResolver struct {
+	user *Dto.UserResponse
+}
+
+func (u *userResolver) ID() graphql.ID {
+	return graphql.ID(u.user.ID.String())
+}
+
+func (u *userResolver) Name() string {
+	return u.user.Name
+}
+
+func (u *userResolver) Email() string {
+	return u.user.Email
+}
+
+func (u *userResolver) CardNumber() string {
+	return u.user.CardNumber
+}
+
+func (u *userResolver) Role() string {
+	return u.user.Role
+}
+
+func (u *userResolver) PatronType() string {
+	return u.user.PatronType
+}
+
+func (u *userResolver) Status() string {
+	return u.user.Status
+}
+
+type loanFilterArgs struct {
+	Active *bool
+}
+
+func (u *userResolver) Loans(ctx context.Context, args loanFilterArgs) ([]*loanResolver, error) {
+	return u.loans(ctx, models.LoanKindLoan, args)
+}
+
+func (u *userResolver) Holds(ctx context.Context, args loanFilterArgs) ([]*loanResolver, error) {
+	return u.loans(ctx, models.LoanKindHold, args)
+}
+
+func (u *userResolver) loans(ctx context.Context, kind string, args loanFilterArgs) ([]*loanResolver, error) {
+	loans, err := loadersFrom(ctx).userLoans.Load(ctx, u.user.ID)()
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	activeOnly := args.Active != nil && *args.Active
+	resolvers := []*loanResolver{}
+	for _, loan := range loans {
+		if loan.Kind != kind || (activeOnly && loan.ReturnDate != nil) {
+			continue
+		}
+		resolvers = append(resolvers, &loanResolver{loan: loan})
+	}
+	return resolvers, nil
+}
+
+type loanResolver struct {
+	loan Dto.LoanResponse
+}
+
+func (l *loanResolver) ID() graphql.ID {
+	return graphql.ID(l.loan.ID.String())
+}
+
+func (l *loanResolver) Kind() string {
+	return l.loan.Kind
+}
+
+func (l *loanResolver) LoanDate() graphql.Time {
+	return graphql.Time{Time: l.loan.LoanDate}
+}
+
+func (l *loanResolver) ReturnDate() *graphql.Time {
+	if l.loan.ReturnDate == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *l.loan.ReturnDate}
+}
+
+func (l *loanResolver) Book(ctx context.Context) (*bookResolver, error) {
+	return loadBook(ctx, l.loan.BookID)
+}
+
+func (l *loanResolver) Holder(ctx context.Context) (*userResolver, error) {
+	if !canSeeUser(viewerFrom(ctx), l.loan.UserID) {
+		return nil, nil
+	}
+	return loadUser(ctx, l.loan.UserID)
+}
@@ -1,7 +1,7 @@
 package grifts
 
 import (
-	"library_system/actions"
+	"library-system/actions"
 
 	"github.com/gobuffalo/buffalo"
 )
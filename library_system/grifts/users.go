@@ -0,0 +1,92 @@
+package grifts
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/gobuffalo/grift/grift"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"library-system/services"
+)
+
+var _ = grift.Namespace("users", func() {
+
+	grift.Desc("set_role", "Sets a user's role: users:set_role <email> <patron|librarian|admin>")
+	grift.Add("set_role", func(c *grift.Context) error {
+		if len(c.Args) != 2 {
+			return errors.New("usage: users:set_role <email> <patron|librarian|admin>")
+		}
+
+		userService := &services.UserServices{UserRepo: repository.NewUserRepository(models.DB)}
+		user, err := userService.UserRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(c.Args[0])))
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("no user registered with email %s", c.Args[0])
+		}
+
+		updated, err := userService.ChangeUserRole(user.ID, Dto.UserRoleRequest{Role: c.Args[1]})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s is now a %s\n", updated.Email, updated.Role)
+		return nil
+	})
+
+	grift.Desc("export", "Writes a user's personal data archive: users:export <email> [path.zip]")
+	grift.Add("export", func(c *grift.Context) error {
+		if len(c.Args) < 1 || len(c.Args) > 2 {
+			return errors.New("usage: users:export <email> [path.zip]")
+		}
+
+		exportService := &services.ExportServices{
+			UserRepo:  repository.NewUserRepository(models.DB),
+			LoanRepo:  repository.NewLoanRepository(models.DB),
+			BookRepo:  repository.NewBookRepository(models.DB),
+			FineRepo:  repository.NewFineRepository(models.DB),
+			TokenRepo: repository.NewAPITokenRepository(models.DB),
+		}
+		user, err := exportService.UserRepo.GetUserByEmail(strings.ToLower(strings.TrimSpace(c.Args[0])))
+		if err != nil {
+			return err
+		}
+		if user == nil {
+			return fmt.Errorf("no user registered with email %s", c.Args[0])
+		}
+
+		export, err := exportService.ExportUserData(user.ID)
+		if err != nil {
+			return err
+		}
+
+		path := fmt.Sprintf("library-data-%s.zip", user.ID)
+		if len(c.Args) == 2 {
+			path = c.Args[1]
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if err := services.WriteExportArchive(file, export); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote data export for %s to %s\n", user.Email, path)
+		return file.Close()
+	})
+
+	grift.Desc("assign_cards", "Gives every open account without a library card a new card number")
+	grift.Add("assign_cards", func(c *grift.Context) error {
+		userService := &services.UserServices{UserRepo: repository.NewUserRepository(models.DB)}
+		assigned, err := userService.AssignMissingCardNumbers()
+		fmt.Printf("Assigned library cards to %d users\n", assigned)
+		return err
+	})
+
+})
@@ -0,0 +1,97 @@
+package mailers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gobuffalo/buffalo/mail"
+	"github.com/gobuffalo/envy"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers plain-text notification emails. Services depend on this interface
+// only, so tests and development can swap in LogMailer for a real SMTP server.
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns an SMTPMailer when SMTP_HOST is configured and a LogMailer otherwise.
+func New() (Mailer, error) {
+	from := envy.Get("MAIL_FROM", "no-reply@library.local")
+	host := envy.Get("SMTP_HOST", "")
+	if host == "" {
+		return &LogMailer{Path: envy.Get("MAIL_LOG_PATH", ""), From: from}, nil
+	}
+	return NewSMTPMailer(
+		host,
+		envy.Get("SMTP_PORT", "587"),
+		envy.Get("SMTP_USER", ""),
+		envy.Get("SMTP_PASSWORD", ""),
+		from,
+	)
+}
+
+type SMTPMailer struct {
+	Sender mail.Sender
+	From   string
+}
+
+func NewSMTPMailer(host, port, user, password, from string) (*SMTPMailer, error) {
+	sender, err := mail.NewSMTPSender(host, port, user, password)
+	if err != nil {
+		return nil, fmt.Errorf("error configuring SMTP sender: %w", err)
+	}
+	return &SMTPMailer{Sender: sender, From: from}, nil
+}
+
+func (m *SMTPMailer) Send(msg Message) error {
+	message := mail.NewMessage()
+	message.From = m.From
+	message.To = []string{msg.To}
+	message.Subject = msg.Subject
+	message.Bodies = append(message.Bodies, mail.Body{
+		Content:     msg.Body,
+		ContentType: "text/plain",
+	})
+	return m.Sender.Send(message)
+}
+
+// LogMailer writes messages to a file, or to the standard logger when Path is empty,
+// instead of delivering them.
+type LogMailer struct {
+	Path string
+	From string
+	mu   sync.Mutex
+}
+
+func (m *LogMailer) Send(msg Message) error {
+	entry := fmt.Sprintf("Date: %s\nFrom: %s\nTo: %s\nSubject: %s\n\n%s\n---\n",
+		time.Now().Format(time.RFC1123Z), m.From, msg.To, msg.Subject, msg.Body)
+
+	if m.Path == "" {
+		log.Printf("Outgoing mail:\n%s", entry)
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	f, err := os.OpenFile(m.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening mail log: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.WriteString(entry); err != nil {
+		return fmt.Errorf("error writing mail log: %w", err)
+	}
+	return nil
+}
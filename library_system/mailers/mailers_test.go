@@ -0,0 +1,26 @@
+package mailers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogMailer_WritesMessagesToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "mail.log")
+	mailer := &LogMailer{Path: path, From: "no-reply@library.local"}
+
+	err := mailer.Send(Message{To: "meenah20@gmail.com", Subject: "Verify your email", Body: "Follow the link"})
+	assert.NoError(t, err)
+	err = mailer.Send(Message{To: "other@gmail.com", Subject: "Second", Body: "Another"})
+	assert.NoError(t, err)
+
+	contents, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Contains(t, string(contents), "To: meenah20@gmail.com")
+	assert.Contains(t, string(contents), "Subject: Verify your email")
+	assert.Contains(t, string(contents), "Follow the link")
+	assert.Contains(t, string(contents), "To: other@gmail.com")
+}
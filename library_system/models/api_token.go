@@ -0,0 +1,73 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"strings"
+	"time"
+)
+
+const (
+	ScopeBooksWrite = "books:write"
+	ScopeLoansWrite = "loans:write"
+	ScopeUsersAdmin = "users:admin"
+)
+
+// roleScopes lists the scopes a token may carry for an owner with the given role,
+// so a token can never do more than its owner could.
+var roleScopes = map[string][]string{
+	RolePatron:    {ScopeLoansWrite},
+	RoleLibrarian: {ScopeBooksWrite, ScopeLoansWrite},
+	RoleAdmin:     {ScopeBooksWrite, ScopeLoansWrite, ScopeUsersAdmin},
+}
+
+type APIToken struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	TokenHash  string     `json:"-" db:"token_hash"`
+	Scopes     string     `json:"scopes" db:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (t *APIToken) Validate() error {
+	if t.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		return errors.New("token name is required")
+	}
+	if t.TokenHash == "" {
+		return errors.New("token hash is required")
+	}
+	return nil
+}
+
+func (t *APIToken) ScopeList() []string {
+	return strings.Fields(t.Scopes)
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *APIToken) IsActive(now time.Time) bool {
+	if t.RevokedAt != nil {
+		return false
+	}
+	return t.ExpiresAt == nil || now.Before(*t.ExpiresAt)
+}
+
+func ScopesForRole(role string) []string {
+	return roleScopes[role]
+}
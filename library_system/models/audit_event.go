@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditAddressLocked   = "ip_locked"
+)
+
+// AuditEvent records a security-relevant action. ActorID is nil for actions the system
+// took on its own, and UserID is nil when the action did not concern a known account.
+type AuditEvent struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	Action    string     `json:"action" db:"action"`
+	ActorID   *uuid.UUID `json:"actor_id" db:"actor_id"`
+	UserID    *uuid.UUID `json:"user_id" db:"user_id"`
+	IPAddress string     `json:"ip_address" db:"ip_address"`
+	Detail    string     `json:"detail" db:"detail"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (e *AuditEvent) Validate() error {
+	if e.Action == "" {
+		return errors.New("audit action is required")
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"errors"
+	"fmt"
 	"github.com/gofrs/uuid"
 	"strings"
 	"time"
@@ -12,8 +13,26 @@ const (
 	StatusAvailable = "available"
 	StatusBorrowed  = "borrowed"
 	StatusReserved  = "reserved"
+	StatusLost      = "lost"
+	StatusDamaged   = "damaged"
+	StatusInRepair  = "in_repair"
+	StatusInTransit = "in_transit"
+	StatusWithdrawn = "withdrawn"
 )
 
+// bookStatusTransitions lists, for every status, the statuses a book may move to next.
+// Withdrawn is terminal: a withdrawn copy has left the collection for good.
+var bookStatusTransitions = map[string][]string{
+	StatusAvailable: {StatusBorrowed, StatusReserved, StatusLost, StatusDamaged, StatusInRepair, StatusInTransit, StatusWithdrawn},
+	StatusBorrowed:  {StatusAvailable, StatusLost, StatusDamaged},
+	StatusReserved:  {StatusAvailable, StatusBorrowed, StatusInTransit, StatusLost},
+	StatusLost:      {StatusAvailable, StatusWithdrawn},
+	StatusDamaged:   {StatusInRepair, StatusAvailable, StatusWithdrawn},
+	StatusInRepair:  {StatusAvailable, StatusDamaged, StatusWithdrawn},
+	StatusInTransit: {StatusAvailable, StatusReserved, StatusLost},
+	StatusWithdrawn: {},
+}
+
 type Book struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	Title     string    `json:"title" db:"title"`
@@ -41,16 +60,36 @@ func (b *Book) Validate() error {
 		return errors.New("invalid ISBN format")
 	}
 
-	validStatuses := map[string]bool{
-		StatusAvailable: true,
-		StatusBorrowed:  true,
-		StatusReserved:  true,
+	if !IsValidBookStatus(b.Status) {
+		return errors.New("invalid status value")
 	}
 
-	if !validStatuses[b.Status] {
-		return errors.New("invalid status value")
+	return nil
+}
+
+func IsValidBookStatus(status string) bool {
+	_, ok := bookStatusTransitions[status]
+	return ok
+}
+
+func CanTransitionBookStatus(from, to string) bool {
+	for _, next := range bookStatusTransitions[from] {
+		if next == to {
+			return true
+		}
 	}
+	return false
+}
 
+// TransitionTo moves the book to the given status, refusing moves the state machine does not allow.
+func (b *Book) TransitionTo(status string) error {
+	if !IsValidBookStatus(status) {
+		return fmt.Errorf("invalid status value: %s", status)
+	}
+	if !CanTransitionBookStatus(b.Status, status) {
+		return fmt.Errorf("invalid status transition from %s to %s", b.Status, status)
+	}
+	b.Status = status
 	return nil
 }
 func ValidateISBN(isbn string) bool {
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+type BookStatusChange struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	BookID     uuid.UUID `json:"book_id" db:"book_id"`
+	FromStatus string    `json:"from_status" db:"from_status"`
+	ToStatus   string    `json:"to_status" db:"to_status"`
+	Reason     string    `json:"reason" db:"reason"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+func (c *BookStatusChange) Validate() error {
+	if c.BookID == uuid.Nil {
+		return errors.New("book ID is required")
+	}
+	if c.Reason == "" {
+		return errors.New("reason is required")
+	}
+	if !CanTransitionBookStatus(c.FromStatus, c.ToStatus) {
+		return errors.New("invalid status transition")
+	}
+	return nil
+}
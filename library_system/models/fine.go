@@ -0,0 +1,37 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// Fine is a charge against a patron's account. Amounts are held in cents to avoid
+// floating point rounding.
+type Fine struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UserID      uuid.UUID  `json:"user_id" db:"user_id"`
+	LoanID      *uuid.UUID `json:"loan_id" db:"loan_id"`
+	AmountCents int        `json:"amount_cents" db:"amount_cents"`
+	Reason      string     `json:"reason" db:"reason"`
+	PaidAt      *time.Time `json:"paid_at" db:"paid_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (f *Fine) Validate() error {
+	if f.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if f.AmountCents <= 0 {
+		return errors.New("amount must be positive")
+	}
+	if f.Reason == "" {
+		return errors.New("reason is required")
+	}
+	return nil
+}
+
+func (f *Fine) IsOutstanding() bool {
+	return f.PaidAt == nil
+}
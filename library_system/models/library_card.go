@@ -0,0 +1,67 @@
+package models
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Library cards carry a 14-digit Codabar-style barcode: a fixed prefix identifying the
+// library, a random serial and a trailing Luhn check digit that catches mis-scans and
+// single-digit typos at the desk.
+const (
+	CardNumberPrefix = "2900"
+	CardNumberLength = 14
+)
+
+// GenerateCardNumber returns a new random card number with a valid check digit.
+// Uniqueness is the caller's responsibility.
+func GenerateCardNumber() (string, error) {
+	serialLength := CardNumberLength - len(CardNumberPrefix) - 1
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(serialLength)), nil)
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	payload := fmt.Sprintf("%s%0*d", CardNumberPrefix, serialLength, n)
+	return payload + string(luhnCheckDigit(payload)), nil
+}
+
+// NormalizeCardNumber strips the spaces and dashes printed on cards and the A/B
+// start/stop characters some Codabar scanners pass through.
+func NormalizeCardNumber(card string) string {
+	card = strings.ToUpper(strings.TrimSpace(card))
+	card = strings.Trim(card, "ABCD")
+	return strings.NewReplacer(" ", "", "-", "").Replace(card)
+}
+
+// IsValidCardNumber checks the length, the digits and the Luhn check digit.
+func IsValidCardNumber(card string) bool {
+	if len(card) != CardNumberLength {
+		return false
+	}
+	for _, r := range card {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(card[:len(card)-1]) == card[len(card)-1]
+}
+
+func luhnCheckDigit(payload string) byte {
+	sum := 0
+	double := true
+	for i := len(payload) - 1; i >= 0; i-- {
+		d := int(payload[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return byte('0' + (10-sum%10)%10)
+}
@@ -6,11 +6,18 @@ import (
 	"time"
 )
 
+const (
+	LoanKindLoan = "loan"
+	LoanKindHold = "hold"
+)
+
+// Loan records both checkouts and holds (reservations); Kind tells them apart.
 type Loan struct {
 	ID         uuid.UUID  `json:"id" db:"id"`
 	BookID     uuid.UUID  `json:"book_id" db:"book_id"`
 	Email      string     `json:"email" db:"email"`
 	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	Kind       string     `json:"kind" db:"kind"`
 	LoanDate   time.Time  `json:"loan_date" db:"loan_date"`
 	ReturnDate *time.Time `json:"return_date" db:"return_date"`
 	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
@@ -40,6 +47,10 @@ func (l *Loan) BeforeCreate() error {
 		l.UserID = uuid.Nil
 	}
 
+	if l.Kind == "" {
+		l.Kind = LoanKindLoan
+	}
+
 	now := time.Now()
 	if l.CreatedAt.IsZero() {
 		l.CreatedAt = now
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// LoginAttempt counts recent failed logins for one throttling key, such as an account
+// email or a client IP address. The key is the primary key.
+type LoginAttempt struct {
+	ID            string     `json:"id" db:"id"`
+	Failures      int        `json:"failures" db:"failures"`
+	LastFailureAt time.Time  `json:"last_failure_at" db:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" db:"locked_until"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (a *LoginAttempt) IsLocked(now time.Time) bool {
+	return a.LockedUntil != nil && now.Before(*a.LockedUntil)
+}
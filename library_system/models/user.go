@@ -2,20 +2,165 @@ package models
 
 import (
 	"encoding/json"
+	"fmt"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gobuffalo/validate/v3"
 	"github.com/gobuffalo/validate/v3/validators"
 	"github.com/gofrs/uuid"
+	"golang.org/x/crypto/bcrypt"
 )
 
 type User struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name"`
-	Email     string    `json:"email" db:"email"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID                uuid.UUID    `json:"id" db:"id"`
+	Name              string       `json:"name" db:"name"`
+	Email             string       `json:"email" db:"email"`
+	CardNumber        nulls.String `json:"card_number" db:"card_number"`
+	EmailVerifiedAt   *time.Time   `json:"email_verified_at" db:"email_verified_at"`
+	PasswordHash      string       `json:"-" db:"password_hash"`
+	Role              string       `json:"role" db:"role"`
+	PatronType        string       `json:"patron_type" db:"patron_type"`
+	SessionsRevokedAt *time.Time   `json:"-" db:"sessions_revoked_at"`
+	ClosedAt          *time.Time   `json:"closed_at" db:"closed_at"`
+	SuspendedAt       *time.Time   `json:"suspended_at" db:"suspended_at"`
+	SuspendedUntil    *time.Time   `json:"suspended_until" db:"suspended_until"`
+	SuspensionReason  string       `json:"suspension_reason" db:"suspension_reason"`
+	TOTPSecret        string       `json:"-" db:"totp_secret"`
+	TOTPEnabledAt     *time.Time   `json:"-" db:"totp_enabled_at"`
+	TOTPLastCounter   int64        `json:"-" db:"totp_last_counter"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+}
+
+const MinPasswordLength = 8
+
+const (
+	RolePatron    = "patron"
+	RoleLibrarian = "librarian"
+	RoleAdmin     = "admin"
+)
+
+// Patron types drive the borrowing terms a patron is offered.
+const (
+	PatronTypeAdult   = "adult"
+	PatronTypeChild   = "child"
+	PatronTypeStudent = "student"
+	PatronTypeStaff   = "staff"
+)
+
+// Account statuses as shown in the user directory, derived from the account's dates.
+const (
+	UserStatusActive     = "active"
+	UserStatusUnverified = "unverified"
+	UserStatusSuspended  = "suspended"
+	UserStatusClosed     = "closed"
+)
+
+func IsValidPatronType(patronType string) bool {
+	switch patronType {
+	case PatronTypeAdult, PatronTypeChild, PatronTypeStudent, PatronTypeStaff:
+		return true
+	}
+	return false
+}
+
+func IsValidUserStatus(status string) bool {
+	switch status {
+	case UserStatusActive, UserStatusUnverified, UserStatusSuspended, UserStatusClosed:
+		return true
+	}
+	return false
+}
+
+func IsValidRole(role string) bool {
+	switch role {
+	case RolePatron, RoleLibrarian, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// SessionValid reports whether a session that signed in at the given time is still
+// honoured, i.e. it was not started before the user's sessions were last revoked.
+func (u *User) SessionValid(authenticatedAt time.Time) bool {
+	if u.IsClosed() {
+		return false
+	}
+	return u.SessionsRevokedAt == nil || authenticatedAt.After(*u.SessionsRevokedAt)
+}
+
+// TwoFactorEnabled reports whether the user has confirmed an authenticator app. A
+// secret without TOTPEnabledAt is an enrolment that was started but not finished.
+func (u *User) TwoFactorEnabled() bool {
+	return u.TOTPEnabledAt != nil && u.TOTPSecret != ""
+}
+
+// RequiresTwoFactor is the policy for roles that can change the catalogue or other
+// accounts; they may not use privileged endpoints until two-factor is enabled.
+func (u *User) RequiresTwoFactor() bool {
+	return u.HasRole(RoleLibrarian, RoleAdmin)
+}
+
+func (u *User) IsClosed() bool {
+	return u.ClosedAt != nil
+}
+
+// IsSuspended reports whether a suspension is in force at the given time. A suspension
+// without an end date lasts until a librarian reinstates the patron.
+func (u *User) IsSuspended(now time.Time) bool {
+	if u.SuspendedAt == nil {
+		return false
+	}
+	return u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil)
+}
+
+// Status summarises the account for the directory. Closed wins over suspended, which
+// wins over an unverified email.
+func (u *User) Status(now time.Time) string {
+	switch {
+	case u.IsClosed():
+		return UserStatusClosed
+	case u.IsSuspended(now):
+		return UserStatusSuspended
+	case !u.IsEmailVerified():
+		return UserStatusUnverified
+	}
+	return UserStatusActive
+}
+
+func (u *User) HasRole(roles ...string) bool {
+	for _, role := range roles {
+		if u.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPassword stores a bcrypt hash of the given password; the plain text is never kept.
+func (u *User) SetPassword(password string) error {
+	if len(password) < MinPasswordLength {
+		return fmt.Errorf("password must be at least %d characters", MinPasswordLength)
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u.PasswordHash = string(hash)
+	return nil
+}
+
+func (u *User) CheckPassword(password string) bool {
+	if u.PasswordHash == "" {
+		return false
+	}
+	return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(password)) == nil
 }
 
 func (u User) String() string {
@@ -34,6 +179,8 @@ func (u *User) Validate(tx *pop.Connection) (*validate.Errors, error) {
 	return validate.Validate(
 		&validators.StringIsPresent{Field: u.Name, Name: "Name"},
 		&validators.StringIsPresent{Field: u.Email, Name: "Email"},
+		&validators.StringInclusion{Field: u.Role, Name: "Role", List: []string{RolePatron, RoleLibrarian, RoleAdmin}},
+		&validators.StringInclusion{Field: u.PatronType, Name: "PatronType", List: []string{PatronTypeAdult, PatronTypeChild, PatronTypeStudent, PatronTypeStaff}},
 	), nil
 }
 
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// UserIdentity links a user to an account at an external identity provider, keyed by
+// the provider's stable subject rather than the email, which can change on either side.
+type UserIdentity struct {
+	ID       uuid.UUID `json:"id" db:"id"`
+	UserID   uuid.UUID `json:"user_id" db:"user_id"`
+	Provider string    `json:"provider" db:"provider"`
+	Subject  string    `json:"subject" db:"subject"`
+	// Username is the name typed at sign-in, kept for providers that have one, such as
+	// the staff directory, so failed sign-ins can be traced back to the user.
+	Username   string     `json:"username" db:"username"`
+	Email      string     `json:"email" db:"email"`
+	LastUsedAt *time.Time `json:"last_used_at" db:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (i *UserIdentity) Validate() error {
+	if i.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if i.Provider == "" || i.Subject == "" {
+		return errors.New("provider and subject are required")
+	}
+	return nil
+}
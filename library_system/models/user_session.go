@@ -0,0 +1,35 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+// UserSession is the server-side record of a browser sign-in. The session cookie only
+// carries its ID, so revoking the record signs that browser out on its next request.
+type UserSession struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	UserID     uuid.UUID  `json:"user_id" db:"user_id"`
+	UserAgent  string     `json:"user_agent" db:"user_agent"`
+	IPAddress  string     `json:"ip_address" db:"ip_address"`
+	LastSeenAt time.Time  `json:"last_seen_at" db:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (s *UserSession) Validate() error {
+	if s.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if s.ExpiresAt.IsZero() {
+		return errors.New("session expiry is required")
+	}
+	return nil
+}
+
+func (s *UserSession) IsActive(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"errors"
+	"github.com/gofrs/uuid"
+	"time"
+)
+
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	TokenPurposeRecoveryCode      = "recovery_code"
+)
+
+// UserToken is a single-use secret given to a user, e.g. emailed to prove they own an
+// address or shown once as a two-factor recovery code. Only a hash of the secret is stored.
+type UserToken struct {
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	Purpose   string     `json:"purpose" db:"purpose"`
+	Email     string     `json:"email" db:"email"`
+	TokenHash string     `json:"-" db:"token_hash"`
+	ExpiresAt time.Time  `json:"expires_at" db:"expires_at"`
+	UsedAt    *time.Time `json:"used_at" db:"used_at"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+func (t *UserToken) Validate() error {
+	if t.UserID == uuid.Nil {
+		return errors.New("user ID is required")
+	}
+	if t.Purpose == "" {
+		return errors.New("purpose is required")
+	}
+	if t.TokenHash == "" {
+		return errors.New("token hash is required")
+	}
+	return nil
+}
+
+func (t *UserToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
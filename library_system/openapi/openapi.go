@@ -0,0 +1,222 @@
+// Package openapi builds an OpenAPI 3 description of the HTTP API. Operations are
+// declared by hand next to the routes; request and response schemas are derived from
+// the Dto types by reflection so they cannot drift from what the handlers encode.
+package openapi
+
+import (
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"library-system/problem"
+)
+
+const Version = "3.0.3"
+
+type Document struct {
+	OpenAPI    string               `json:"openapi"`
+	Info       Info                 `json:"info"`
+	Paths      map[string]*PathItem `json:"paths"`
+	Components Components           `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+// PathItem holds the operations on one path, keyed by lower-case HTTP method.
+type PathItem map[string]*Operation
+
+type Operation struct {
+	Summary     string                `json:"summary"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]*Response  `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+	Deprecated  bool                  `json:"deprecated,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                  `json:"required"`
+	Content  map[string]*MediaType `json:"content"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+type Response struct {
+	Description string                `json:"description"`
+	Content     map[string]*MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Schemas         map[string]*Schema         `json:"schemas"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type        string `json:"type"`
+	Scheme      string `json:"scheme,omitempty"`
+	In          string `json:"in,omitempty"`
+	Name        string `json:"name,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// Object describes an ad-hoc JSON object, such as the {"status": ..., "user": ...}
+// envelopes most handlers render. Each value is an example of the field's type.
+type Object map[string]interface{}
+
+// Route declares one operation. Request and Response are zero values of the types the
+// handler binds and renders; Query lists query parameters by name and description.
+type Route struct {
+	Method      string
+	Path        string
+	Summary     string
+	Description string
+	Tag         string
+	Query       map[string]string
+	Request     interface{}
+	Status      int
+	Response    interface{}
+	ContentType string
+	Secured     bool
+	Deprecated  bool
+}
+
+var pathParam = regexp.MustCompile(`\{(\w+)\}`)
+
+// Spec collects routes into a Document.
+type Spec struct {
+	doc *Document
+}
+
+func New(info Info) *Spec {
+	return &Spec{doc: &Document{
+		OpenAPI: Version,
+		Info:    info,
+		Paths:   map[string]*PathItem{},
+		Components: Components{
+			Schemas: map[string]*Schema{},
+		},
+	}}
+}
+
+// SecurityScheme registers a scheme that secured routes accept.
+func (s *Spec) SecurityScheme(name string, scheme *SecurityScheme) {
+	if s.doc.Components.SecuritySchemes == nil {
+		s.doc.Components.SecuritySchemes = map[string]*SecurityScheme{}
+	}
+	s.doc.Components.SecuritySchemes[name] = scheme
+}
+
+// Add declares a route. Paths use the router's {param} syntax.
+func (s *Spec) Add(route Route) {
+	item, ok := s.doc.Paths[route.Path]
+	if !ok {
+		item = &PathItem{}
+		s.doc.Paths[route.Path] = item
+	}
+
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		Responses:   map[string]*Response{},
+		Deprecated:  route.Deprecated,
+	}
+	if route.Tag != "" {
+		op.Tags = []string{route.Tag}
+	}
+	for _, match := range pathParam.FindAllStringSubmatch(route.Path, -1) {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:     match[1],
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+	names := make([]string, 0, len(route.Query))
+	for name := range route.Query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		op.Parameters = append(op.Parameters, Parameter{
+			Name:        name,
+			In:          "query",
+			Description: route.Query[name],
+			Schema:      &Schema{Type: "string"},
+		})
+	}
+
+	if route.Request != nil {
+		op.RequestBody = &RequestBody{
+			Required: true,
+			Content:  map[string]*MediaType{"application/json": {Schema: s.SchemaFor(route.Request)}},
+		}
+	}
+
+	status := route.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	success := &Response{Description: http.StatusText(status)}
+	if route.Response != nil {
+		contentType := route.ContentType
+		if contentType == "" {
+			contentType = "application/json"
+		}
+		success.Content = map[string]*MediaType{contentType: {Schema: s.SchemaFor(route.Response)}}
+	}
+	op.Responses[strconv.Itoa(status)] = success
+	op.Responses["default"] = &Response{
+		Description: "Error, described as RFC 7807 problem details",
+		Content:     map[string]*MediaType{problem.ContentType: {Schema: s.SchemaFor(problem.Details{})}},
+	}
+
+	if route.Secured {
+		for name := range s.doc.Components.SecuritySchemes {
+			op.Security = append(op.Security, map[string][]string{name: {}})
+		}
+		sort.Slice(op.Security, func(i, j int) bool {
+			return firstKey(op.Security[i]) < firstKey(op.Security[j])
+		})
+	}
+
+	(*item)[strings.ToLower(route.Method)] = op
+}
+
+// Has reports whether an operation is declared for the method and path.
+func (s *Spec) Has(method, path string) bool {
+	item, ok := s.doc.Paths[path]
+	if !ok {
+		return false
+	}
+	_, ok = (*item)[strings.ToLower(method)]
+	return ok
+}
+
+func (s *Spec) Document() *Document {
+	return s.doc
+}
+
+func firstKey(m map[string][]string) string {
+	for key := range m {
+		return key
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+type testBase struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type testBook struct {
+	testBase
+	Title    string     `json:"title" validate:"required"`
+	Tags     []string   `json:"tags,omitempty" validate:"omitempty,min=1"`
+	Returned *time.Time `json:"returned_at"`
+	Secret   string     `json:"-"`
+	internal string
+}
+
+func TestSchemaForStruct(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+
+	ref := spec.SchemaFor(testBook{})
+
+	assert.Equal(t, "#/components/schemas/testBook", ref.Ref)
+	schema := spec.Document().Components.Schemas["testBook"]
+	if assert.NotNil(t, schema) {
+		assert.Equal(t, &Schema{Type: "string", Format: "uuid"}, schema.Properties["id"])
+		assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, schema.Properties["created_at"])
+		assert.Equal(t, &Schema{Type: "string", Format: "date-time", Nullable: true}, schema.Properties["returned_at"])
+		assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, schema.Properties["tags"])
+		assert.NotContains(t, schema.Properties, "Secret")
+		assert.NotContains(t, schema.Properties, "internal")
+		assert.Equal(t, []string{"title"}, schema.Required)
+	}
+}
+
+func TestSchemaForObject(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+
+	schema := spec.SchemaFor(Object{"status": "", "books": []testBook{}})
+
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, []string{"books", "status"}, schema.Required)
+	assert.Equal(t, "#/components/schemas/testBook", schema.Properties["books"].Items.Ref)
+}
+
+func TestAddRoute(t *testing.T) {
+	spec := New(Info{Title: "Test", Version: "1"})
+	spec.SecurityScheme("bearerToken", &SecurityScheme{Type: "http", Scheme: "bearer"})
+
+	spec.Add(Route{Method: "PATCH", Path: "/books/{id}", Summary: "Update", Request: testBook{}, Response: testBook{}, Secured: true})
+
+	assert.True(t, spec.Has("PATCH", "/books/{id}"))
+	assert.False(t, spec.Has("GET", "/books/{id}"))
+	op := (*spec.Document().Paths["/books/{id}"])["patch"]
+	assert.Equal(t, []Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "string"}}}, op.Parameters)
+	assert.Contains(t, op.Responses, "200")
+	if assert.Contains(t, op.Responses, "default") {
+		assert.Contains(t, op.Responses["default"].Content, "application/problem+json")
+	}
+	assert.Equal(t, []map[string][]string{{"bearerToken": {}}}, op.Security)
+
+	_, err := json.Marshal(spec.Document())
+	assert.NoError(t, err)
+}
@@ -0,0 +1,143 @@
+// Package problem describes API errors as RFC 7807 problem details. Handlers and
+// middleware return errors rather than rendering them; one middleware turns whatever
+// comes back into an application/problem+json response, so every error has the same
+// shape whichever layer produced it.
+package problem
+
+import (
+	"errors"
+	"net/http"
+
+	"library-system/apperrors"
+	"library-system/validation"
+)
+
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes every problem type. The URIs are relative, so clients resolve them
+// against the API's own address (RFC 7807, section 3.1).
+const TypeBase = "/problems/"
+
+// Problem types. Clients should branch on the type, not on the status or the detail.
+const (
+	TypeBadRequest         = "bad-request"
+	TypeValidation         = "validation-error"
+	TypeUnauthorized       = "unauthorized"
+	TypeForbidden          = "forbidden"
+	TypeNotFound           = "not-found"
+	TypeMethodNotAllowed   = "method-not-allowed"
+	TypeConflict           = "conflict"
+	TypeUnavailable        = "unavailable"
+	TypeTooManyRequests    = "too-many-requests"
+	TypeBorrowingBlocked   = "borrowing-blocked"
+	TypeTwoFactorRequired  = "two-factor-required"
+	TypeCSRF               = "csrf-token-invalid"
+	TypeInternal           = "internal-error"
+	TypeServiceUnavailable = "service-unavailable"
+)
+
+var titles = map[string]string{
+	TypeBadRequest:         "Bad request",
+	TypeValidation:         "Validation failed",
+	TypeUnauthorized:       "Authentication required",
+	TypeForbidden:          "Forbidden",
+	TypeNotFound:           "Not found",
+	TypeMethodNotAllowed:   "Method not allowed",
+	TypeConflict:           "Conflict",
+	TypeUnavailable:        "Resource unavailable",
+	TypeTooManyRequests:    "Too many requests",
+	TypeBorrowingBlocked:   "Borrowing blocked",
+	TypeTwoFactorRequired:  "Two-factor authentication required",
+	TypeCSRF:               "Invalid CSRF token",
+	TypeInternal:           "Internal server error",
+	TypeServiceUnavailable: "Service unavailable",
+}
+
+// Details is the response body. Code and Fields are extension members: Code narrows the
+// type down, such as the reason a patron is blocked, and Fields lists every failing
+// field of a validation error.
+type Details struct {
+	Type      string                  `json:"type"`
+	Title     string                  `json:"title"`
+	Status    int                     `json:"status"`
+	Detail    string                  `json:"detail,omitempty"`
+	Instance  string                  `json:"instance,omitempty"`
+	RequestID string                  `json:"request_id,omitempty"`
+	Code      string                  `json:"code,omitempty"`
+	Fields    []validation.FieldError `json:"fields,omitempty"`
+}
+
+// Error is returned by handlers that need a particular status or type. Detail is shown
+// to the client as it is, so it must not carry internal details.
+type Error struct {
+	Status int
+	Type   string
+	Detail string
+	Code   string
+}
+
+// New builds an error with the type that goes with status.
+func New(status int, detail string) *Error {
+	return &Error{Status: status, Type: typeForStatus(status), Detail: detail}
+}
+
+func (e *Error) Error() string {
+	return e.Detail
+}
+
+// From describes err. Errors of a known kind keep their message; anything else is an
+// unexpected failure, reported without its message since that may expose internals.
+func From(err error) *Details {
+	var p *Error
+	var fields validation.Errors
+	switch {
+	case errors.As(err, &p):
+		return &Details{Type: TypeBase + p.Type, Title: titles[p.Type], Status: p.Status, Detail: p.Detail, Code: p.Code}
+	case errors.As(err, &fields):
+		return &Details{Type: TypeBase + TypeValidation, Title: titles[TypeValidation], Status: http.StatusBadRequest,
+			Detail: "One or more fields are invalid", Fields: fields}
+	}
+
+	status, typ := http.StatusInternalServerError, TypeInternal
+	switch {
+	case errors.Is(err, apperrors.ErrNotFound):
+		status, typ = http.StatusNotFound, TypeNotFound
+	case errors.Is(err, apperrors.ErrConflict):
+		status, typ = http.StatusConflict, TypeConflict
+	case errors.Is(err, apperrors.ErrUnavailable):
+		status, typ = http.StatusConflict, TypeUnavailable
+	case errors.Is(err, apperrors.ErrValidation):
+		status, typ = http.StatusBadRequest, TypeValidation
+	case errors.Is(err, apperrors.ErrForbidden):
+		status, typ = http.StatusForbidden, TypeForbidden
+	case errors.Is(err, apperrors.ErrUnauthorized):
+		status, typ = http.StatusUnauthorized, TypeUnauthorized
+	default:
+		return &Details{Type: TypeBase + TypeInternal, Title: titles[TypeInternal], Status: status,
+			Detail: "An unexpected error occurred"}
+	}
+	return &Details{Type: TypeBase + typ, Title: titles[typ], Status: status, Detail: err.Error()}
+}
+
+func typeForStatus(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return TypeBadRequest
+	case http.StatusUnauthorized:
+		return TypeUnauthorized
+	case http.StatusForbidden:
+		return TypeForbidden
+	case http.StatusNotFound:
+		return TypeNotFound
+	case http.StatusMethodNotAllowed:
+		return TypeMethodNotAllowed
+	case http.StatusConflict:
+		return TypeConflict
+	case http.StatusTooManyRequests:
+		return TypeTooManyRequests
+	case http.StatusServiceUnavailable:
+		return TypeServiceUnavailable
+	default:
+		return TypeInternal
+	}
+}
@@ -0,0 +1,67 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"library-system/apperrors"
+	"library-system/validation"
+)
+
+func TestFromErrorKinds(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		typ    string
+	}{
+		{apperrors.NotFound("book not found"), http.StatusNotFound, TypeNotFound},
+		{fmt.Errorf("failed to find book: %w", apperrors.NotFound("book not found")), http.StatusNotFound, TypeNotFound},
+		{apperrors.Conflict("duplicate ISBN"), http.StatusConflict, TypeConflict},
+		{apperrors.Unavailable("book is on loan"), http.StatusConflict, TypeUnavailable},
+		{apperrors.Validation("Invalid Email Address"), http.StatusBadRequest, TypeValidation},
+		{apperrors.Forbidden("not yours"), http.StatusForbidden, TypeForbidden},
+		{apperrors.Unauthorized("bad password"), http.StatusUnauthorized, TypeUnauthorized},
+	}
+
+	for _, tt := range tests {
+		details := From(tt.err)
+		assert.Equal(t, tt.status, details.Status)
+		assert.Equal(t, TypeBase+tt.typ, details.Type)
+		assert.Equal(t, titles[tt.typ], details.Title)
+		assert.Equal(t, tt.err.Error(), details.Detail)
+	}
+}
+
+func TestFromHidesUnexpectedErrors(t *testing.T) {
+	details := From(errors.New("dial tcp 10.0.0.5:3306: connection refused"))
+
+	assert.Equal(t, http.StatusInternalServerError, details.Status)
+	assert.Equal(t, "/problems/internal-error", details.Type)
+	assert.Equal(t, "An unexpected error occurred", details.Detail)
+}
+
+func TestFromExplicitProblem(t *testing.T) {
+	err := fmt.Errorf("checkout: %w", &Error{Status: http.StatusForbidden, Type: TypeBorrowingBlocked, Detail: "Outstanding fines", Code: "fines_over_limit"})
+
+	assert.Equal(t, &Details{
+		Type:   "/problems/borrowing-blocked",
+		Title:  "Borrowing blocked",
+		Status: http.StatusForbidden,
+		Detail: "Outstanding fines",
+		Code:   "fines_over_limit",
+	}, From(err))
+	assert.Equal(t, "/problems/too-many-requests", From(New(http.StatusTooManyRequests, "Slow down")).Type)
+}
+
+func TestFromValidationErrors(t *testing.T) {
+	fields := validation.Errors{{Field: "isbn", Code: "required", Message: "isbn is required"}}
+
+	details := From(fields)
+
+	assert.Equal(t, http.StatusBadRequest, details.Status)
+	assert.Equal(t, "/problems/validation-error", details.Type)
+	assert.Equal(t, []validation.FieldError(fields), details.Fields)
+}
@@ -0,0 +1,74 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+)
+
+type MockAPITokenRepository struct {
+	MockTokens           []models.APIToken
+	AddTokenError        error
+	GetTokenByIDError    error
+	GetTokenByHashError  error
+	GetTokensByUserError error
+	UpdateTokenError     error
+}
+
+func (r *MockAPITokenRepository) AddToken(token *models.APIToken) error {
+	if r.AddTokenError != nil {
+		return r.AddTokenError
+	}
+	r.MockTokens = append(r.MockTokens, *token)
+	return nil
+}
+
+func (r *MockAPITokenRepository) GetTokenByID(tokenID uuid.UUID) (*models.APIToken, error) {
+	if r.GetTokenByIDError != nil {
+		return nil, r.GetTokenByIDError
+	}
+	for _, token := range r.MockTokens {
+		if token.ID == tokenID {
+			return &token, nil
+		}
+	}
+	return nil, apperrors.NotFound("token not found")
+}
+
+func (r *MockAPITokenRepository) GetTokenByHash(hash string) (*models.APIToken, error) {
+	if r.GetTokenByHashError != nil {
+		return nil, r.GetTokenByHashError
+	}
+	for _, token := range r.MockTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, apperrors.NotFound("token not found")
+}
+
+func (r *MockAPITokenRepository) GetTokensByUser(userID uuid.UUID) ([]models.APIToken, error) {
+	if r.GetTokensByUserError != nil {
+		return nil, r.GetTokensByUserError
+	}
+	var tokens []models.APIToken
+	for _, token := range r.MockTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, token)
+		}
+	}
+	return tokens, nil
+}
+
+func (r *MockAPITokenRepository) UpdateToken(token *models.APIToken) error {
+	if r.UpdateTokenError != nil {
+		return r.UpdateTokenError
+	}
+	for i, existingToken := range r.MockTokens {
+		if existingToken.ID == token.ID {
+			r.MockTokens[i] = *token
+			return nil
+		}
+	}
+	return apperrors.NotFound("token not found")
+}
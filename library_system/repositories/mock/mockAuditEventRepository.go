@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type MockAuditEventRepository struct {
+	MockEvents           []models.AuditEvent
+	AddEventError        error
+	GetEventsByUserError error
+}
+
+func (r *MockAuditEventRepository) AddEvent(event *models.AuditEvent) error {
+	if r.AddEventError != nil {
+		return r.AddEventError
+	}
+	r.MockEvents = append(r.MockEvents, *event)
+	return nil
+}
+
+func (r *MockAuditEventRepository) GetEventsByUser(userID uuid.UUID) ([]models.AuditEvent, error) {
+	if r.GetEventsByUserError != nil {
+		return nil, r.GetEventsByUserError
+	}
+	var events []models.AuditEvent
+	for _, event := range r.MockEvents {
+		if event.UserID != nil && *event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
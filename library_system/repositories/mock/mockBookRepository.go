@@ -1,10 +1,11 @@
 package mock
 
 import (
-	"fmt"
+	"sort"
 	"sync"
 
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
 )
 
@@ -18,6 +19,8 @@ type MockBookRepository struct {
 	SearchBookError    error
 	GetBookByISBNError error
 	GetAllBooksError   error
+	GetBooksByIDsError error
+	GetBooksAfterError error
 }
 
 func NewMockBookRepository() *MockBookRepository {
@@ -52,7 +55,7 @@ func (r *MockBookRepository) RemoveBook(bookID uuid.UUID) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("book not found")
+	return apperrors.NotFound("book not found")
 }
 
 func (r *MockBookRepository) GetBookByID(bookID uuid.UUID) (*models.Book, error) {
@@ -69,7 +72,7 @@ func (r *MockBookRepository) GetBookByID(bookID uuid.UUID) (*models.Book, error)
 			return &bookCopy, nil
 		}
 	}
-	return nil, fmt.Errorf("book not found")
+	return nil, apperrors.NotFound("book not found")
 }
 
 func (r *MockBookRepository) UpdateBook(book *models.Book) error {
@@ -86,7 +89,7 @@ func (r *MockBookRepository) UpdateBook(book *models.Book) error {
 			return nil
 		}
 	}
-	return fmt.Errorf("book not found")
+	return apperrors.NotFound("book not found")
 }
 
 func (r *MockBookRepository) SearchBook(query string) ([]*models.Book, error) {
@@ -139,3 +142,45 @@ func (r *MockBookRepository) GetAllBooks() ([]*models.Book, error) {
 	}
 	return books, nil
 }
+
+func (r *MockBookRepository) GetBooksByIDs(ids []uuid.UUID) ([]*models.Book, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.GetBooksByIDsError != nil {
+		return nil, r.GetBooksByIDsError
+	}
+
+	books := []*models.Book{}
+	for _, id := range ids {
+		for _, book := range r.MockBooks {
+			if book.ID == id {
+				bookCopy := book
+				books = append(books, &bookCopy)
+			}
+		}
+	}
+	return books, nil
+}
+
+func (r *MockBookRepository) GetBooksAfter(after uuid.UUID, limit int) ([]*models.Book, error) {
+	r.RLock()
+	defer r.RUnlock()
+
+	if r.GetBooksAfterError != nil {
+		return nil, r.GetBooksAfterError
+	}
+
+	books := []*models.Book{}
+	for _, book := range r.MockBooks {
+		if book.ID.String() > after.String() {
+			bookCopy := book
+			books = append(books, &bookCopy)
+		}
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].ID.String() < books[j].ID.String() })
+	if len(books) > limit {
+		books = books[:limit]
+	}
+	return books, nil
+}
@@ -0,0 +1,33 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type MockBookStatusRepository struct {
+	MockChanges                 []models.BookStatusChange
+	ChangeStatusError           error
+	GetStatusChangesByBookError error
+}
+
+func (r *MockBookStatusRepository) ChangeStatus(book *models.Book, change *models.BookStatusChange) error {
+	if r.ChangeStatusError != nil {
+		return r.ChangeStatusError
+	}
+	r.MockChanges = append(r.MockChanges, *change)
+	return nil
+}
+
+func (r *MockBookStatusRepository) GetStatusChangesByBook(bookID uuid.UUID) ([]models.BookStatusChange, error) {
+	if r.GetStatusChangesByBookError != nil {
+		return nil, r.GetStatusChangesByBookError
+	}
+	var changes []models.BookStatusChange
+	for _, change := range r.MockChanges {
+		if change.BookID == bookID {
+			changes = append(changes, change)
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,47 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type MockFineRepository struct {
+	MockFines                  []models.Fine
+	AddFineError               error
+	GetFinesByUserError        error
+	GetOutstandingBalanceError error
+}
+
+func (r *MockFineRepository) AddFine(fine *models.Fine) error {
+	if r.AddFineError != nil {
+		return r.AddFineError
+	}
+	r.MockFines = append(r.MockFines, *fine)
+	return nil
+}
+
+func (r *MockFineRepository) GetFinesByUser(userID uuid.UUID) ([]models.Fine, error) {
+	if r.GetFinesByUserError != nil {
+		return nil, r.GetFinesByUserError
+	}
+	var fines []models.Fine
+	for _, fine := range r.MockFines {
+		if fine.UserID == userID {
+			fines = append(fines, fine)
+		}
+	}
+	return fines, nil
+}
+
+func (r *MockFineRepository) GetOutstandingBalance(userID uuid.UUID) (int, error) {
+	if r.GetOutstandingBalanceError != nil {
+		return 0, r.GetOutstandingBalanceError
+	}
+	total := 0
+	for _, fine := range r.MockFines {
+		if fine.UserID == userID && fine.IsOutstanding() {
+			total += fine.AmountCents
+		}
+	}
+	return total, nil
+}
@@ -1,17 +1,23 @@
 package mock
 
 import (
-	"errors"
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
 )
 
 type MockLoanRepository struct {
 	MockLoans                  []models.Loan
 	AddLoanError               error
+	GetLoanByIDError           error
 	GetLoanByBookAndUserError  error
 	UpdateLoanError            error
 	GetLoanByBookAndEmailError error
+	GetLoansByUserError        error
+	GetLoansByUsersError       error
+	GetActiveLoansByBooksError error
+	CountActiveLoansError      error
+	AnonymiseLoansError        error
 }
 
 func (r *MockLoanRepository) AddLoan(loan *models.Loan) error {
@@ -22,6 +28,18 @@ func (r *MockLoanRepository) AddLoan(loan *models.Loan) error {
 	return nil
 }
 
+func (r *MockLoanRepository) GetLoanByID(id uuid.UUID) (*models.Loan, error) {
+	if r.GetLoanByIDError != nil {
+		return nil, r.GetLoanByIDError
+	}
+	for _, loan := range r.MockLoans {
+		if loan.ID == id {
+			return &loan, nil
+		}
+	}
+	return nil, apperrors.NotFound("loan not found")
+}
+
 func (r *MockLoanRepository) GetLoanByBookAndUser(bookID uuid.UUID, userID uuid.UUID) (*models.Loan, error) {
 	if r.GetLoanByBookAndUserError != nil {
 		return nil, r.GetLoanByBookAndUserError
@@ -31,7 +49,7 @@ func (r *MockLoanRepository) GetLoanByBookAndUser(bookID uuid.UUID, userID uuid.
 			return &loan, nil
 		}
 	}
-	return nil, errors.New("loan not found")
+	return nil, apperrors.NotFound("loan not found")
 }
 
 func (r *MockLoanRepository) UpdateLoan(loan *models.Loan) error {
@@ -44,7 +62,7 @@ func (r *MockLoanRepository) UpdateLoan(loan *models.Loan) error {
 			return nil
 		}
 	}
-	return errors.New("loan not found")
+	return apperrors.NotFound("loan not found")
 }
 
 func (r *MockLoanRepository) GetLoanByBookAndEmail(bookID uuid.UUID, email string) (*models.Loan, error) {
@@ -58,3 +76,71 @@ func (r *MockLoanRepository) GetLoanByBookAndEmail(bookID uuid.UUID, email strin
 	}
 	return nil, nil
 }
+
+func (r *MockLoanRepository) GetLoansByUser(userID uuid.UUID) ([]models.Loan, error) {
+	if r.GetLoansByUserError != nil {
+		return nil, r.GetLoansByUserError
+	}
+	var loans []models.Loan
+	for _, loan := range r.MockLoans {
+		if loan.UserID == userID {
+			loans = append(loans, loan)
+		}
+	}
+	return loans, nil
+}
+
+func (r *MockLoanRepository) GetLoansByUsers(userIDs []uuid.UUID) ([]models.Loan, error) {
+	if r.GetLoansByUsersError != nil {
+		return nil, r.GetLoansByUsersError
+	}
+	loans := []models.Loan{}
+	for _, loan := range r.MockLoans {
+		for _, userID := range userIDs {
+			if loan.UserID == userID {
+				loans = append(loans, loan)
+			}
+		}
+	}
+	return loans, nil
+}
+
+func (r *MockLoanRepository) GetActiveLoansByBooks(bookIDs []uuid.UUID) ([]models.Loan, error) {
+	if r.GetActiveLoansByBooksError != nil {
+		return nil, r.GetActiveLoansByBooksError
+	}
+	loans := []models.Loan{}
+	for _, loan := range r.MockLoans {
+		for _, bookID := range bookIDs {
+			if loan.BookID == bookID && loan.ReturnDate == nil {
+				loans = append(loans, loan)
+			}
+		}
+	}
+	return loans, nil
+}
+
+func (r *MockLoanRepository) CountActiveLoansByUser(userID uuid.UUID) (int, error) {
+	if r.CountActiveLoansError != nil {
+		return 0, r.CountActiveLoansError
+	}
+	count := 0
+	for _, loan := range r.MockLoans {
+		if loan.UserID == userID && loan.ReturnDate == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MockLoanRepository) AnonymiseLoans(userID uuid.UUID, email string) error {
+	if r.AnonymiseLoansError != nil {
+		return r.AnonymiseLoansError
+	}
+	for i, loan := range r.MockLoans {
+		if loan.UserID == userID {
+			r.MockLoans[i].Email = email
+		}
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+package mock
+
+import (
+	"library-system/mailers"
+)
+
+type MockMailer struct {
+	SentMessages []mailers.Message
+	SendError    error
+}
+
+func (m *MockMailer) Send(msg mailers.Message) error {
+	if m.SendError != nil {
+		return m.SendError
+	}
+	m.SentMessages = append(m.SentMessages, msg)
+	return nil
+}
@@ -0,0 +1,87 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+)
+
+type MockUserIdentityRepository struct {
+	MockIdentities      []models.UserIdentity
+	AddIdentityError    error
+	GetIdentityError    error
+	UpdateIdentityError error
+	DeleteIdentityError error
+}
+
+func (r *MockUserIdentityRepository) AddIdentity(identity *models.UserIdentity) error {
+	if r.AddIdentityError != nil {
+		return r.AddIdentityError
+	}
+	r.MockIdentities = append(r.MockIdentities, *identity)
+	return nil
+}
+
+func (r *MockUserIdentityRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	if r.GetIdentityError != nil {
+		return nil, r.GetIdentityError
+	}
+	for _, identity := range r.MockIdentities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockUserIdentityRepository) GetIdentityByUsername(provider, username string) (*models.UserIdentity, error) {
+	if r.GetIdentityError != nil {
+		return nil, r.GetIdentityError
+	}
+	for _, identity := range r.MockIdentities {
+		if identity.Provider == provider && identity.Username == username {
+			return &identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockUserIdentityRepository) GetIdentitiesByUser(userID uuid.UUID) ([]models.UserIdentity, error) {
+	if r.GetIdentityError != nil {
+		return nil, r.GetIdentityError
+	}
+	var identities []models.UserIdentity
+	for _, identity := range r.MockIdentities {
+		if identity.UserID == userID {
+			identities = append(identities, identity)
+		}
+	}
+	return identities, nil
+}
+
+func (r *MockUserIdentityRepository) UpdateIdentity(identity *models.UserIdentity) error {
+	if r.UpdateIdentityError != nil {
+		return r.UpdateIdentityError
+	}
+	for i, existing := range r.MockIdentities {
+		if existing.ID == identity.ID {
+			r.MockIdentities[i] = *identity
+			return nil
+		}
+	}
+	return apperrors.NotFound("identity not found")
+}
+
+func (r *MockUserIdentityRepository) DeleteIdentitiesByUser(userID uuid.UUID) error {
+	if r.DeleteIdentityError != nil {
+		return r.DeleteIdentityError
+	}
+	var kept []models.UserIdentity
+	for _, identity := range r.MockIdentities {
+		if identity.UserID != userID {
+			kept = append(kept, identity)
+		}
+	}
+	r.MockIdentities = kept
+	return nil
+}
@@ -1,9 +1,13 @@
 package mock
 
 import (
-	"errors"
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
+	"library-system/repositories/repository"
+	"sort"
+	"strings"
+	"time"
 )
 
 type MockUserRepo struct {
@@ -11,8 +15,21 @@ type MockUserRepo struct {
 	AddUserError        error
 	GetUserByIDError    error
 	GetUserByEmailError error
+	GetUserByCardError  error
 	UpdateUserError     error
 	DeleteUserError     error
+	ListUsersError      error
+	GetUsersByIDsError  error
+}
+
+func (r *MockUserRepo) GetUsersWithoutCardNumber() ([]models.User, error) {
+	users := []models.User{}
+	for _, user := range r.MockUser {
+		if user.CardNumber.String == "" && !user.IsClosed() {
+			users = append(users, user)
+		}
+	}
+	return users, nil
 }
 
 func (r *MockUserRepo) AddUser(user *models.User) error {
@@ -22,7 +39,7 @@ func (r *MockUserRepo) AddUser(user *models.User) error {
 
 	for _, existingUser := range r.MockUser {
 		if existingUser.Email == user.Email {
-			return errors.New("email already exists")
+			return apperrors.Conflict("email already exists")
 		}
 	}
 	r.MockUser = append(r.MockUser, *user)
@@ -38,7 +55,22 @@ func (r *MockUserRepo) GetUserByID(userID uuid.UUID) (*models.User, error) {
 			return &user, nil
 		}
 	}
-	return nil, errors.New("user not found")
+	return nil, apperrors.NotFound("user not found")
+}
+
+func (r *MockUserRepo) GetUsersByIDs(ids []uuid.UUID) ([]models.User, error) {
+	if r.GetUsersByIDsError != nil {
+		return nil, r.GetUsersByIDsError
+	}
+	users := []models.User{}
+	for _, id := range ids {
+		for _, user := range r.MockUser {
+			if user.ID == id {
+				users = append(users, user)
+			}
+		}
+	}
+	return users, nil
 }
 
 func (r *MockUserRepo) GetUserByEmail(email string) (*models.User, error) {
@@ -52,3 +84,63 @@ func (r *MockUserRepo) GetUserByEmail(email string) (*models.User, error) {
 	}
 	return nil, nil
 }
+
+func (r *MockUserRepo) GetUserByCardNumber(card string) (*models.User, error) {
+	if r.GetUserByCardError != nil {
+		return nil, r.GetUserByCardError
+	}
+	for _, user := range r.MockUser {
+		if user.CardNumber.Valid && user.CardNumber.String == card {
+			return &user, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *MockUserRepo) UpdateUser(user *models.User) error {
+	if r.UpdateUserError != nil {
+		return r.UpdateUserError
+	}
+	for i, existingUser := range r.MockUser {
+		if existingUser.ID == user.ID {
+			r.MockUser[i] = *user
+			return nil
+		}
+	}
+	return apperrors.NotFound("user not found")
+}
+
+func (r *MockUserRepo) ListUsers(filter repository.UserFilter) ([]models.User, int, error) {
+	if r.ListUsersError != nil {
+		return nil, 0, r.ListUsersError
+	}
+
+	now := time.Now()
+	query := strings.ToLower(filter.Query)
+	var matched []models.User
+	for _, user := range r.MockUser {
+		if query != "" && !strings.Contains(strings.ToLower(user.Name), query) &&
+			!strings.Contains(strings.ToLower(user.Email), query) &&
+			(filter.CardNumber == "" || user.CardNumber.String != filter.CardNumber) {
+			continue
+		}
+		if filter.PatronType != "" && user.PatronType != filter.PatronType {
+			continue
+		}
+		if filter.Status != "" && user.Status(now) != filter.Status {
+			continue
+		}
+		matched = append(matched, user)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+
+	start := (filter.Page - 1) * filter.PerPage
+	if start >= len(matched) {
+		return []models.User{}, len(matched), nil
+	}
+	end := start + filter.PerPage
+	if end > len(matched) {
+		end = len(matched)
+	}
+	return matched[start:end], len(matched), nil
+}
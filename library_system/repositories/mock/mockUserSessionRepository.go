@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+	"time"
+)
+
+type MockUserSessionRepository struct {
+	MockSessions            []models.UserSession
+	AddSessionError         error
+	GetSessionByIDError     error
+	GetSessionsByUserError  error
+	UpdateSessionError      error
+	RevokeUserSessionsError error
+}
+
+func (r *MockUserSessionRepository) AddSession(session *models.UserSession) error {
+	if r.AddSessionError != nil {
+		return r.AddSessionError
+	}
+	r.MockSessions = append(r.MockSessions, *session)
+	return nil
+}
+
+func (r *MockUserSessionRepository) GetSessionByID(sessionID uuid.UUID) (*models.UserSession, error) {
+	if r.GetSessionByIDError != nil {
+		return nil, r.GetSessionByIDError
+	}
+	for _, session := range r.MockSessions {
+		if session.ID == sessionID {
+			return &session, nil
+		}
+	}
+	return nil, apperrors.NotFound("session not found")
+}
+
+func (r *MockUserSessionRepository) GetSessionsByUser(userID uuid.UUID) ([]models.UserSession, error) {
+	if r.GetSessionsByUserError != nil {
+		return nil, r.GetSessionsByUserError
+	}
+	var sessions []models.UserSession
+	for _, session := range r.MockSessions {
+		if session.UserID == userID {
+			sessions = append(sessions, session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *MockUserSessionRepository) UpdateSession(session *models.UserSession) error {
+	if r.UpdateSessionError != nil {
+		return r.UpdateSessionError
+	}
+	for i, existingSession := range r.MockSessions {
+		if existingSession.ID == session.ID {
+			r.MockSessions[i] = *session
+			return nil
+		}
+	}
+	return apperrors.NotFound("session not found")
+}
+
+func (r *MockUserSessionRepository) RevokeUserSessions(userID uuid.UUID, keep uuid.UUID, revokedAt time.Time) error {
+	if r.RevokeUserSessionsError != nil {
+		return r.RevokeUserSessionsError
+	}
+	for i, session := range r.MockSessions {
+		if session.UserID == userID && session.ID != keep && session.RevokedAt == nil {
+			r.MockSessions[i].RevokedAt = &revokedAt
+			r.MockSessions[i].UpdatedAt = revokedAt
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package mock
+
+import (
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+	"time"
+)
+
+type MockUserTokenRepository struct {
+	MockTokens          []models.UserToken
+	AddTokenError       error
+	GetTokenByHashError error
+	UpdateTokenError    error
+	CountTokensError    error
+}
+
+func (r *MockUserTokenRepository) AddToken(token *models.UserToken) error {
+	if r.AddTokenError != nil {
+		return r.AddTokenError
+	}
+	r.MockTokens = append(r.MockTokens, *token)
+	return nil
+}
+
+func (r *MockUserTokenRepository) GetTokenByHash(purpose, hash string) (*models.UserToken, error) {
+	if r.GetTokenByHashError != nil {
+		return nil, r.GetTokenByHashError
+	}
+	for _, token := range r.MockTokens {
+		if token.Purpose == purpose && token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, apperrors.NotFound("token not found")
+}
+
+func (r *MockUserTokenRepository) UpdateToken(token *models.UserToken) error {
+	if r.UpdateTokenError != nil {
+		return r.UpdateTokenError
+	}
+	for i, existingToken := range r.MockTokens {
+		if existingToken.ID == token.ID {
+			r.MockTokens[i] = *token
+			return nil
+		}
+	}
+	return apperrors.NotFound("token not found")
+}
+
+func (r *MockUserTokenRepository) CountTokensSince(userID uuid.UUID, purpose string, since time.Time) (int, error) {
+	if r.CountTokensError != nil {
+		return 0, r.CountTokensError
+	}
+	count := 0
+	for _, token := range r.MockTokens {
+		if token.UserID == userID && token.Purpose == purpose && !token.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *MockUserTokenRepository) InvalidateTokens(userID uuid.UUID, purpose string) error {
+	now := time.Now()
+	for i, token := range r.MockTokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			r.MockTokens[i].UsedAt = &now
+		}
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+)
+
+type APITokenRepository interface {
+	AddToken(token *models.APIToken) error
+	GetTokenByID(tokenID uuid.UUID) (*models.APIToken, error)
+	GetTokenByHash(hash string) (*models.APIToken, error)
+	GetTokensByUser(userID uuid.UUID) ([]models.APIToken, error)
+	UpdateToken(token *models.APIToken) error
+}
+
+type apiTokenRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewAPITokenRepository(db *pop.Connection) APITokenRepository {
+	return &apiTokenRepositoryImpl{DB: db}
+}
+
+func (r *apiTokenRepositoryImpl) AddToken(token *models.APIToken) error {
+	if err := r.DB.Create(token); err != nil {
+		return fmt.Errorf("error adding API token: %w", err)
+	}
+	return nil
+}
+
+func (r *apiTokenRepositoryImpl) GetTokenByID(tokenID uuid.UUID) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	if err := r.DB.Find(token, tokenID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("token not found")
+		}
+		return nil, fmt.Errorf("error finding API token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *apiTokenRepositoryImpl) GetTokenByHash(hash string) (*models.APIToken, error) {
+	token := &models.APIToken{}
+	if err := r.DB.Where("token_hash = ?", hash).First(token); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("token not found")
+		}
+		return nil, fmt.Errorf("error finding API token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *apiTokenRepositoryImpl) GetTokensByUser(userID uuid.UUID) ([]models.APIToken, error) {
+	var tokens []models.APIToken
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at desc").All(&tokens); err != nil {
+		return nil, fmt.Errorf("error fetching API tokens: %w", err)
+	}
+	return tokens, nil
+}
+
+func (r *apiTokenRepositoryImpl) UpdateToken(token *models.APIToken) error {
+	if err := r.DB.Update(token); err != nil {
+		return fmt.Errorf("error updating API token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type AuditEventRepository interface {
+	AddEvent(event *models.AuditEvent) error
+	GetEventsByUser(userID uuid.UUID) ([]models.AuditEvent, error)
+}
+
+type auditEventRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewAuditEventRepository(db *pop.Connection) AuditEventRepository {
+	return &auditEventRepositoryImpl{DB: db}
+}
+
+func (r *auditEventRepositoryImpl) AddEvent(event *models.AuditEvent) error {
+	if err := r.DB.Create(event); err != nil {
+		return fmt.Errorf("error adding audit event: %w", err)
+	}
+	return nil
+}
+
+func (r *auditEventRepositoryImpl) GetEventsByUser(userID uuid.UUID) ([]models.AuditEvent, error) {
+	var events []models.AuditEvent
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at desc").All(&events); err != nil {
+		return nil, fmt.Errorf("error fetching audit events: %w", err)
+	}
+	return events, nil
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
 	"log"
 	"strings"
@@ -19,6 +20,8 @@ type BookRepository interface {
 	SearchBook(query string) ([]*models.Book, error)
 	GetBookByISBN(isbn string) (*models.Book, error)
 	GetAllBooks() ([]*models.Book, error)
+	GetBooksByIDs(ids []uuid.UUID) ([]*models.Book, error)
+	GetBooksAfter(after uuid.UUID, limit int) ([]*models.Book, error)
 }
 
 type BookRepositoryImpl struct {
@@ -34,7 +37,7 @@ func (r *BookRepositoryImpl) AddBook(book *models.Book) error {
 		existingBook := &models.Book{}
 		err := tx.Where("isbn = ?", book.ISBN).First(existingBook)
 		if err == nil {
-			return fmt.Errorf("duplicate ISBN: book with ISBN %s already exists", book.ISBN)
+			return apperrors.Conflict("duplicate ISBN: book with ISBN %s already exists", book.ISBN)
 		}
 		if !errors.Is(err, sql.ErrNoRows) {
 			return fmt.Errorf("error checking existing ISBN: %w", err)
@@ -50,7 +53,7 @@ func (r *BookRepositoryImpl) RemoveBook(bookID uuid.UUID) error {
 	return r.DB.Transaction(func(tx *pop.Connection) error {
 		book := &models.Book{}
 		if err := tx.Find(book, bookID); err != nil {
-			return fmt.Errorf("book with id %s not found", bookID)
+			return apperrors.NotFound("book with id %s not found", bookID)
 		}
 		return tx.Destroy(book)
 	})
@@ -62,7 +65,7 @@ func (r *BookRepositoryImpl) GetBookByID(id uuid.UUID) (*models.Book, error) {
 
 	if err := r.DB.Find(book, id); err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("book not found with id: %s", id)
+			return nil, apperrors.NotFound("book not found with id: %s", id)
 		}
 		return nil, fmt.Errorf("error finding book: %w", err)
 	}
@@ -96,7 +99,7 @@ func (r *BookRepositoryImpl) GetBookByISBN(isbn string) (*models.Book, error) {
 	err := r.DB.Where("isbn = ?", isbn).First(book)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("book with ISBN %s not found", isbn) // Match the test case expectation
+			return nil, apperrors.NotFound("book with ISBN %s not found", isbn) // Match the test case expectation
 		}
 		return nil, fmt.Errorf("error finding book by ISBN: %w", err)
 	}
@@ -110,3 +113,34 @@ func (r *BookRepositoryImpl) GetAllBooks() ([]*models.Book, error) {
 	}
 	return books, nil
 }
+
+// GetBooksByIDs loads several books in one query. Missing IDs are simply absent from the result.
+func (r *BookRepositoryImpl) GetBooksByIDs(ids []uuid.UUID) ([]*models.Book, error) {
+	books := []*models.Book{}
+	if len(ids) == 0 {
+		return books, nil
+	}
+	if err := r.DB.Where("id IN (?)", uuidArgs(ids)...).All(&books); err != nil {
+		return nil, fmt.Errorf("error fetching books: %w", err)
+	}
+	return books, nil
+}
+
+// GetBooksAfter pages through the catalogue in ID order, starting after the given ID.
+// Paging by key rather than offset keeps pages stable while books are added or removed.
+func (r *BookRepositoryImpl) GetBooksAfter(after uuid.UUID, limit int) ([]*models.Book, error) {
+	books := []*models.Book{}
+	if err := r.DB.Where("id > ?", after).Order("id asc").Limit(limit).All(&books); err != nil {
+		return nil, fmt.Errorf("error fetching books: %w", err)
+	}
+	return books, nil
+}
+
+// uuidArgs spreads IDs into query arguments; pop expands "IN (?)" to one placeholder each.
+func uuidArgs(ids []uuid.UUID) []interface{} {
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return args
+}
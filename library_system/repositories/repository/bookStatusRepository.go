@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type BookStatusRepository interface {
+	ChangeStatus(book *models.Book, change *models.BookStatusChange) error
+	GetStatusChangesByBook(bookID uuid.UUID) ([]models.BookStatusChange, error)
+}
+
+type bookStatusRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewBookStatusRepository(db *pop.Connection) BookStatusRepository {
+	return &bookStatusRepositoryImpl{DB: db}
+}
+
+// ChangeStatus saves the book with its new status and the history row in one
+// transaction, so no status change goes unrecorded.
+func (r *bookStatusRepositoryImpl) ChangeStatus(book *models.Book, change *models.BookStatusChange) error {
+	return r.DB.Transaction(func(tx *pop.Connection) error {
+		if err := tx.Update(book); err != nil {
+			return fmt.Errorf("error updating book status: %w", err)
+		}
+		if err := tx.Create(change); err != nil {
+			return fmt.Errorf("error recording status change: %w", err)
+		}
+		return nil
+	})
+}
+
+func (r *bookStatusRepositoryImpl) GetStatusChangesByBook(bookID uuid.UUID) ([]models.BookStatusChange, error) {
+	var changes []models.BookStatusChange
+	if err := r.DB.Where("book_id = ?", bookID).Order("created_at desc").All(&changes); err != nil {
+		return nil, fmt.Errorf("error fetching status history: %w", err)
+	}
+	return changes, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type FineRepository interface {
+	AddFine(fine *models.Fine) error
+	GetFinesByUser(userID uuid.UUID) ([]models.Fine, error)
+	GetOutstandingBalance(userID uuid.UUID) (int, error)
+}
+
+type fineRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewFineRepository(db *pop.Connection) FineRepository {
+	return &fineRepositoryImpl{DB: db}
+}
+
+func (r *fineRepositoryImpl) AddFine(fine *models.Fine) error {
+	if err := r.DB.Create(fine); err != nil {
+		return fmt.Errorf("error adding fine: %w", err)
+	}
+	return nil
+}
+
+func (r *fineRepositoryImpl) GetFinesByUser(userID uuid.UUID) ([]models.Fine, error) {
+	var fines []models.Fine
+	if err := r.DB.Where("user_id = ?", userID).Order("created_at desc").All(&fines); err != nil {
+		return nil, fmt.Errorf("error fetching fines: %w", err)
+	}
+	return fines, nil
+}
+
+func (r *fineRepositoryImpl) GetOutstandingBalance(userID uuid.UUID) (int, error) {
+	var balance struct {
+		Total int `db:"total"`
+	}
+	err := r.DB.RawQuery("SELECT COALESCE(SUM(amount_cents), 0) AS total FROM fines WHERE user_id = ? AND paid_at IS NULL", userID).
+		First(&balance)
+	if err != nil {
+		return 0, fmt.Errorf("error calculating fine balance: %w", err)
+	}
+	return balance.Total, nil
+}
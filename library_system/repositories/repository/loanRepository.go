@@ -1,16 +1,27 @@
 package repository
 
 import (
+	"database/sql"
+	"errors"
+	"fmt"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
+	"time"
 )
 
 type LoanRepository interface {
 	AddLoan(loan *models.Loan) error
+	GetLoanByID(id uuid.UUID) (*models.Loan, error)
 	GetLoanByBookAndUser(bookID, userID uuid.UUID) (*models.Loan, error)
 	UpdateLoan(loan *models.Loan) error
 	GetLoanByBookAndEmail(bookID uuid.UUID, email string) (*models.Loan, error)
+	GetLoansByUser(userID uuid.UUID) ([]models.Loan, error)
+	GetLoansByUsers(userIDs []uuid.UUID) ([]models.Loan, error)
+	GetActiveLoansByBooks(bookIDs []uuid.UUID) ([]models.Loan, error)
+	CountActiveLoansByUser(userID uuid.UUID) (int, error)
+	AnonymiseLoans(userID uuid.UUID, email string) error
 }
 
 type loanRepositoryImpl struct {
@@ -29,6 +40,17 @@ func (r *loanRepositoryImpl) UpdateLoan(loan *models.Loan) error {
 	return r.DB.Update(loan)
 }
 
+func (r *loanRepositoryImpl) GetLoanByID(id uuid.UUID) (*models.Loan, error) {
+	loan := &models.Loan{}
+	if err := r.DB.Find(loan, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("loan not found")
+		}
+		return nil, fmt.Errorf("error finding loan: %w", err)
+	}
+	return loan, nil
+}
+
 func (r *loanRepositoryImpl) GetLoanByBookAndUser(bookID, userID uuid.UUID) (*models.Loan, error) {
 	loan := &models.Loan{}
 	err := r.DB.Where("book_id = ? AND user_id = ? AND return_date IS NULL", bookID, userID).First(loan)
@@ -50,3 +72,45 @@ func (r *loanRepositoryImpl) GetLoanByBookAndEmail(bookID uuid.UUID, email strin
 	}
 	return loan, nil
 }
+
+func (r *loanRepositoryImpl) GetLoansByUser(userID uuid.UUID) ([]models.Loan, error) {
+	var loans []models.Loan
+	if err := r.DB.Where("user_id = ?", userID).Order("loan_date desc").All(&loans); err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// GetLoansByUsers is GetLoansByUser for several users in one query.
+func (r *loanRepositoryImpl) GetLoansByUsers(userIDs []uuid.UUID) ([]models.Loan, error) {
+	loans := []models.Loan{}
+	if len(userIDs) == 0 {
+		return loans, nil
+	}
+	if err := r.DB.Where("user_id IN (?)", uuidArgs(userIDs)...).Order("loan_date desc").All(&loans); err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+// GetActiveLoansByBooks returns the open loans and holds on the given books.
+func (r *loanRepositoryImpl) GetActiveLoansByBooks(bookIDs []uuid.UUID) ([]models.Loan, error) {
+	loans := []models.Loan{}
+	if len(bookIDs) == 0 {
+		return loans, nil
+	}
+	if err := r.DB.Where("return_date IS NULL").Where("book_id IN (?)", uuidArgs(bookIDs)...).Order("loan_date asc").All(&loans); err != nil {
+		return nil, err
+	}
+	return loans, nil
+}
+
+func (r *loanRepositoryImpl) CountActiveLoansByUser(userID uuid.UUID) (int, error) {
+	return r.DB.Where("user_id = ? AND return_date IS NULL", userID).Count(&models.Loan{})
+}
+
+// AnonymiseLoans replaces the email copied onto a user's loans, keeping the loan history
+// for circulation statistics while dropping the personal data.
+func (r *loanRepositoryImpl) AnonymiseLoans(userID uuid.UUID, email string) error {
+	return r.DB.RawQuery("UPDATE loans SET email = ?, updated_at = ? WHERE user_id = ?", email, time.Now(), userID).Exec()
+}
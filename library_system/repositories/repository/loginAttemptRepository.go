@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"library-system/apperrors"
+	"library-system/models"
+	"time"
+)
+
+// LoginAttemptRepository is the store behind login throttling. Counters must be
+// shared by every app instance to be effective, so multi-instance deployments use the
+// database store; a single instance can use the in-memory one.
+type LoginAttemptRepository interface {
+	// GetAttempt returns nil without an error when the key has no recent failures.
+	GetAttempt(key string) (*models.LoginAttempt, error)
+	// RecordFailure atomically counts a failure. The count restarts when the previous
+	// failure is older than window.
+	RecordFailure(key string, at time.Time, window time.Duration) (*models.LoginAttempt, error)
+	LockUntil(key string, until time.Time) error
+	ClearAttempts(key string) error
+}
+
+type loginAttemptRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewLoginAttemptRepository(db *pop.Connection) LoginAttemptRepository {
+	return &loginAttemptRepositoryImpl{DB: db}
+}
+
+func (r *loginAttemptRepositoryImpl) GetAttempt(key string) (*models.LoginAttempt, error) {
+	attempt := &models.LoginAttempt{}
+	if err := r.DB.Where("id = ?", key).First(attempt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding login attempts: %w", err)
+	}
+	return attempt, nil
+}
+
+func (r *loginAttemptRepositoryImpl) RecordFailure(key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	// failures is assigned before last_failure_at, so the comparison sees the old value.
+	err := r.DB.RawQuery(
+		`INSERT INTO login_attempts (id, failures, last_failure_at, created_at, updated_at) VALUES (?, 1, ?, ?, ?)
+		ON DUPLICATE KEY UPDATE failures = IF(last_failure_at < ?, 1, failures + 1), last_failure_at = VALUES(last_failure_at), updated_at = VALUES(updated_at)`,
+		key, at, at, at, at.Add(-window),
+	).Exec()
+	if err != nil {
+		return nil, fmt.Errorf("error recording login failure: %w", err)
+	}
+
+	attempt, err := r.GetAttempt(key)
+	if err != nil {
+		return nil, err
+	}
+	if attempt == nil {
+		return nil, apperrors.NotFound("login attempts not found")
+	}
+	return attempt, nil
+}
+
+func (r *loginAttemptRepositoryImpl) LockUntil(key string, until time.Time) error {
+	err := r.DB.RawQuery("UPDATE login_attempts SET locked_until = ?, updated_at = ? WHERE id = ?", until, time.Now(), key).Exec()
+	if err != nil {
+		return fmt.Errorf("error locking login: %w", err)
+	}
+	return nil
+}
+
+func (r *loginAttemptRepositoryImpl) ClearAttempts(key string) error {
+	if err := r.DB.RawQuery("DELETE FROM login_attempts WHERE id = ?", key).Exec(); err != nil {
+		return fmt.Errorf("error clearing login attempts: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"library-system/models"
+	"sync"
+	"time"
+)
+
+// memoryLoginAttemptRetention is how long an idle counter is kept before it may be
+// pruned. It must outlast any failure window and lockout.
+const memoryLoginAttemptRetention = 24 * time.Hour
+
+type memoryLoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts map[string]models.LoginAttempt
+	writes   int
+}
+
+// NewMemoryLoginAttemptRepository keeps counters in process memory. They are lost on
+// restart and not shared between instances.
+func NewMemoryLoginAttemptRepository() LoginAttemptRepository {
+	return &memoryLoginAttemptRepository{attempts: map[string]models.LoginAttempt{}}
+}
+
+func (r *memoryLoginAttemptRepository) GetAttempt(key string) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		return nil, nil
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) RecordFailure(key string, at time.Time, window time.Duration) (*models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{ID: key, CreatedAt: at}
+	}
+	if attempt.LastFailureAt.Before(at.Add(-window)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = at
+	attempt.UpdatedAt = at
+	r.attempts[key] = attempt
+
+	r.writes++
+	if r.writes%1000 == 0 {
+		r.prune(at)
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepository) LockUntil(key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		attempt.UpdatedAt = time.Now()
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *memoryLoginAttemptRepository) ClearAttempts(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, key)
+	return nil
+}
+
+// prune drops idle counters so an attacker cycling through keys cannot grow the map
+// without bound.
+func (r *memoryLoginAttemptRepository) prune(now time.Time) {
+	for key, attempt := range r.attempts {
+		if attempt.LastFailureAt.Before(now.Add(-memoryLoginAttemptRetention)) && !attempt.IsLocked(now) {
+			delete(r.attempts, key)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/models"
+)
+
+type UserIdentityRepository interface {
+	AddIdentity(identity *models.UserIdentity) error
+	GetIdentity(provider, subject string) (*models.UserIdentity, error)
+	GetIdentityByUsername(provider, username string) (*models.UserIdentity, error)
+	GetIdentitiesByUser(userID uuid.UUID) ([]models.UserIdentity, error)
+	UpdateIdentity(identity *models.UserIdentity) error
+	DeleteIdentitiesByUser(userID uuid.UUID) error
+}
+
+type userIdentityRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewUserIdentityRepository(db *pop.Connection) UserIdentityRepository {
+	return &userIdentityRepositoryImpl{DB: db}
+}
+
+func (r *userIdentityRepositoryImpl) AddIdentity(identity *models.UserIdentity) error {
+	if err := r.DB.Create(identity); err != nil {
+		return fmt.Errorf("error adding user identity: %w", err)
+	}
+	return nil
+}
+
+// GetIdentity returns nil without an error when the subject has never signed in.
+func (r *userIdentityRepositoryImpl) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := r.DB.Where("provider = ? AND subject = ?", provider, subject).First(identity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding user identity: %w", err)
+	}
+	return identity, nil
+}
+
+// GetIdentityByUsername returns nil without an error when nobody has signed in with the
+// username.
+func (r *userIdentityRepositoryImpl) GetIdentityByUsername(provider, username string) (*models.UserIdentity, error) {
+	identity := &models.UserIdentity{}
+	err := r.DB.Where("provider = ? AND username = ?", provider, username).Order("last_used_at desc").First(identity)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error finding user identity: %w", err)
+	}
+	return identity, nil
+}
+
+func (r *userIdentityRepositoryImpl) GetIdentitiesByUser(userID uuid.UUID) ([]models.UserIdentity, error) {
+	identities := []models.UserIdentity{}
+	if err := r.DB.Where("user_id = ?", userID).All(&identities); err != nil {
+		return nil, fmt.Errorf("error finding user identities: %w", err)
+	}
+	return identities, nil
+}
+
+func (r *userIdentityRepositoryImpl) UpdateIdentity(identity *models.UserIdentity) error {
+	if err := r.DB.Update(identity); err != nil {
+		return fmt.Errorf("error updating user identity: %w", err)
+	}
+	return nil
+}
+
+func (r *userIdentityRepositoryImpl) DeleteIdentitiesByUser(userID uuid.UUID) error {
+	if err := r.DB.RawQuery("DELETE FROM user_identities WHERE user_id = ?", userID).Exec(); err != nil {
+		return fmt.Errorf("error deleting user identities: %w", err)
+	}
+	return nil
+}
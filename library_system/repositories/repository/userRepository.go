@@ -5,14 +5,32 @@ import (
 	"errors"
 	"github.com/gobuffalo/pop/v6"
 	"github.com/gofrs/uuid"
+	"library-system/apperrors"
 	"library-system/models"
 	"log"
+	"strings"
+	"time"
 )
 
 type UserRepository interface {
 	AddUser(user *models.User) error
 	GetUserByID(ID uuid.UUID) (*models.User, error)
 	GetUserByEmail(email string) (*models.User, error)
+	GetUserByCardNumber(card string) (*models.User, error)
+	UpdateUser(user *models.User) error
+	ListUsers(filter UserFilter) ([]models.User, int, error)
+	GetUsersByIDs(ids []uuid.UUID) ([]models.User, error)
+	GetUsersWithoutCardNumber() ([]models.User, error)
+}
+
+// UserFilter narrows the user directory. Empty fields match everything; Page starts at 1.
+type UserFilter struct {
+	Query      string
+	CardNumber string
+	Status     string
+	PatronType string
+	Page       int
+	PerPage    int
 }
 
 type UserRepositoryImpl struct {
@@ -34,7 +52,7 @@ func (r *UserRepositoryImpl) AddUser(user *models.User) error {
 		existingUser := &models.User{}
 		err := tx.Where("email = ?", user.Email).First(existingUser)
 		if err == nil {
-			return errors.New("email already exists")
+			return apperrors.Conflict("email already exists")
 		}
 		if !errors.Is(err, sql.ErrNoRows) {
 			return err
@@ -48,13 +66,34 @@ func (r *UserRepositoryImpl) GetUserByID(ID uuid.UUID) (*models.User, error) {
 	err := r.DB.Where("id = ?", ID).First(user)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return nil, errors.New("user not found")
+			return nil, apperrors.NotFound("user not found")
 		}
 		return nil, err
 	}
 	return user, nil
 }
 
+// GetUsersByIDs loads several users in one query. Missing IDs are simply absent from the result.
+func (r *UserRepositoryImpl) GetUsersByIDs(ids []uuid.UUID) ([]models.User, error) {
+	users := []models.User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+	if err := r.DB.Where("id IN (?)", uuidArgs(ids)...).All(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// GetUsersWithoutCardNumber returns the open accounts created before card numbers existed.
+func (r *UserRepositoryImpl) GetUsersWithoutCardNumber() ([]models.User, error) {
+	users := []models.User{}
+	if err := r.DB.Where("(card_number IS NULL OR card_number = '') AND closed_at IS NULL").All(&users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepositoryImpl) GetUserByEmail(email string) (*models.User, error) {
 	log.Printf("Querying user by email: %s", email)
 	user := &models.User{}
@@ -70,3 +109,72 @@ func (r *UserRepositoryImpl) GetUserByEmail(email string) (*models.User, error)
 	log.Printf("User found: %v", user)
 	return user, nil
 }
+
+func (r *UserRepositoryImpl) GetUserByCardNumber(card string) (*models.User, error) {
+	user := &models.User{}
+	err := r.DB.Where("card_number = ?", card).First(user)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+func (r *UserRepositoryImpl) UpdateUser(user *models.User) error {
+	return r.DB.Transaction(func(tx *pop.Connection) error {
+		existingUser := &models.User{}
+		err := tx.Where("email = ? AND id <> ?", user.Email, user.ID).First(existingUser)
+		if err == nil {
+			return apperrors.Conflict("email already exists")
+		}
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		return tx.Update(user)
+	})
+}
+
+func (r *UserRepositoryImpl) ListUsers(filter UserFilter) ([]models.User, int, error) {
+	users := []models.User{}
+	q := r.DB.Paginate(filter.Page, filter.PerPage)
+
+	if filter.Query != "" {
+		like := "%" + escapeLike(filter.Query) + "%"
+		// pop joins Where clauses with AND, so the alternatives need their own parentheses
+		// to stay inside the other filters.
+		if filter.CardNumber != "" {
+			q = q.Where("(name LIKE ? OR email LIKE ? OR card_number = ?)", like, like, filter.CardNumber)
+		} else {
+			q = q.Where("(name LIKE ? OR email LIKE ?)", like, like)
+		}
+	}
+	if filter.PatronType != "" {
+		q = q.Where("patron_type = ?", filter.PatronType)
+	}
+
+	now := time.Now()
+	suspended := "(suspended_at IS NOT NULL AND (suspended_until IS NULL OR suspended_until > ?))"
+	switch filter.Status {
+	case models.UserStatusClosed:
+		q = q.Where("closed_at IS NOT NULL")
+	case models.UserStatusSuspended:
+		q = q.Where("closed_at IS NULL AND "+suspended, now)
+	case models.UserStatusUnverified:
+		q = q.Where("closed_at IS NULL AND email_verified_at IS NULL AND NOT "+suspended, now)
+	case models.UserStatusActive:
+		q = q.Where("closed_at IS NULL AND email_verified_at IS NOT NULL AND NOT "+suspended, now)
+	}
+
+	if err := q.Order("name asc, email asc").All(&users); err != nil {
+		return nil, 0, err
+	}
+	return users, q.Paginator.TotalEntriesSize, nil
+}
+
+// escapeLike makes % and _ in a search term match themselves. Backslash is the LIKE
+// escape character in MySQL.
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term)
+}
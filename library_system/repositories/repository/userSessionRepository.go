@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+	"time"
+)
+
+type UserSessionRepository interface {
+	AddSession(session *models.UserSession) error
+	GetSessionByID(sessionID uuid.UUID) (*models.UserSession, error)
+	GetSessionsByUser(userID uuid.UUID) ([]models.UserSession, error)
+	UpdateSession(session *models.UserSession) error
+	// RevokeUserSessions revokes every active session of the user except keep, which
+	// may be uuid.Nil to revoke them all.
+	RevokeUserSessions(userID uuid.UUID, keep uuid.UUID, revokedAt time.Time) error
+}
+
+type userSessionRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewUserSessionRepository(db *pop.Connection) UserSessionRepository {
+	return &userSessionRepositoryImpl{DB: db}
+}
+
+func (r *userSessionRepositoryImpl) AddSession(session *models.UserSession) error {
+	if err := r.DB.Create(session); err != nil {
+		return fmt.Errorf("error adding session: %w", err)
+	}
+	return nil
+}
+
+func (r *userSessionRepositoryImpl) GetSessionByID(sessionID uuid.UUID) (*models.UserSession, error) {
+	session := &models.UserSession{}
+	if err := r.DB.Find(session, sessionID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("session not found")
+		}
+		return nil, fmt.Errorf("error finding session: %w", err)
+	}
+	return session, nil
+}
+
+func (r *userSessionRepositoryImpl) GetSessionsByUser(userID uuid.UUID) ([]models.UserSession, error) {
+	var sessions []models.UserSession
+	if err := r.DB.Where("user_id = ?", userID).Order("last_seen_at desc").All(&sessions); err != nil {
+		return nil, fmt.Errorf("error fetching sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *userSessionRepositoryImpl) UpdateSession(session *models.UserSession) error {
+	if err := r.DB.Update(session); err != nil {
+		return fmt.Errorf("error updating session: %w", err)
+	}
+	return nil
+}
+
+func (r *userSessionRepositoryImpl) RevokeUserSessions(userID uuid.UUID, keep uuid.UUID, revokedAt time.Time) error {
+	err := r.DB.RawQuery(
+		"UPDATE user_sessions SET revoked_at = ?, updated_at = ? WHERE user_id = ? AND id <> ? AND revoked_at IS NULL",
+		revokedAt, revokedAt, userID, keep,
+	).Exec()
+	if err != nil {
+		return fmt.Errorf("error revoking sessions: %w", err)
+	}
+	return nil
+}
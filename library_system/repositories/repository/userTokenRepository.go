@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gobuffalo/pop/v6"
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/models"
+	"time"
+)
+
+type UserTokenRepository interface {
+	AddToken(token *models.UserToken) error
+	GetTokenByHash(purpose, hash string) (*models.UserToken, error)
+	UpdateToken(token *models.UserToken) error
+	CountTokensSince(userID uuid.UUID, purpose string, since time.Time) (int, error)
+	InvalidateTokens(userID uuid.UUID, purpose string) error
+}
+
+type userTokenRepositoryImpl struct {
+	DB *pop.Connection
+}
+
+func NewUserTokenRepository(db *pop.Connection) UserTokenRepository {
+	return &userTokenRepositoryImpl{DB: db}
+}
+
+func (r *userTokenRepositoryImpl) AddToken(token *models.UserToken) error {
+	if err := r.DB.Create(token); err != nil {
+		return fmt.Errorf("error adding user token: %w", err)
+	}
+	return nil
+}
+
+func (r *userTokenRepositoryImpl) GetTokenByHash(purpose, hash string) (*models.UserToken, error) {
+	token := &models.UserToken{}
+	err := r.DB.Where("purpose = ? AND token_hash = ?", purpose, hash).First(token)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, apperrors.NotFound("token not found")
+		}
+		return nil, fmt.Errorf("error finding user token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *userTokenRepositoryImpl) UpdateToken(token *models.UserToken) error {
+	if err := r.DB.Update(token); err != nil {
+		return fmt.Errorf("error updating user token: %w", err)
+	}
+	return nil
+}
+
+func (r *userTokenRepositoryImpl) CountTokensSince(userID uuid.UUID, purpose string, since time.Time) (int, error) {
+	count, err := r.DB.Where("user_id = ? AND purpose = ? AND created_at >= ?", userID, purpose, since).
+		Count(&models.UserToken{})
+	if err != nil {
+		return 0, fmt.Errorf("error counting user tokens: %w", err)
+	}
+	return count, nil
+}
+
+func (r *userTokenRepositoryImpl) InvalidateTokens(userID uuid.UUID, purpose string) error {
+	err := r.DB.RawQuery("UPDATE user_tokens SET used_at = ?, updated_at = ? WHERE user_id = ? AND purpose = ? AND used_at IS NULL",
+		time.Now(), time.Now(), userID, purpose).Exec()
+	if err != nil {
+		return fmt.Errorf("error invalidating user tokens: %w", err)
+	}
+	return nil
+}
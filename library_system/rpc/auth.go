@@ -0,0 +1,137 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"library-system/models"
+	"library-system/rpc/librarypb"
+	"library-system/services"
+)
+
+// rule is the gRPC counterpart of the middleware on an HTTP route group.
+type rule struct {
+	public bool
+	roles  []string
+	scope  string
+}
+
+var staff = []string{models.RoleLibrarian, models.RoleAdmin}
+
+// rules lists every method. A method without an entry is refused, so a new RPC cannot be
+// reachable before someone decides who may call it.
+var rules = map[string]rule{
+	librarypb.BookService_GetBook_FullMethodName:              {public: true},
+	librarypb.BookService_ListBooks_FullMethodName:            {public: true},
+	librarypb.BookService_ExportCatalogue_FullMethodName:      {public: true},
+	librarypb.BookService_GetBookStatusHistory_FullMethodName: {public: true},
+	librarypb.BookService_AddBook_FullMethodName:              {roles: staff, scope: models.ScopeBooksWrite},
+	librarypb.BookService_UpdateBook_FullMethodName:           {roles: staff, scope: models.ScopeBooksWrite},
+	librarypb.BookService_ChangeBookStatus_FullMethodName:     {roles: staff, scope: models.ScopeBooksWrite},
+	librarypb.BookService_RemoveBook_FullMethodName:           {roles: staff, scope: models.ScopeBooksWrite},
+
+	librarypb.UserService_GetUser_FullMethodName:      {scope: models.ScopeLoansWrite},
+	librarypb.UserService_ListUsers_FullMethodName:    {roles: staff, scope: models.ScopeLoansWrite},
+	librarypb.UserService_ListLoans_FullMethodName:    {scope: models.ScopeLoansWrite},
+	librarypb.UserService_CheckOutBook_FullMethodName: {scope: models.ScopeLoansWrite},
+	librarypb.UserService_ReserveBook_FullMethodName:  {scope: models.ScopeLoansWrite},
+	librarypb.UserService_ReturnLoan_FullMethodName:   {scope: models.ScopeLoansWrite},
+}
+
+type callerKey struct{}
+
+// caller returns the token's owner, or nil on public methods called without a token.
+func caller(ctx context.Context) *models.User {
+	user, _ := ctx.Value(callerKey{}).(*models.User)
+	return user
+}
+
+type authenticator struct {
+	tokens *services.TokenServices
+}
+
+func (a *authenticator) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := a.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (a *authenticator) stream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := a.authenticate(stream.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+}
+
+// authenticate resolves the bearer token in the "authorization" metadata and applies the
+// method's rule, with the same checks and messages as the HTTP middleware.
+func (a *authenticator) authenticate(ctx context.Context, method string) (context.Context, error) {
+	rule, ok := rules[method]
+	if !ok {
+		return nil, status.Error(codes.PermissionDenied, "You do not have permission to perform this action")
+	}
+
+	raw, ok := bearerToken(ctx)
+	if !ok {
+		if rule.public {
+			return ctx, nil
+		}
+		return nil, status.Error(codes.Unauthenticated, "Authentication required")
+	}
+
+	user, token, err := a.tokens.Authenticate(raw)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	if len(rule.roles) > 0 {
+		if !user.HasRole(rule.roles...) {
+			return nil, status.Error(codes.PermissionDenied, "You do not have permission to perform this action")
+		}
+		if user.RequiresTwoFactor() && !user.TwoFactorEnabled() {
+			return nil, status.Error(codes.PermissionDenied, "Two-factor authentication must be enabled for your role")
+		}
+	}
+	if rule.scope != "" && !token.HasScope(rule.scope) {
+		return nil, status.Errorf(codes.PermissionDenied, "Token is missing the %s scope", rule.scope)
+	}
+	return context.WithValue(ctx, callerKey{}, user), nil
+}
+
+// checkActingForOthers applies the two-factor rule of the REST desk routes to staff who
+// read or act on another user's account.
+func checkActingForOthers(actor *models.User) error {
+	if !services.CanManageCatalogue(actor) {
+		return status.Error(codes.PermissionDenied, "You can only manage your own loans")
+	}
+	if actor.RequiresTwoFactor() && !actor.TwoFactorEnabled() {
+		return status.Error(codes.PermissionDenied, "Two-factor authentication must be enabled for your role")
+	}
+	return nil
+}
+
+func bearerToken(ctx context.Context) (string, bool) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, value := range md.Get("authorization") {
+		if token, ok := strings.CutPrefix(value, "Bearer "); ok && token != "" {
+			return token, true
+		}
+	}
+	return "", false
+}
+
+// authenticatedStream carries the caller into streaming handlers.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,154 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"library-system/Dto"
+	"library-system/rpc/librarypb"
+	"library-system/services"
+)
+
+type bookServer struct {
+	librarypb.UnimplementedBookServiceServer
+	books *services.BookServices
+}
+
+func (s *bookServer) GetBook(ctx context.Context, req *librarypb.GetBookRequest) (*librarypb.Book, error) {
+	bookID, err := parseID(req.GetId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.books.GetBookByID(bookID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toBook(*book), nil
+}
+
+func (s *bookServer) ListBooks(ctx context.Context, req *librarypb.ListBooksRequest) (*librarypb.ListBooksResponse, error) {
+	var books []Dto.BookResponse
+	var err error
+	if req.GetQuery() != "" {
+		books, err = s.books.SearchBook(req.GetQuery())
+	} else {
+		books, err = s.books.GetAllBooks()
+	}
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &librarypb.ListBooksResponse{}
+	for _, book := range books {
+		response.Books = append(response.Books, toBook(book))
+	}
+	return response, nil
+}
+
+func (s *bookServer) ExportCatalogue(req *librarypb.ExportCatalogueRequest, stream grpc.ServerStreamingServer[librarypb.Book]) error {
+	err := s.books.ExportCatalogue(func(book Dto.BookResponse) error {
+		return stream.Send(toBook(book))
+	})
+	if err != nil {
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+		return toStatus(err)
+	}
+	return nil
+}
+
+func (s *bookServer) GetBookStatusHistory(ctx context.Context, req *librarypb.GetBookRequest) (*librarypb.BookStatusHistory, error) {
+	bookID, err := parseID(req.GetId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	changes, err := s.books.GetBookStatusHistory(bookID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	history := &librarypb.BookStatusHistory{}
+	for _, change := range changes {
+		history.Changes = append(history.Changes, &librarypb.BookStatusChange{
+			Id:         change.ID.String(),
+			BookId:     change.BookID.String(),
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			Reason:     change.Reason,
+			ChangedAt:  timestamppb.New(change.ChangedAt),
+		})
+	}
+	return history, nil
+}
+
+func (s *bookServer) AddBook(ctx context.Context, req *librarypb.AddBookRequest) (*librarypb.Book, error) {
+	book, err := s.books.AddBook(Dto.BookRequest{
+		Title:  req.GetTitle(),
+		Author: req.GetAuthor(),
+		ISBN:   req.GetIsbn(),
+		Status: req.GetStatus(),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toBook(*book), nil
+}
+
+func (s *bookServer) UpdateBook(ctx context.Context, req *librarypb.UpdateBookRequest) (*librarypb.Book, error) {
+	bookID, err := parseID(req.GetId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.books.UpdateBook(bookID, Dto.BookPatchRequest{Title: req.Title, Author: req.Author})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toBook(*book), nil
+}
+
+func (s *bookServer) ChangeBookStatus(ctx context.Context, req *librarypb.ChangeBookStatusRequest) (*librarypb.Book, error) {
+	bookID, err := parseID(req.GetId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.books.ChangeBookStatus(bookID, Dto.BookStatusRequest{Status: req.GetStatus(), Reason: req.GetReason()})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toBook(*book), nil
+}
+
+func (s *bookServer) RemoveBook(ctx context.Context, req *librarypb.RemoveBookRequest) (*librarypb.Book, error) {
+	bookID, err := parseID(req.GetId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	book, err := s.books.RemoveBook(bookID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toBook(*book), nil
+}
+
+func toBook(book Dto.BookResponse) *librarypb.Book {
+	return &librarypb.Book{
+		Id:     book.ID.String(),
+		Title:  book.Title,
+		Author: book.Author,
+		Isbn:   book.ISBN,
+		Status: book.Status,
+	}
+}
+
+func parseID(id, kind string) (uuid.UUID, error) {
+	parsed, err := uuid.FromString(id)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "Invalid %s ID format", kind)
+	}
+	return parsed, nil
+}
@@ -0,0 +1,37 @@
+package rpc
+
+import (
+	"errors"
+	"log"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"library-system/apperrors"
+	"library-system/services"
+)
+
+// toStatus maps service errors onto gRPC codes the way controllers map them onto HTTP
+// statuses, keeping the service's message. Unexpected failures are logged and reported
+// without their message, which may expose internals.
+func toStatus(err error) error {
+	var blocked *services.BorrowingBlockedError
+	switch {
+	case errors.As(err, &blocked):
+		return status.Error(codes.FailedPrecondition, blocked.Message)
+	case errors.Is(err, apperrors.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, apperrors.ErrConflict):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.Is(err, apperrors.ErrUnavailable):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, apperrors.ErrValidation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, apperrors.ErrForbidden):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, apperrors.ErrUnauthorized):
+		return status.Error(codes.Unauthenticated, err.Error())
+	default:
+		log.Printf("gRPC call failed: %v", err)
+		return status.Error(codes.Internal, "An unexpected error occurred")
+	}
+}
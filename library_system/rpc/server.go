@@ -0,0 +1,24 @@
+// Package rpc serves BookServices and UserServices over gRPC for internal systems such as
+// finance and campus apps. The contract is rpc/library/v1/library.proto; the Go code in
+// librarypb is generated from it and must not be edited by hand.
+package rpc
+
+//go:generate protoc -I . --go_out=.. --go_opt=module=library-system --go-grpc_out=.. --go-grpc_opt=module=library-system library/v1/library.proto
+
+import (
+	"google.golang.org/grpc"
+	"library-system/rpc/librarypb"
+	"library-system/services"
+)
+
+// NewServer registers the book and user services behind token authentication.
+func NewServer(books *services.BookServices, users *services.UserServices, tokens *services.TokenServices) *grpc.Server {
+	auth := &authenticator{tokens: tokens}
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(auth.unary),
+		grpc.ChainStreamInterceptor(auth.stream),
+	)
+	librarypb.RegisterBookServiceServer(server, &bookServer{books: books})
+	librarypb.RegisterUserServiceServer(server, &userServer{users: users})
+	return server
+}
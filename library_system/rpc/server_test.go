@@ -0,0 +1,206 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/mock"
+	"library-system/rpc/librarypb"
+	"library-system/services"
+)
+
+type fixture struct {
+	conn      *grpc.ClientConn
+	tokens    *services.TokenServices
+	users     *mock.MockUserRepo
+	patron    *models.User
+	librarian *models.User
+	books     []models.Book
+}
+
+func setupServer(t *testing.T) *fixture {
+	t.Helper()
+	verifiedAt := time.Now()
+	f := &fixture{
+		patron: &models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com", Role: models.RolePatron, EmailVerifiedAt: &verifiedAt},
+		librarian: &models.User{ID: uuid.Must(uuid.NewV4()), Name: "chidi okeke", Email: "chidi@example.com", Role: models.RoleLibrarian,
+			EmailVerifiedAt: &verifiedAt, TOTPSecret: "JBSWY3DPEHPK3PXP", TOTPEnabledAt: &verifiedAt},
+	}
+	f.books = []models.Book{
+		{ID: uuid.Must(uuid.NewV4()), Title: "Dune", Author: "Frank Herbert", ISBN: "0-441-17271-7", Status: models.StatusAvailable},
+		{ID: uuid.Must(uuid.NewV4()), Title: "Emma", Author: "Jane Austen", ISBN: "0-14-143958-7", Status: models.StatusAvailable},
+		{ID: uuid.Must(uuid.NewV4()), Title: "Ulysses", Author: "James Joyce", ISBN: "0-394-74312-9", Status: models.StatusAvailable},
+	}
+
+	bookRepo := &mock.MockBookRepository{MockBooks: f.books}
+	userRepo := &mock.MockUserRepo{MockUser: []models.User{*f.patron, *f.librarian}}
+	f.users = userRepo
+	bookService := services.NewBookServices(bookRepo)
+	bookService.StatusRepo = &mock.MockBookStatusRepository{}
+	userService := &services.UserServices{UserRepo: userRepo, BookRepo: bookRepo, LoanRepo: &mock.MockLoanRepository{}, FineRepo: &mock.MockFineRepository{}}
+	f.tokens = &services.TokenServices{TokenRepo: &mock.MockAPITokenRepository{}, UserRepo: userRepo}
+
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(bookService, userService, f.tokens)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	f.conn = conn
+	return f
+}
+
+// as returns a context carrying a fresh token for user with the given scopes.
+func (f *fixture) as(t *testing.T, user *models.User, scopes ...string) context.Context {
+	t.Helper()
+	created, err := f.tokens.CreateToken(user, Dto.APITokenRequest{Name: "integration", Scopes: scopes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+created.Token)
+}
+
+func TestEveryMethodHasARule(t *testing.T) {
+	for _, desc := range []grpc.ServiceDesc{librarypb.BookService_ServiceDesc, librarypb.UserService_ServiceDesc} {
+		for _, method := range desc.Methods {
+			_, ok := rules["/"+desc.ServiceName+"/"+method.MethodName]
+			assert.True(t, ok, "no rule for %s", method.MethodName)
+		}
+		for _, stream := range desc.Streams {
+			_, ok := rules["/"+desc.ServiceName+"/"+stream.StreamName]
+			assert.True(t, ok, "no rule for %s", stream.StreamName)
+		}
+	}
+}
+
+func TestExportCatalogueStreamsEveryBook(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewBookServiceClient(f.conn)
+
+	stream, err := client.ExportCatalogue(context.Background(), &librarypb.ExportCatalogueRequest{})
+	assert.NoError(t, err)
+
+	var titles []string
+	for {
+		book, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if !assert.NoError(t, err) {
+			return
+		}
+		titles = append(titles, book.GetTitle())
+	}
+	assert.ElementsMatch(t, []string{"Dune", "Emma", "Ulysses"}, titles)
+}
+
+func TestAddBookRequiresStaffWithScope(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewBookServiceClient(f.conn)
+	request := &librarypb.AddBookRequest{Title: "Beloved", Author: "Toni Morrison", Isbn: "1-4000-3341-6", Status: models.StatusAvailable}
+
+	_, err := client.AddBook(context.Background(), request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.AddBook(f.as(t, f.patron, models.ScopeLoansWrite), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.AddBook(f.as(t, f.librarian, models.ScopeLoansWrite), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "Token is missing the books:write scope", status.Convert(err).Message())
+
+	book, err := client.AddBook(f.as(t, f.librarian, models.ScopeBooksWrite), request)
+	assert.NoError(t, err)
+	assert.Equal(t, "Beloved", book.GetTitle())
+}
+
+func TestCheckOutAndReturn(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewUserServiceClient(f.conn)
+	ctx := f.as(t, f.patron, models.ScopeLoansWrite)
+
+	loan, err := client.CheckOutBook(ctx, &librarypb.LoanActionRequest{BookId: f.books[0].ID.String()})
+	assert.NoError(t, err)
+	assert.Equal(t, f.patron.Email, loan.GetEmail())
+	assert.Equal(t, models.StatusBorrowed, loan.GetStatus())
+	assert.Nil(t, loan.GetReturnDate())
+
+	loans, err := client.ListLoans(ctx, &librarypb.ListLoansRequest{})
+	assert.NoError(t, err)
+	assert.Len(t, loans.GetLoans(), 1)
+
+	returned, err := client.ReturnLoan(ctx, &librarypb.ReturnLoanRequest{LoanId: loan.GetId()})
+	assert.NoError(t, err)
+	assert.NotNil(t, returned.GetReturnDate())
+}
+
+func TestLoanActionsCheckOwnership(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewUserServiceClient(f.conn)
+	ctx := f.as(t, f.patron, models.ScopeLoansWrite)
+
+	_, err := client.CheckOutBook(ctx, &librarypb.LoanActionRequest{BookId: f.books[0].ID.String(), Email: f.librarian.Email})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.ListLoans(ctx, &librarypb.ListLoansRequest{UserId: f.librarian.ID.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetUser(ctx, &librarypb.GetUserRequest{Id: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestStaffNeedTwoFactorToReachOtherAccounts(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewUserServiceClient(f.conn)
+	withoutTwoFactor := *f.librarian
+	withoutTwoFactor.ID = uuid.Must(uuid.NewV4())
+	withoutTwoFactor.Email = "tunde@example.com"
+	withoutTwoFactor.TOTPEnabledAt = nil
+	f.users.MockUser = append(f.users.MockUser, withoutTwoFactor)
+	ctx := f.as(t, &withoutTwoFactor, models.ScopeLoansWrite)
+
+	_, err := client.GetUser(ctx, &librarypb.GetUserRequest{Id: f.patron.ID.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Equal(t, "Two-factor authentication must be enabled for your role", status.Convert(err).Message())
+
+	_, err = client.CheckOutBook(ctx, &librarypb.LoanActionRequest{BookId: f.books[0].ID.String(), Email: f.patron.Email})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetUser(ctx, &librarypb.GetUserRequest{Id: withoutTwoFactor.ID.String()})
+	assert.NoError(t, err)
+}
+
+func TestUserMethodsNeedTheLoansScope(t *testing.T) {
+	f := setupServer(t)
+	client := librarypb.NewUserServiceClient(f.conn)
+	ctx := f.as(t, f.librarian, models.ScopeBooksWrite)
+
+	_, err := client.GetUser(ctx, &librarypb.GetUserRequest{Id: f.patron.ID.String()})
+	assert.Equal(t, "Token is missing the loans:write scope", status.Convert(err).Message())
+
+	_, err = client.ListLoans(ctx, &librarypb.ListLoansRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.ListUsers(ctx, &librarypb.ListUsersRequest{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -0,0 +1,219 @@
+package rpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/rpc/librarypb"
+	"library-system/services"
+)
+
+type userServer struct {
+	librarypb.UnimplementedUserServiceServer
+	users *services.UserServices
+}
+
+func (s *userServer) GetUser(ctx context.Context, req *librarypb.GetUserRequest) (*librarypb.User, error) {
+	userID, err := parseID(req.GetId(), "user")
+	if err != nil {
+		return nil, err
+	}
+	actor := caller(ctx)
+	if actor.ID != userID && !services.CanManageCatalogue(actor) {
+		return nil, status.Error(codes.PermissionDenied, "You can only look up your own account")
+	}
+	if actor.ID != userID {
+		if err := checkActingForOthers(actor); err != nil {
+			return nil, err
+		}
+	}
+	user, err := s.users.GetProfile(userID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUser(*user), nil
+}
+
+func (s *userServer) ListUsers(ctx context.Context, req *librarypb.ListUsersRequest) (*librarypb.ListUsersResponse, error) {
+	list, err := s.users.ListUsers(Dto.UserSearchRequest{
+		Query:      req.GetQuery(),
+		Status:     req.GetStatus(),
+		PatronType: req.GetPatronType(),
+		Page:       int(req.GetPage()),
+		PerPage:    int(req.GetPerPage()),
+	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &librarypb.ListUsersResponse{
+		Page:       int32(list.Page),
+		PerPage:    int32(list.PerPage),
+		Total:      int32(list.Total),
+		TotalPages: int32(list.TotalPages),
+	}
+	for _, user := range list.Users {
+		response.Users = append(response.Users, toUser(user))
+	}
+	return response, nil
+}
+
+// ListLoans defaults to the caller's own loans when no user is named.
+func (s *userServer) ListLoans(ctx context.Context, req *librarypb.ListLoansRequest) (*librarypb.ListLoansResponse, error) {
+	actor := caller(ctx)
+	userID := actor.ID
+	if req.GetUserId() != "" {
+		var err error
+		if userID, err = parseID(req.GetUserId(), "user"); err != nil {
+			return nil, err
+		}
+	}
+	if actor.ID != userID {
+		if err := checkActingForOthers(actor); err != nil {
+			return nil, err
+		}
+	}
+
+	kind := models.LoanKindLoan
+	if req.GetKind() == librarypb.LoanKind_LOAN_KIND_HOLD {
+		kind = models.LoanKindHold
+	}
+	loans, err := s.users.ListLoans(userID, kind)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	response := &librarypb.ListLoansResponse{}
+	for _, loan := range loans {
+		response.Loans = append(response.Loans, &librarypb.Loan{
+			Id:         loan.ID.String(),
+			BookId:     loan.BookID.String(),
+			BookTitle:  loan.BookTitle,
+			BookIsbn:   loan.BookISBN,
+			Email:      loan.Email,
+			LoanDate:   timestamppb.New(loan.LoanDate),
+			ReturnDate: optionalTimestamp(loan.ReturnDate),
+		})
+	}
+	return response, nil
+}
+
+func (s *userServer) CheckOutBook(ctx context.Context, req *librarypb.LoanActionRequest) (*librarypb.LoanAction, error) {
+	request, err := s.loanActionRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.users.CheckOutBook(*request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toLoanAction(response), nil
+}
+
+func (s *userServer) ReserveBook(ctx context.Context, req *librarypb.LoanActionRequest) (*librarypb.LoanAction, error) {
+	request, err := s.loanActionRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	response, err := s.users.ReserveBook(*request)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toLoanAction(response), nil
+}
+
+func (s *userServer) ReturnLoan(ctx context.Context, req *librarypb.ReturnLoanRequest) (*librarypb.LoanAction, error) {
+	loanID, err := parseID(req.GetLoanId(), "loan")
+	if err != nil {
+		return nil, err
+	}
+	actor := caller(ctx)
+	if err := s.checkOwnLoan(actor, loanID); err != nil {
+		return nil, err
+	}
+	response, err := s.users.ReturnLoan(actor, loanID)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toLoanAction(response), nil
+}
+
+func (s *userServer) loanActionRequest(ctx context.Context, req *librarypb.LoanActionRequest) (*Dto.BookActionRequest, error) {
+	bookID, err := parseID(req.GetBookId(), "book")
+	if err != nil {
+		return nil, err
+	}
+	request := &Dto.BookActionRequest{
+		BookID:     bookID,
+		Email:      req.GetEmail(),
+		CardNumber: req.GetCardNumber(),
+	}
+	actor := caller(ctx)
+	if err := s.users.AuthorizeLoanAction(actor, request); err != nil {
+		return nil, toStatus(err)
+	}
+	if !strings.EqualFold(strings.TrimSpace(request.Email), actor.Email) {
+		if err := checkActingForOthers(actor); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// checkOwnLoan lets staff without two-factor authentication return only their own loans.
+func (s *userServer) checkOwnLoan(actor *models.User, loanID uuid.UUID) error {
+	if !actor.RequiresTwoFactor() || actor.TwoFactorEnabled() {
+		return nil
+	}
+	loans, err := s.users.ListLoans(actor.ID, models.LoanKindLoan)
+	if err != nil {
+		return toStatus(err)
+	}
+	for _, loan := range loans {
+		if loan.ID == loanID {
+			return nil
+		}
+	}
+	return checkActingForOthers(actor)
+}
+
+func toUser(user Dto.UserResponse) *librarypb.User {
+	return &librarypb.User{
+		Id:               user.ID.String(),
+		Name:             user.Name,
+		Email:            user.Email,
+		CardNumber:       user.CardNumber,
+		EmailVerified:    user.EmailVerified,
+		Role:             user.Role,
+		PatronType:       user.PatronType,
+		Status:           user.Status,
+		Suspended:        user.Suspended,
+		TwoFactorEnabled: user.TwoFactor,
+	}
+}
+
+func toLoanAction(response *Dto.BookActionResponse) *librarypb.LoanAction {
+	return &librarypb.LoanAction{
+		Id:         response.ID.String(),
+		UserId:     response.UserID.String(),
+		BookId:     response.BookID.String(),
+		Status:     response.Status,
+		LoanDate:   timestamppb.New(response.LoanDate),
+		ReturnDate: optionalTimestamp(response.ReturnDate),
+		Email:      response.Email,
+	}
+}
+
+func optionalTimestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -1,9 +1,11 @@
 package services
 
 import (
+	"errors"
 	"fmt"
 	"github.com/gofrs/uuid"
 	"library-system/Dto"
+	"library-system/apperrors"
 	"library-system/models"
 	"library-system/repositories/repository"
 	"regexp"
@@ -12,7 +14,10 @@ import (
 )
 
 type BookServices struct {
-	BookRepo repository.BookRepository
+	BookRepo   repository.BookRepository
+	StatusRepo repository.BookStatusRepository
+	// LoanRepo keeps manual status changes from stranding an open loan or hold.
+	LoanRepo repository.LoanRepository
 }
 
 func NewBookServices(bookRepo repository.BookRepository) *BookServices {
@@ -24,17 +29,17 @@ func NewBookServices(bookRepo repository.BookRepository) *BookServices {
 func (s *BookServices) AddBook(req Dto.BookRequest) (*Dto.BookResponse, error) {
 	if strings.TrimSpace(req.ISBN) != "" {
 		if !isValidISBN(req.ISBN) {
-			return nil, fmt.Errorf("invalid ISBN format")
+			return nil, apperrors.Validation("invalid ISBN format")
 		}
 
 		existingBook, err := s.BookRepo.GetBookByISBN(req.ISBN)
 		if err != nil {
-			if !strings.Contains(err.Error(), "not found") {
+			if !errors.Is(err, apperrors.ErrNotFound) {
 				return nil, fmt.Errorf("error checking ISBN: %w", err)
 			}
 		}
 		if existingBook != nil {
-			return nil, fmt.Errorf("duplicate ISBN: book with ISBN %s already exists", req.ISBN)
+			return nil, apperrors.Conflict("duplicate ISBN: book with ISBN %s already exists", req.ISBN)
 		}
 	}
 
@@ -49,7 +54,7 @@ func (s *BookServices) AddBook(req Dto.BookRequest) (*Dto.BookResponse, error) {
 	}
 
 	if err := book.Validate(); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
+		return nil, apperrors.Validation("validation error: %w", err)
 	}
 
 	if err := s.BookRepo.AddBook(book); err != nil {
@@ -76,23 +81,27 @@ func (s *BookServices) UpdateBookByISBN(request Dto.BookRequest) (*Dto.BookRespo
 
 	existingBook, err := s.BookRepo.GetBookByISBN(request.ISBN)
 	if err != nil {
-		return nil, fmt.Errorf("book with ISBN %s not found", request.ISBN)
+		return nil, apperrors.NotFound("book with ISBN %s not found", request.ISBN)
 	}
 
 	if existingBook == nil {
-		return nil, fmt.Errorf("book with ISBN %s not found", request.ISBN)
+		return nil, apperrors.NotFound("book with ISBN %s not found", request.ISBN)
 	}
 
 	if existingBook.ISBN != request.ISBN {
-		return nil, fmt.Errorf("cannot update ISBN")
+		return nil, apperrors.Validation("cannot update ISBN")
+	}
+	// Status changes need a reason and leave a history entry, so they go through
+	// ChangeBookStatus instead.
+	if request.Status != "" && normalizeStatus(request.Status) != existingBook.Status {
+		return nil, apperrors.Validation("status cannot be changed here; use the status endpoint with a reason")
 	}
 	existingBook.Title = request.Title
 	existingBook.Author = request.Author
-	existingBook.Status = request.Status
 	existingBook.UpdatedAt = time.Now()
 
 	if err := existingBook.Validate(); err != nil {
-		return nil, fmt.Errorf("validation error: %w", err)
+		return nil, apperrors.Validation("validation error: %w", err)
 	}
 
 	if err := s.BookRepo.UpdateBook(existingBook); err != nil {
@@ -102,9 +111,112 @@ func (s *BookServices) UpdateBookByISBN(request Dto.BookRequest) (*Dto.BookRespo
 	return mapBookToResponse(existingBook), nil
 }
 
+// UpdateBook applies a partial update to the book with the given ID.
+func (s *BookServices) UpdateBook(bookID uuid.UUID, request Dto.BookPatchRequest) (*Dto.BookResponse, error) {
+	book, err := s.BookRepo.GetBookByID(bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find book: %w", err)
+	}
+
+	if request.Title != nil {
+		book.Title = strings.TrimSpace(*request.Title)
+	}
+	if request.Author != nil {
+		book.Author = strings.TrimSpace(*request.Author)
+	}
+	book.UpdatedAt = time.Now()
+
+	if err := book.Validate(); err != nil {
+		return nil, apperrors.Validation("validation error: %w", err)
+	}
+
+	if err := s.BookRepo.UpdateBook(book); err != nil {
+		return nil, fmt.Errorf("failed to update book: %w", err)
+	}
+
+	return mapBookToResponse(book), nil
+}
+
+// ChangeBookStatus records a manual status change, such as a book found damaged. Books
+// only become borrowed or reserved through checkouts and holds, which keep the loan
+// records in step with the status.
+func (s *BookServices) ChangeBookStatus(bookID uuid.UUID, request Dto.BookStatusRequest) (*Dto.BookResponse, error) {
+	status := normalizeStatus(request.Status)
+	reason := strings.TrimSpace(request.Reason)
+	if reason == "" {
+		return nil, apperrors.Validation("validation error: reason is required")
+	}
+	if status == models.StatusBorrowed || status == models.StatusReserved {
+		return nil, apperrors.Validation("validation error: books are marked %s by checkouts and holds only", status)
+	}
+
+	book, err := s.BookRepo.GetBookByID(bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find book: %w", err)
+	}
+
+	previousStatus := book.Status
+	if err := book.TransitionTo(status); err != nil {
+		return nil, apperrors.Validation("validation error: %w", err)
+	}
+
+	// The return or pickup of an open loan or hold expects the book to still be
+	// borrowed or reserved, so it could never be closed after a manual move.
+	if previousStatus == models.StatusBorrowed || previousStatus == models.StatusReserved {
+		loans, err := s.LoanRepo.GetActiveLoansByBooks([]uuid.UUID{book.ID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to check loans: %w", err)
+		}
+		if len(loans) > 0 {
+			return nil, apperrors.Conflict("book is %s by an open loan or hold; close it before changing the status", previousStatus)
+		}
+	}
+
+	now := time.Now()
+	book.UpdatedAt = now
+	change := &models.BookStatusChange{
+		ID:         uuid.Must(uuid.NewV4()),
+		BookID:     book.ID,
+		FromStatus: previousStatus,
+		ToStatus:   book.Status,
+		Reason:     reason,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := s.StatusRepo.ChangeStatus(book, change); err != nil {
+		return nil, fmt.Errorf("failed to update book status: %w", err)
+	}
+
+	return mapBookToResponse(book), nil
+}
+
+func (s *BookServices) GetBookStatusHistory(bookID uuid.UUID) ([]Dto.BookStatusChangeResponse, error) {
+	if _, err := s.BookRepo.GetBookByID(bookID); err != nil {
+		return nil, fmt.Errorf("failed to find book: %w", err)
+	}
+
+	changes, err := s.StatusRepo.GetStatusChangesByBook(bookID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status history: %w", err)
+	}
+
+	responses := make([]Dto.BookStatusChangeResponse, 0, len(changes))
+	for _, change := range changes {
+		responses = append(responses, Dto.BookStatusChangeResponse{
+			ID:         change.ID,
+			BookID:     change.BookID,
+			FromStatus: change.FromStatus,
+			ToStatus:   change.ToStatus,
+			Reason:     change.Reason,
+			ChangedAt:  change.CreatedAt,
+		})
+	}
+	return responses, nil
+}
+
 func (s *BookServices) SearchBook(query string) ([]Dto.BookResponse, error) {
 	if query == "" {
-		return nil, fmt.Errorf("search query cannot be empty")
+		return nil, apperrors.Validation("search query cannot be empty")
 	}
 
 	books, err := s.BookRepo.SearchBook(query)
@@ -156,6 +268,41 @@ func (s *BookServices) GetBookByID(bookID uuid.UUID) (*Dto.BookResponse, error)
 	return mapBookToResponse(book), nil
 }
 
+// catalogueExportBatch is how many books ExportCatalogue loads at a time.
+var catalogueExportBatch = 500
+
+// ExportCatalogue hands every book to send, in ID order, loading the catalogue a batch
+// at a time so exports do not hold it all in memory. It stops at the first error from send.
+func (s *BookServices) ExportCatalogue(send func(Dto.BookResponse) error) error {
+	after := uuid.Nil
+	for {
+		books, err := s.BookRepo.GetBooksAfter(after, catalogueExportBatch)
+		if err != nil {
+			return fmt.Errorf("failed to export catalogue: %w", err)
+		}
+		for _, book := range books {
+			if err := send(*mapBookToResponse(book)); err != nil {
+				return err
+			}
+		}
+		if len(books) < catalogueExportBatch {
+			return nil
+		}
+		after = books[len(books)-1].ID
+	}
+}
+
+// GetBooksByIDs looks up several books at once, for callers that batch lookups. IDs
+// that are not in the catalogue are left out rather than reported as errors.
+func (s *BookServices) GetBooksByIDs(bookIDs []uuid.UUID) ([]Dto.BookResponse, error) {
+	books, err := s.BookRepo.GetBooksByIDs(bookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find books: %w", err)
+	}
+
+	return mapBooksToResponses(books), nil
+}
+
 func isValidISBN(isbn string) bool {
 	isbn = strings.ReplaceAll(isbn, "-", "")
 	isbn10Regex := regexp.MustCompile(`^\d{9}[\dXx]$`)
@@ -8,6 +8,7 @@ import (
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
 	"library-system/Dto"
+	"library-system/apperrors"
 	"library-system/models"
 	"library-system/repositories/mock"
 )
@@ -66,6 +67,7 @@ func TestBookServices_TestThatYouCanAddBookWithDuplicateISBN(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, book)
 	assert.Contains(t, err.Error(), "duplicate ISBN")
+	assert.True(t, errors.Is(err, apperrors.ErrConflict))
 }
 
 func TestBookServices_TestThatAddBookCanThrowAddBookError(t *testing.T) {
@@ -146,7 +148,7 @@ func TestBookServices_TestYouCanUpdateBookByISBN(t *testing.T) {
 		Title:  "Updated Title",
 		Author: "Updated Author",
 		ISBN:   "0-7475-3269-9",
-		Status: models.StatusBorrowed,
+		Status: models.StatusAvailable,
 	}
 
 	book, err := service.UpdateBookByISBN(req)
@@ -184,6 +186,7 @@ func TestBookServices_TestUpdateBookByISBN_NotFound(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, book)
 	assert.Contains(t, err.Error(), "book with ISBN 0-7475-3269-9 not found")
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
 }
 
 func TestBookServices_UpdateBookByISBN_DatabaseError(t *testing.T) {
@@ -290,3 +293,265 @@ func TestBookServices_GetAllBooks(t *testing.T) {
 	assert.Equal(t, mockBooks[0].Title, books[0].Title)
 	assert.Equal(t, mockBooks[1].Title, books[1].Title)
 }
+
+func TestBookServices_TestThatYouCanChangeBookStatus(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{
+			{ID: bookID, Title: "Test Book", Author: "Test Author", ISBN: "0-7475-3269-9", Status: models.StatusAvailable},
+		},
+	}
+	statusRepo := &mock.MockBookStatusRepository{}
+	service := &BookServices{BookRepo: mockRepo, StatusRepo: statusRepo}
+
+	book, err := service.ChangeBookStatus(bookID, Dto.BookStatusRequest{
+		Status: models.StatusDamaged,
+		Reason: "Water damage on return",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusDamaged, book.Status)
+	assert.Len(t, statusRepo.MockChanges, 1)
+	assert.Equal(t, models.StatusAvailable, statusRepo.MockChanges[0].FromStatus)
+	assert.Equal(t, models.StatusDamaged, statusRepo.MockChanges[0].ToStatus)
+	assert.Equal(t, "Water damage on return", statusRepo.MockChanges[0].Reason)
+}
+
+func TestBookServices_TestThatChangeBookStatusRejectsIllegalTransition(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{
+			{ID: bookID, Title: "Test Book", Author: "Test Author", ISBN: "0-7475-3269-9", Status: models.StatusWithdrawn},
+		},
+	}
+	statusRepo := &mock.MockBookStatusRepository{}
+	service := &BookServices{BookRepo: mockRepo, StatusRepo: statusRepo}
+
+	book, err := service.ChangeBookStatus(bookID, Dto.BookStatusRequest{
+		Status: models.StatusAvailable,
+		Reason: "Found on shelf",
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, book)
+	assert.Contains(t, err.Error(), "invalid status transition from withdrawn to available")
+	assert.Empty(t, statusRepo.MockChanges)
+}
+
+func TestBookServices_TestThatChangeBookStatusRequiresReason(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{
+			{ID: bookID, Title: "Test Book", Author: "Test Author", ISBN: "0-7475-3269-9", Status: models.StatusAvailable},
+		},
+	}
+	service := &BookServices{BookRepo: mockRepo, StatusRepo: &mock.MockBookStatusRepository{}}
+
+	book, err := service.ChangeBookStatus(bookID, Dto.BookStatusRequest{Status: models.StatusLost})
+
+	assert.Error(t, err)
+	assert.Nil(t, book)
+	assert.Contains(t, err.Error(), "reason is required")
+}
+
+func TestBookServices_TestThatUpdateBookByISBNKeepsStatusWhenOmitted(t *testing.T) {
+	existingBook := models.Book{
+		ID:     uuid.Must(uuid.NewV4()),
+		Title:  "Original Title",
+		Author: "Original Author",
+		ISBN:   "0-7475-3269-9",
+		Status: models.StatusReserved,
+	}
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{existingBook},
+	}
+	service := NewBookServices(mockRepo)
+
+	book, err := service.UpdateBookByISBN(Dto.BookRequest{
+		Title:  "Updated Title",
+		Author: "Updated Author",
+		ISBN:   "0-7475-3269-9",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusReserved, book.Status)
+}
+
+func TestBookServices_TestThatUpdateBookOnlyChangesGivenFields(t *testing.T) {
+	existingBook := models.Book{
+		ID:     uuid.Must(uuid.NewV4()),
+		Title:  "Original Title",
+		Author: "Original Author",
+		ISBN:   "0-7475-3269-9",
+		Status: models.StatusBorrowed,
+	}
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{existingBook},
+	}
+	service := NewBookServices(mockRepo)
+
+	title := "Updated Title"
+	book, err := service.UpdateBook(existingBook.ID, Dto.BookPatchRequest{Title: &title})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated Title", book.Title)
+	assert.Equal(t, "Original Author", book.Author)
+	assert.Equal(t, models.StatusBorrowed, book.Status)
+}
+
+func TestBookServices_TestThatUpdateBookRejectsBlankTitle(t *testing.T) {
+	existingBook := models.Book{
+		ID:     uuid.Must(uuid.NewV4()),
+		Title:  "Original Title",
+		Author: "Original Author",
+		ISBN:   "0-7475-3269-9",
+		Status: models.StatusAvailable,
+	}
+	service := NewBookServices(&mock.MockBookRepository{MockBooks: []models.Book{existingBook}})
+
+	blank := "  "
+	book, err := service.UpdateBook(existingBook.ID, Dto.BookPatchRequest{Title: &blank})
+
+	assert.Nil(t, book)
+	assert.True(t, errors.Is(err, apperrors.ErrValidation))
+}
+
+func TestBookServices_TestThatUpdateBookReportsUnknownBook(t *testing.T) {
+	service := NewBookServices(mock.NewMockBookRepository())
+
+	title := "Updated Title"
+	book, err := service.UpdateBook(uuid.Must(uuid.NewV4()), Dto.BookPatchRequest{Title: &title})
+
+	assert.Nil(t, book)
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+}
+
+func TestBookServices_TestThatGetBooksByIDsSkipsUnknownBooks(t *testing.T) {
+	existingBook := models.Book{
+		ID:     uuid.Must(uuid.NewV4()),
+		Title:  "Dune",
+		Author: "Frank Herbert",
+		ISBN:   "0-441-17271-7",
+		Status: models.StatusAvailable,
+	}
+	service := NewBookServices(&mock.MockBookRepository{MockBooks: []models.Book{existingBook}})
+
+	books, err := service.GetBooksByIDs([]uuid.UUID{existingBook.ID, uuid.Must(uuid.NewV4())})
+
+	assert.NoError(t, err)
+	if assert.Len(t, books, 1) {
+		assert.Equal(t, existingBook.ID, books[0].ID)
+		assert.Equal(t, "Dune", books[0].Title)
+	}
+}
+
+func TestBookServices_TestThatExportCatalogueSendsEveryBookInBatches(t *testing.T) {
+	defer func(batch int) { catalogueExportBatch = batch }(catalogueExportBatch)
+	catalogueExportBatch = 2
+
+	mockRepo := mock.NewMockBookRepository()
+	for _, title := range []string{"Dune", "Emma", "Ulysses", "Beloved", "Middlemarch"} {
+		mockRepo.MockBooks = append(mockRepo.MockBooks, models.Book{ID: uuid.Must(uuid.NewV4()), Title: title, Status: models.StatusAvailable})
+	}
+	service := NewBookServices(mockRepo)
+
+	seen := map[uuid.UUID]bool{}
+	err := service.ExportCatalogue(func(book Dto.BookResponse) error {
+		seen[book.ID] = true
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, seen, 5)
+}
+
+func TestBookServices_TestThatExportCatalogueStopsWhenSendFails(t *testing.T) {
+	mockRepo := mock.NewMockBookRepository()
+	mockRepo.MockBooks = []models.Book{
+		{ID: uuid.Must(uuid.NewV4()), Title: "Dune"},
+		{ID: uuid.Must(uuid.NewV4()), Title: "Emma"},
+	}
+	service := NewBookServices(mockRepo)
+
+	sent := 0
+	closed := errors.New("stream closed")
+	err := service.ExportCatalogue(func(book Dto.BookResponse) error {
+		sent++
+		return closed
+	})
+
+	assert.Equal(t, closed, err)
+	assert.Equal(t, 1, sent)
+}
+
+func TestBookServices_TestThatUpdateBookByISBNRejectsStatusChanges(t *testing.T) {
+	existingBook := models.Book{
+		ID:     uuid.Must(uuid.NewV4()),
+		Title:  "Original Title",
+		Author: "Original Author",
+		ISBN:   "0-7475-3269-9",
+		Status: models.StatusAvailable,
+	}
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{existingBook},
+	}
+	service := NewBookServices(mockRepo)
+
+	book, err := service.UpdateBookByISBN(Dto.BookRequest{
+		Title:  "Updated Title",
+		Author: "Updated Author",
+		ISBN:   "0-7475-3269-9",
+		Status: models.StatusLost,
+	})
+
+	assert.Nil(t, book)
+	assert.True(t, errors.Is(err, apperrors.ErrValidation))
+	assert.Equal(t, models.StatusAvailable, mockRepo.MockBooks[0].Status)
+}
+
+func TestBookServices_TestThatChangeBookStatusLeavesCirculationStatusesToLoans(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{
+			{ID: bookID, Title: "Test Book", Author: "Test Author", ISBN: "0-7475-3269-9", Status: models.StatusAvailable},
+		},
+	}
+	statusRepo := &mock.MockBookStatusRepository{}
+	service := &BookServices{BookRepo: mockRepo, StatusRepo: statusRepo}
+
+	for _, status := range []string{models.StatusBorrowed, models.StatusReserved} {
+		book, err := service.ChangeBookStatus(bookID, Dto.BookStatusRequest{Status: status, Reason: "Handed over at the desk"})
+
+		assert.Nil(t, book)
+		assert.True(t, errors.Is(err, apperrors.ErrValidation))
+	}
+	assert.Empty(t, statusRepo.MockChanges)
+}
+
+func TestBookServices_TestThatChangeBookStatusKeepsOpenLoansClosable(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	mockRepo := &mock.MockBookRepository{
+		MockBooks: []models.Book{
+			{ID: bookID, Title: "Test Book", Author: "Test Author", ISBN: "0-7475-3269-9", Status: models.StatusBorrowed},
+		},
+	}
+	loanRepo := &mock.MockLoanRepository{
+		MockLoans: []models.Loan{{ID: uuid.Must(uuid.NewV4()), BookID: bookID, UserID: uuid.Must(uuid.NewV4())}},
+	}
+	statusRepo := &mock.MockBookStatusRepository{}
+	service := &BookServices{BookRepo: mockRepo, StatusRepo: statusRepo, LoanRepo: loanRepo}
+
+	book, err := service.ChangeBookStatus(bookID, Dto.BookStatusRequest{Status: models.StatusLost, Reason: "Patron reported it lost"})
+
+	assert.Nil(t, book)
+	assert.True(t, errors.Is(err, apperrors.ErrConflict))
+	assert.Empty(t, statusRepo.MockChanges)
+
+	returnedAt := time.Now()
+	loanRepo.MockLoans[0].ReturnDate = &returnedAt
+	book, err = service.ChangeBookStatus(bookID, Dto.BookStatusRequest{Status: models.StatusLost, Reason: "Patron reported it lost"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.StatusLost, book.Status)
+	assert.Len(t, statusRepo.MockChanges, 1)
+}
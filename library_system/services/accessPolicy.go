@@ -0,0 +1,39 @@
+package services
+
+import (
+	"library-system/models"
+)
+
+// The access policy answers "may this user do that" questions that depend on more
+// than the caller's role, so controllers and middleware share one set of rules.
+
+func CanManageCatalogue(user *models.User) bool {
+	return user != nil && user.HasRole(models.RoleLibrarian, models.RoleAdmin)
+}
+
+func CanManageUsers(user *models.User) bool {
+	return user != nil && user.HasRole(models.RoleAdmin)
+}
+
+// CanViewPatrons reports whether actor may read other users' accounts, loans and holds.
+// It mirrors the /users desk routes: staff with two-factor authentication enabled,
+// signed in or using a token with the loans:write scope. token is nil for sessions.
+func CanViewPatrons(actor *models.User, token *models.APIToken) bool {
+	if !CanManageCatalogue(actor) || (actor.RequiresTwoFactor() && !actor.TwoFactorEnabled()) {
+		return false
+	}
+	return token == nil || token.HasScope(models.ScopeLoansWrite)
+}
+
+// CanActOnPatronLoans reports whether actor may check out, return or reserve books on
+// behalf of the patron with the given email. Patrons may only act on their own loans;
+// desk staff may act for anyone.
+func CanActOnPatronLoans(actor *models.User, patronEmail string) bool {
+	if actor == nil {
+		return false
+	}
+	if CanManageCatalogue(actor) {
+		return true
+	}
+	return normalizeEmail(actor.Email) == normalizeEmail(patronEmail)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"io"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"time"
+)
+
+type ExportServices struct {
+	UserRepo  repository.UserRepository
+	LoanRepo  repository.LoanRepository
+	BookRepo  repository.BookRepository
+	FineRepo  repository.FineRepository
+	TokenRepo repository.APITokenRepository
+}
+
+func (s *ExportServices) ExportUserData(userID uuid.UUID) (*Dto.UserDataExport, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loans, err := s.LoanRepo.GetLoansByUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to export loans: %w", err)
+	}
+
+	fines, err := s.FineRepo.GetFinesByUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to export fines: %w", err)
+	}
+
+	tokens, err := s.TokenRepo.GetTokensByUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to export API tokens: %w", err)
+	}
+
+	export := &Dto.UserDataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile: Dto.UserProfileExport{
+			ID:               user.ID,
+			Name:             user.Name,
+			Email:            user.Email,
+			CardNumber:       user.CardNumber.String,
+			Role:             user.Role,
+			PatronType:       user.PatronType,
+			EmailVerifiedAt:  user.EmailVerifiedAt,
+			ClosedAt:         user.ClosedAt,
+			SuspendedAt:      user.SuspendedAt,
+			SuspendedUntil:   user.SuspendedUntil,
+			SuspensionReason: user.SuspensionReason,
+			CreatedAt:        user.CreatedAt,
+			UpdatedAt:        user.UpdatedAt,
+		},
+		Fines:     []Dto.FineExport{},
+		APITokens: []Dto.APITokenResponse{},
+	}
+
+	export.Loans, export.Holds = summariseLoans(s.BookRepo, loans)
+
+	for _, fine := range fines {
+		export.Fines = append(export.Fines, Dto.FineExport{
+			ID:          fine.ID,
+			LoanID:      fine.LoanID,
+			AmountCents: fine.AmountCents,
+			Reason:      fine.Reason,
+			PaidAt:      fine.PaidAt,
+			CreatedAt:   fine.CreatedAt,
+		})
+	}
+
+	for i := range tokens {
+		export.APITokens = append(export.APITokens, mapTokenToResponse(&tokens[i]))
+	}
+
+	return export, nil
+}
+
+// summariseLoans splits a patron's loans into loans and holds, adding the title and
+// ISBN of each book that is still in the catalogue.
+func summariseLoans(bookRepo repository.BookRepository, loans []models.Loan) ([]Dto.LoanSummary, []Dto.LoanSummary) {
+	summaries := []Dto.LoanSummary{}
+	holds := []Dto.LoanSummary{}
+	for _, loan := range loans {
+		entry := Dto.LoanSummary{
+			ID:         loan.ID,
+			BookID:     loan.BookID,
+			Email:      loan.Email,
+			LoanDate:   loan.LoanDate,
+			ReturnDate: loan.ReturnDate,
+		}
+		if book, err := bookRepo.GetBookByID(loan.BookID); err == nil && book != nil {
+			entry.BookTitle = book.Title
+			entry.BookISBN = book.ISBN
+		}
+		if loan.Kind == models.LoanKindHold {
+			holds = append(holds, entry)
+		} else {
+			summaries = append(summaries, entry)
+		}
+	}
+	return summaries, holds
+}
+
+// WriteExportArchive writes the export as a ZIP archive with one JSON file per section
+// plus the complete export, so it can be read without tooling.
+func WriteExportArchive(w io.Writer, export *Dto.UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"export.json", export},
+		{"profile.json", export.Profile},
+		{"loans.json", export.Loans},
+		{"holds.json", export.Holds},
+		{"fines.json", export.Fines},
+		{"api_tokens.json", export.APITokens},
+	}
+
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.GeneratedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to add %s to archive: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("Failed to write %s: %w", file.name, err)
+		}
+	}
+
+	return archive.Close()
+}
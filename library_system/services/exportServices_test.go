@@ -0,0 +1,111 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupExportService(user models.User) (*ExportServices, *mock.MockLoanRepository, *mock.MockBookRepository) {
+	loanRepo := &mock.MockLoanRepository{}
+	bookRepo := mock.NewMockBookRepository()
+	service := &ExportServices{
+		UserRepo:  &mock.MockUserRepo{MockUser: []models.User{user}},
+		LoanRepo:  loanRepo,
+		BookRepo:  bookRepo,
+		FineRepo:  &mock.MockFineRepository{},
+		TokenRepo: &mock.MockAPITokenRepository{},
+	}
+	return service, loanRepo, bookRepo
+}
+
+func TestExportServices_ExportUserData(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, loanRepo, bookRepo := setupExportService(user)
+
+	book := models.Book{ID: uuid.Must(uuid.NewV4()), Title: "Things Fall Apart", ISBN: "9780385474542"}
+	bookRepo.MockBooks = append(bookRepo.MockBooks, book)
+	loanRepo.MockLoans = []models.Loan{
+		{ID: uuid.Must(uuid.NewV4()), BookID: book.ID, UserID: user.ID, Email: user.Email, Kind: models.LoanKindLoan, LoanDate: time.Now()},
+		{ID: uuid.Must(uuid.NewV4()), BookID: uuid.Must(uuid.NewV4()), UserID: user.ID, Email: user.Email, Kind: models.LoanKindHold, LoanDate: time.Now()},
+		{ID: uuid.Must(uuid.NewV4()), BookID: book.ID, UserID: uuid.Must(uuid.NewV4()), Email: "someone@else.com", Kind: models.LoanKindLoan, LoanDate: time.Now()},
+	}
+	service.FineRepo.(*mock.MockFineRepository).MockFines = []models.Fine{
+		{ID: uuid.Must(uuid.NewV4()), UserID: user.ID, AmountCents: 250, Reason: "late return"},
+	}
+
+	export, err := service.ExportUserData(user.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, export.Profile.Email)
+	assert.Len(t, export.Loans, 1)
+	assert.Equal(t, "Things Fall Apart", export.Loans[0].BookTitle)
+	assert.Len(t, export.Holds, 1)
+	assert.Empty(t, export.Holds[0].BookTitle)
+	assert.Len(t, export.Fines, 1)
+	assert.Equal(t, 250, export.Fines[0].AmountCents)
+	assert.NotNil(t, export.APITokens)
+}
+
+func TestExportServices_ExportUserDataUnknownUser(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com"}
+	service, _, _ := setupExportService(user)
+
+	export, err := service.ExportUserData(uuid.Must(uuid.NewV4()))
+
+	assert.Error(t, err)
+	assert.Nil(t, export)
+}
+
+func TestExportServices_ExportUserDataLoanError(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com"}
+	service, loanRepo, _ := setupExportService(user)
+	loanRepo.GetLoansByUserError = errors.New("database error")
+
+	export, err := service.ExportUserData(user.ID)
+
+	assert.Error(t, err)
+	assert.Equal(t, "Failed to export loans: database error", err.Error())
+	assert.Nil(t, export)
+}
+
+func TestWriteExportArchive(t *testing.T) {
+	export := &Dto.UserDataExport{
+		GeneratedAt: time.Now().UTC(),
+		Profile:     Dto.UserProfileExport{Email: "meenah20@gmail.com"},
+		Loans:       []Dto.LoanSummary{},
+		Holds:       []Dto.LoanSummary{},
+		Fines:       []Dto.FineExport{},
+		APITokens:   []Dto.APITokenResponse{},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteExportArchive(&buf, export))
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	assert.NoError(t, err)
+
+	names := map[string]*zip.File{}
+	for _, f := range archive.File {
+		names[f.Name] = f
+	}
+	for _, name := range []string{"export.json", "profile.json", "loans.json", "holds.json", "fines.json", "api_tokens.json"} {
+		assert.Contains(t, names, name)
+	}
+
+	f, err := names["profile.json"].Open()
+	assert.NoError(t, err)
+	defer f.Close()
+	var profile Dto.UserProfileExport
+	assert.NoError(t, json.NewDecoder(f).Decode(&profile))
+	assert.Equal(t, "meenah20@gmail.com", profile.Email)
+}
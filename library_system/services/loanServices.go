@@ -0,0 +1,85 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+)
+
+// ListLoans returns the user's loans or holds, newest first, including returned ones.
+func (s *UserServices) ListLoans(userID uuid.UUID, kind string) ([]Dto.LoanSummary, error) {
+	loans, err := s.LoanRepo.GetLoansByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load loans: %w", err)
+	}
+
+	summaries, holds := summariseLoans(s.BookRepo, loans)
+	if kind == models.LoanKindHold {
+		return holds, nil
+	}
+	return summaries, nil
+}
+
+// ReturnLoan returns the book on the given loan. Patrons can only see their own loans, so
+// anyone else's is reported as not found; circulation staff can return any loan.
+func (s *UserServices) ReturnLoan(actor *models.User, loanID uuid.UUID) (*Dto.BookActionResponse, error) {
+	loan, err := s.LoanRepo.GetLoanByID(loanID)
+	if err != nil {
+		return nil, err
+	}
+	if loan.Kind == models.LoanKindHold || (loan.UserID != actor.ID && !CanManageCatalogue(actor)) {
+		return nil, apperrors.NotFound("loan not found")
+	}
+	return s.completeReturn(loan)
+}
+
+// AuthorizeLoanAction settles whose loan a checkout, return or reservation is for: the
+// patron named by card number or email, or the actor when neither is given. Patrons may
+// only act for themselves; desk staff may act for anyone.
+func (s *UserServices) AuthorizeLoanAction(actor *models.User, request *Dto.BookActionRequest) error {
+	if err := s.ResolveCardNumber(request); err != nil {
+		return err
+	}
+	if request.Email == "" && actor != nil {
+		request.Email = actor.Email
+	}
+	if !CanActOnPatronLoans(actor, request.Email) {
+		return apperrors.Forbidden("You can only manage your own loans")
+	}
+	return nil
+}
+
+// ListLoansForUsers returns the loans and holds of several users at once, newest first.
+func (s *UserServices) ListLoansForUsers(userIDs []uuid.UUID) ([]Dto.LoanResponse, error) {
+	loans, err := s.LoanRepo.GetLoansByUsers(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load loans: %w", err)
+	}
+	return mapLoansToResponses(loans), nil
+}
+
+// ListActiveLoansForBooks returns the open loans and holds on several books at once.
+func (s *UserServices) ListActiveLoansForBooks(bookIDs []uuid.UUID) ([]Dto.LoanResponse, error) {
+	loans, err := s.LoanRepo.GetActiveLoansByBooks(bookIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load loans: %w", err)
+	}
+	return mapLoansToResponses(loans), nil
+}
+
+func mapLoansToResponses(loans []models.Loan) []Dto.LoanResponse {
+	responses := make([]Dto.LoanResponse, 0, len(loans))
+	for _, loan := range loans {
+		responses = append(responses, Dto.LoanResponse{
+			ID:         loan.ID,
+			BookID:     loan.BookID,
+			UserID:     loan.UserID,
+			Kind:       loan.Kind,
+			LoanDate:   loan.LoanDate,
+			ReturnDate: loan.ReturnDate,
+		})
+	}
+	return responses
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupLoanService(loans ...models.Loan) (*UserServices, *mock.MockBookRepository) {
+	bookRepo := mock.NewMockBookRepository()
+	service := &UserServices{
+		LoanRepo: &mock.MockLoanRepository{MockLoans: loans},
+		BookRepo: bookRepo,
+	}
+	return service, bookRepo
+}
+
+func TestUserServices_ListLoans(t *testing.T) {
+	userID := uuid.Must(uuid.NewV4())
+	loan := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: userID, BookID: uuid.Must(uuid.NewV4()), Kind: models.LoanKindLoan}
+	hold := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: userID, BookID: uuid.Must(uuid.NewV4()), Kind: models.LoanKindHold}
+	other := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), Kind: models.LoanKindLoan}
+	service, _ := setupLoanService(loan, hold, other)
+
+	loans, err := service.ListLoans(userID, models.LoanKindLoan)
+	assert.NoError(t, err)
+	if assert.Len(t, loans, 1) {
+		assert.Equal(t, loan.ID, loans[0].ID)
+	}
+
+	holds, err := service.ListLoans(userID, models.LoanKindHold)
+	assert.NoError(t, err)
+	if assert.Len(t, holds, 1) {
+		assert.Equal(t, hold.ID, holds[0].ID)
+	}
+}
+
+func TestUserServices_ReturnLoan(t *testing.T) {
+	patron := &models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RolePatron}
+	bookID := uuid.Must(uuid.NewV4())
+	newLoan := func() models.Loan {
+		return models.Loan{
+			ID:       uuid.Must(uuid.NewV4()),
+			BookID:   bookID,
+			UserID:   patron.ID,
+			Kind:     models.LoanKindLoan,
+			LoanDate: time.Now().Add(-24 * time.Hour),
+		}
+	}
+	newBook := models.Book{ID: bookID, Title: "Dune", Author: "Frank Herbert", ISBN: "0-441-17271-7", Status: models.StatusBorrowed}
+
+	t.Run("patron returns own loan", func(t *testing.T) {
+		loan := newLoan()
+		service, bookRepo := setupLoanService(loan)
+		bookRepo.MockBooks = []models.Book{newBook}
+
+		response, err := service.ReturnLoan(patron, loan.ID)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, response.ReturnDate)
+		book, _ := bookRepo.GetBookByID(bookID)
+		assert.Equal(t, models.StatusAvailable, book.Status)
+	})
+
+	t.Run("another patron's loan is not found", func(t *testing.T) {
+		loan := newLoan()
+		service, bookRepo := setupLoanService(loan)
+		bookRepo.MockBooks = []models.Book{newBook}
+		stranger := &models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RolePatron}
+
+		response, err := service.ReturnLoan(stranger, loan.ID)
+
+		assert.Nil(t, response)
+		assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+	})
+
+	t.Run("librarian returns any loan", func(t *testing.T) {
+		loan := newLoan()
+		service, bookRepo := setupLoanService(loan)
+		bookRepo.MockBooks = []models.Book{newBook}
+		librarian := &models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleLibrarian}
+
+		_, err := service.ReturnLoan(librarian, loan.ID)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("already returned", func(t *testing.T) {
+		loan := newLoan()
+		returned := time.Now()
+		loan.ReturnDate = &returned
+		service, bookRepo := setupLoanService(loan)
+		bookRepo.MockBooks = []models.Book{newBook}
+
+		_, err := service.ReturnLoan(patron, loan.ID)
+
+		assert.True(t, errors.Is(err, apperrors.ErrValidation))
+	})
+
+	t.Run("unknown loan", func(t *testing.T) {
+		service, _ := setupLoanService()
+
+		_, err := service.ReturnLoan(patron, uuid.Must(uuid.NewV4()))
+
+		assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+	})
+}
+
+func TestUserServices_ListLoansForUsers(t *testing.T) {
+	first := uuid.Must(uuid.NewV4())
+	second := uuid.Must(uuid.NewV4())
+	loan := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: first, Kind: models.LoanKindLoan}
+	hold := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: second, Kind: models.LoanKindHold}
+	other := models.Loan{ID: uuid.Must(uuid.NewV4()), UserID: uuid.Must(uuid.NewV4()), Kind: models.LoanKindLoan}
+	service, _ := setupLoanService(loan, hold, other)
+
+	loans, err := service.ListLoansForUsers([]uuid.UUID{first, second})
+	assert.NoError(t, err)
+	if assert.Len(t, loans, 2) {
+		assert.Equal(t, first, loans[0].UserID)
+		assert.Equal(t, models.LoanKindLoan, loans[0].Kind)
+		assert.Equal(t, second, loans[1].UserID)
+		assert.Equal(t, models.LoanKindHold, loans[1].Kind)
+	}
+
+	service.LoanRepo = &mock.MockLoanRepository{GetLoansByUsersError: errors.New("db down")}
+	_, err = service.ListLoansForUsers([]uuid.UUID{first})
+	assert.Error(t, err)
+}
+
+func TestUserServices_ListActiveLoansForBooks(t *testing.T) {
+	bookID := uuid.Must(uuid.NewV4())
+	returned := time.Now()
+	open := models.Loan{ID: uuid.Must(uuid.NewV4()), BookID: bookID, Kind: models.LoanKindLoan}
+	closed := models.Loan{ID: uuid.Must(uuid.NewV4()), BookID: bookID, Kind: models.LoanKindLoan, ReturnDate: &returned}
+	elsewhere := models.Loan{ID: uuid.Must(uuid.NewV4()), BookID: uuid.Must(uuid.NewV4()), Kind: models.LoanKindHold}
+	service, _ := setupLoanService(open, closed, elsewhere)
+
+	loans, err := service.ListActiveLoansForBooks([]uuid.UUID{bookID})
+	assert.NoError(t, err)
+	if assert.Len(t, loans, 1) {
+		assert.Equal(t, open.ID, loans[0].ID)
+	}
+}
+
+func TestUserServices_AuthorizeLoanAction(t *testing.T) {
+	patron := &models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	librarian := &models.User{ID: uuid.Must(uuid.NewV4()), Email: "chidi@example.com", Role: models.RoleLibrarian}
+	service, _ := setupLoanService()
+
+	request := Dto.BookActionRequest{}
+	assert.NoError(t, service.AuthorizeLoanAction(patron, &request))
+	assert.Equal(t, patron.Email, request.Email)
+
+	request = Dto.BookActionRequest{Email: "bola@example.com"}
+	err := service.AuthorizeLoanAction(patron, &request)
+	assert.True(t, errors.Is(err, apperrors.ErrForbidden))
+
+	request = Dto.BookActionRequest{Email: "bola@example.com"}
+	assert.NoError(t, service.AuthorizeLoanAction(librarian, &request))
+	assert.Equal(t, "bola@example.com", request.Email)
+}
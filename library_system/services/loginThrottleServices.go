@@ -0,0 +1,240 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"log"
+	"strings"
+	"time"
+)
+
+// ThrottlePolicy decides how long a key must wait after repeated failed logins. Once
+// BackoffAfter failures have piled up each further failure doubles the wait, starting at
+// BaseDelay; at LockoutAfter failures the key is locked for LockoutDuration.
+type ThrottlePolicy struct {
+	BackoffAfter    int
+	BaseDelay       time.Duration
+	LockoutAfter    int
+	LockoutDuration time.Duration
+	// Window is how long a failure counts against the key.
+	Window time.Duration
+}
+
+var (
+	DefaultAccountThrottle = ThrottlePolicy{
+		BackoffAfter:    3,
+		BaseDelay:       time.Second,
+		LockoutAfter:    10,
+		LockoutDuration: 15 * time.Minute,
+		Window:          time.Hour,
+	}
+	// DefaultAddressThrottle is looser because several people can share an address.
+	DefaultAddressThrottle = ThrottlePolicy{
+		BackoffAfter:    20,
+		BaseDelay:       time.Second,
+		LockoutAfter:    100,
+		LockoutDuration: time.Hour,
+		Window:          time.Hour,
+	}
+)
+
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	if p.LockoutAfter > 0 && failures >= p.LockoutAfter {
+		return p.LockoutDuration
+	}
+	if p.BackoffAfter <= 0 || failures < p.BackoffAfter {
+		return 0
+	}
+	shift := failures - p.BackoffAfter
+	if shift > 20 {
+		shift = 20
+	}
+	delay := p.BaseDelay << shift
+	if p.LockoutDuration > 0 && delay > p.LockoutDuration {
+		delay = p.LockoutDuration
+	}
+	return delay
+}
+
+// LoginLockedError is returned while an account or address has to wait before it may
+// try to log in again.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "Too many failed login attempts. Try again later"
+}
+
+// LoginThrottleServices tracks failed logins per account and per client address.
+// Accounts are keyed by the submitted name whether or not it exists, so lockouts do
+// not reveal which accounts are registered.
+type LoginThrottleServices struct {
+	AttemptRepo   repository.LoginAttemptRepository
+	AuditRepo     repository.AuditEventRepository
+	UserRepo      repository.UserRepository
+	IdentityRepo  repository.UserIdentityRepository
+	AccountPolicy ThrottlePolicy
+	AddressPolicy ThrottlePolicy
+}
+
+// Check returns a *LoginLockedError when either the account or the address is waiting
+// out a backoff or lockout. Store errors are logged and let the login through.
+func (s *LoginThrottleServices) Check(account, ipAddress string) error {
+	now := time.Now()
+	var retryAfter time.Duration
+	for _, key := range []string{accountThrottleKey(account), addressThrottleKey(ipAddress)} {
+		attempt, err := s.AttemptRepo.GetAttempt(key)
+		if err != nil {
+			log.Printf("Failed to check login attempts for %s: %v", key, err)
+			continue
+		}
+		if attempt != nil && attempt.IsLocked(now) && attempt.LockedUntil.Sub(now) > retryAfter {
+			retryAfter = attempt.LockedUntil.Sub(now)
+		}
+	}
+	if retryAfter > 0 {
+		return &LoginLockedError{RetryAfter: retryAfter}
+	}
+	return nil
+}
+
+func (s *LoginThrottleServices) RecordFailure(account, ipAddress string) {
+	now := time.Now()
+	if s.recordFailure(accountThrottleKey(account), s.AccountPolicy, now) {
+		s.audit(models.AuditAccountLocked, nil, s.lookupUser(account), ipAddress, "account "+normalizeThrottleAccount(account), now)
+	}
+	if s.recordFailure(addressThrottleKey(ipAddress), s.AddressPolicy, now) {
+		s.audit(models.AuditAddressLocked, nil, nil, ipAddress, "address "+ipAddress, now)
+	}
+}
+
+// RecordSuccess forgets the account's failures. The address keeps its count so a
+// valid login cannot be used to reset guessing against other accounts.
+func (s *LoginThrottleServices) RecordSuccess(account string) {
+	if err := s.AttemptRepo.ClearAttempts(accountThrottleKey(account)); err != nil {
+		log.Printf("Failed to clear login attempts for %s: %v", normalizeThrottleAccount(account), err)
+	}
+}
+
+// DirectoryThrottleAccount is the account directory sign-ins are throttled under, kept
+// apart from emails so the two cannot collide.
+func DirectoryThrottleAccount(username string) string {
+	return directoryAccountPrefix + username
+}
+
+// TwoFactorThrottleAccount is the account two-factor codes are throttled under.
+func TwoFactorThrottleAccount(userID uuid.UUID) string {
+	return twoFactorAccountPrefix + userID.String()
+}
+
+const (
+	directoryAccountPrefix = "ldap:"
+	twoFactorAccountPrefix = "2fa:"
+)
+
+// Unlock lifts every lockout on the user's account before it runs out: password,
+// directory and two-factor sign-ins alike.
+func (s *LoginThrottleServices) Unlock(actor *models.User, userID uuid.UUID) error {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	accounts := []string{user.Email, TwoFactorThrottleAccount(user.ID)}
+	if s.IdentityRepo != nil {
+		identities, err := s.IdentityRepo.GetIdentitiesByUser(user.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to unlock account: %w", err)
+		}
+		for _, identity := range identities {
+			if identity.Provider == auth.ProviderLDAP && identity.Username != "" {
+				accounts = append(accounts, DirectoryThrottleAccount(identity.Username))
+			}
+		}
+	}
+	for _, account := range accounts {
+		if err := s.AttemptRepo.ClearAttempts(accountThrottleKey(account)); err != nil {
+			return fmt.Errorf("Failed to unlock account: %w", err)
+		}
+	}
+	s.audit(models.AuditAccountUnlocked, &actor.ID, &user.ID, "", "account "+user.Email, time.Now())
+	return nil
+}
+
+// recordFailure counts a failure against the key and reports whether it locked the key.
+func (s *LoginThrottleServices) recordFailure(key string, policy ThrottlePolicy, now time.Time) bool {
+	attempt, err := s.AttemptRepo.RecordFailure(key, now, policy.Window)
+	if err != nil {
+		log.Printf("Failed to record login failure for %s: %v", key, err)
+		return false
+	}
+	delay := policy.delay(attempt.Failures)
+	if delay <= 0 {
+		return false
+	}
+	if err := s.AttemptRepo.LockUntil(key, now.Add(delay)); err != nil {
+		log.Printf("Failed to lock %s: %v", key, err)
+		return false
+	}
+	return policy.LockoutAfter > 0 && attempt.Failures >= policy.LockoutAfter
+}
+
+// lookupUser finds the user a throttled account belongs to, if any.
+func (s *LoginThrottleServices) lookupUser(account string) *uuid.UUID {
+	account = normalizeThrottleAccount(account)
+	if id, ok := strings.CutPrefix(account, twoFactorAccountPrefix); ok {
+		userID, err := uuid.FromString(id)
+		if err != nil {
+			return nil
+		}
+		return &userID
+	}
+	if username, ok := strings.CutPrefix(account, directoryAccountPrefix); ok {
+		if s.IdentityRepo == nil {
+			return nil
+		}
+		identity, err := s.IdentityRepo.GetIdentityByUsername(auth.ProviderLDAP, username)
+		if err != nil || identity == nil {
+			return nil
+		}
+		return &identity.UserID
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(account)
+	if err != nil || user == nil {
+		return nil
+	}
+	return &user.ID
+}
+
+func (s *LoginThrottleServices) audit(action string, actorID, userID *uuid.UUID, ipAddress, detail string, now time.Time) {
+	event := &models.AuditEvent{
+		ID:        uuid.Must(uuid.NewV4()),
+		Action:    action,
+		ActorID:   actorID,
+		UserID:    userID,
+		IPAddress: ipAddress,
+		Detail:    detail,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.AuditRepo.AddEvent(event); err != nil {
+		log.Printf("Failed to record audit event %s: %v", action, err)
+	}
+}
+
+func normalizeThrottleAccount(account string) string {
+	return strings.ToLower(strings.TrimSpace(account))
+}
+
+func accountThrottleKey(account string) string {
+	return "account:" + normalizeThrottleAccount(account)
+}
+
+func addressThrottleKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
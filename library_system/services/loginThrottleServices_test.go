@@ -0,0 +1,152 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/mock"
+	"library-system/repositories/repository"
+)
+
+func setupLoginThrottle(users ...models.User) (*LoginThrottleServices, repository.LoginAttemptRepository, *mock.MockAuditEventRepository) {
+	attemptRepo := repository.NewMemoryLoginAttemptRepository()
+	auditRepo := &mock.MockAuditEventRepository{}
+	return &LoginThrottleServices{
+		AttemptRepo:   attemptRepo,
+		AuditRepo:     auditRepo,
+		UserRepo:      &mock.MockUserRepo{MockUser: users},
+		IdentityRepo:  &mock.MockUserIdentityRepository{},
+		AccountPolicy: DefaultAccountThrottle,
+		AddressPolicy: DefaultAddressThrottle,
+	}, attemptRepo, auditRepo
+}
+
+func TestThrottlePolicy_BacksOffExponentially(t *testing.T) {
+	policy := DefaultAccountThrottle
+
+	assert.Equal(t, time.Duration(0), policy.delay(2))
+	assert.Equal(t, time.Second, policy.delay(3))
+	assert.Equal(t, 2*time.Second, policy.delay(4))
+	assert.Equal(t, 64*time.Second, policy.delay(9))
+	assert.Equal(t, 15*time.Minute, policy.delay(10))
+	assert.Equal(t, 15*time.Minute, policy.delay(40))
+}
+
+func TestLoginThrottleServices_LocksAccountAfterRepeatedFailures(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, attemptRepo, auditRepo := setupLoginThrottle(patron)
+
+	for i := 0; i < 2; i++ {
+		service.RecordFailure("Meenah20@gmail.com", "192.0.2.10")
+	}
+	assert.NoError(t, service.Check("meenah20@gmail.com", "192.0.2.10"))
+
+	service.RecordFailure("meenah20@gmail.com", "192.0.2.10")
+	err := service.Check("meenah20@gmail.com", "198.51.100.7")
+	var locked *LoginLockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.True(t, locked.RetryAfter > 0 && locked.RetryAfter <= time.Second)
+	assert.Empty(t, auditRepo.MockEvents)
+
+	// RecordFailure does not enforce the wait itself; the login handler checks first.
+	for i := 3; i < DefaultAccountThrottle.LockoutAfter; i++ {
+		service.RecordFailure("meenah20@gmail.com", "192.0.2.10")
+	}
+	attempt, _ := attemptRepo.GetAttempt("account:meenah20@gmail.com")
+	assert.WithinDuration(t, time.Now().Add(15*time.Minute), *attempt.LockedUntil, time.Second)
+	assert.Len(t, auditRepo.MockEvents, 1)
+	assert.Equal(t, models.AuditAccountLocked, auditRepo.MockEvents[0].Action)
+	assert.Equal(t, patron.ID, *auditRepo.MockEvents[0].UserID)
+	assert.Equal(t, "192.0.2.10", auditRepo.MockEvents[0].IPAddress)
+
+	assert.NoError(t, service.Check("someone.else@gmail.com", "192.0.2.10"))
+}
+
+func TestLoginThrottleServices_LocksAddressAcrossAccounts(t *testing.T) {
+	service, _, auditRepo := setupLoginThrottle()
+
+	for i := 0; i < DefaultAddressThrottle.LockoutAfter; i++ {
+		service.RecordFailure("guess"+uuid.Must(uuid.NewV4()).String()+"@gmail.com", "203.0.113.9")
+	}
+
+	err := service.Check("meenah20@gmail.com", "203.0.113.9")
+	var locked *LoginLockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.NoError(t, service.Check("meenah20@gmail.com", "192.0.2.10"))
+	assert.Equal(t, models.AuditAddressLocked, auditRepo.MockEvents[len(auditRepo.MockEvents)-1].Action)
+}
+
+func TestLoginThrottleServices_SuccessClearsAccountFailures(t *testing.T) {
+	service, attemptRepo, _ := setupLoginThrottle()
+	service.RecordFailure("meenah20@gmail.com", "192.0.2.10")
+	service.RecordFailure("meenah20@gmail.com", "192.0.2.10")
+
+	service.RecordSuccess("meenah20@gmail.com")
+
+	attempt, _ := attemptRepo.GetAttempt("account:meenah20@gmail.com")
+	assert.Nil(t, attempt)
+	attempt, _ = attemptRepo.GetAttempt("ip:192.0.2.10")
+	assert.Equal(t, 2, attempt.Failures)
+}
+
+func TestLoginThrottleServices_FailuresExpireAfterWindow(t *testing.T) {
+	_, attemptRepo, _ := setupLoginThrottle()
+	start := time.Now().Add(-2 * time.Hour)
+
+	_, _ = attemptRepo.RecordFailure("account:meenah20@gmail.com", start, time.Hour)
+	_, _ = attemptRepo.RecordFailure("account:meenah20@gmail.com", start.Add(time.Minute), time.Hour)
+	attempt, _ := attemptRepo.RecordFailure("account:meenah20@gmail.com", time.Now(), time.Hour)
+
+	assert.Equal(t, 1, attempt.Failures)
+}
+
+func TestLoginThrottleServices_AdminUnlock(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), Email: "admin@library.org", Role: models.RoleAdmin}
+	service, _, auditRepo := setupLoginThrottle(patron, admin)
+	for i := 0; i < DefaultAccountThrottle.LockoutAfter; i++ {
+		service.RecordFailure("meenah20@gmail.com", "192.0.2.10")
+	}
+	assert.Error(t, service.Check("meenah20@gmail.com", "198.51.100.7"))
+
+	assert.NoError(t, service.Unlock(&admin, patron.ID))
+
+	assert.NoError(t, service.Check("meenah20@gmail.com", "198.51.100.7"))
+	last := auditRepo.MockEvents[len(auditRepo.MockEvents)-1]
+	assert.Equal(t, models.AuditAccountUnlocked, last.Action)
+	assert.Equal(t, admin.ID, *last.ActorID)
+	assert.Equal(t, patron.ID, *last.UserID)
+}
+
+func TestLoginThrottleServices_UnlockClearsDirectoryAndTwoFactorLockouts(t *testing.T) {
+	librarian := models.User{ID: uuid.Must(uuid.NewV4()), Email: "chidi@example.com", Role: models.RoleLibrarian}
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), Email: "admin@library.org", Role: models.RoleAdmin}
+	service, _, auditRepo := setupLoginThrottle(librarian, admin)
+	service.IdentityRepo.(*mock.MockUserIdentityRepository).MockIdentities = []models.UserIdentity{
+		{ID: uuid.Must(uuid.NewV4()), UserID: librarian.ID, Provider: auth.ProviderLDAP, Subject: "uuid-chidi", Username: "cokeke"},
+	}
+
+	accounts := []string{DirectoryThrottleAccount("COkeke"), TwoFactorThrottleAccount(librarian.ID)}
+	for _, account := range accounts {
+		for i := 0; i < DefaultAccountThrottle.LockoutAfter; i++ {
+			service.RecordFailure(account, "192.0.2.10")
+		}
+		assert.Error(t, service.Check(account, "198.51.100.7"))
+	}
+	if assert.Len(t, auditRepo.MockEvents, 2) {
+		for _, event := range auditRepo.MockEvents {
+			assert.Equal(t, librarian.ID, *event.UserID)
+		}
+	}
+
+	assert.NoError(t, service.Unlock(&admin, librarian.ID))
+
+	for _, account := range accounts {
+		assert.NoError(t, service.Check(account, "198.51.100.7"))
+	}
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"fmt"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/mailers"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	passwordResetLifetime    = time.Hour
+	passwordResetWindow      = time.Hour
+	maxPasswordResetRequests = 3
+)
+
+type PasswordResetServices struct {
+	UserRepo  repository.UserRepository
+	TokenRepo repository.UserTokenRepository
+	Mailer    mailers.Mailer
+	BaseURL   string
+}
+
+// RequestReset emails a reset link to a registered address. It reports success for
+// unknown addresses and silently drops requests over the per-email limit, so callers
+// learn nothing about which addresses are registered.
+func (s *PasswordResetServices) RequestReset(email string) error {
+	normalizedEmail := normalizeEmail(email)
+	if !isValidEmail(normalizedEmail) {
+		return apperrors.Validation("Invalid Email Address")
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(normalizedEmail)
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		log.Printf("Password reset requested for unknown email: %v", normalizedEmail)
+		return nil
+	}
+
+	recent, err := s.TokenRepo.CountTokensSince(user.ID, models.TokenPurposePasswordReset, time.Now().Add(-passwordResetWindow))
+	if err != nil {
+		return err
+	}
+	if recent >= maxPasswordResetRequests {
+		log.Printf("Password reset rate limit reached for email: %v", normalizedEmail)
+		return nil
+	}
+
+	raw, err := issueUserToken(s.TokenRepo, user, models.TokenPurposePasswordReset, passwordResetLifetime)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/reset-password?token=%s", strings.TrimRight(s.BaseURL, "/"), url.QueryEscape(raw))
+	return s.Mailer.Send(mailers.Message{
+		To:      user.Email,
+		Subject: "Reset your library account password",
+		Body: fmt.Sprintf("Hello %s,\n\nWe received a request to reset your password. Open the link below to choose a new one:\n\n%s\n\n"+
+			"The link expires in %d minutes and can only be used once. If you did not ask for a reset, you can ignore this email.\n",
+			user.Name, link, int(passwordResetLifetime.Minutes())),
+	})
+}
+
+// ResetPassword consumes a reset token, sets the new password and signs the user out
+// everywhere by revoking all sessions started before now.
+func (s *PasswordResetServices) ResetPassword(request Dto.PasswordResetRequest) error {
+	invalid := apperrors.Validation("invalid or expired reset link")
+
+	token, err := s.TokenRepo.GetTokenByHash(models.TokenPurposePasswordReset, hashSecret(request.Token))
+	if err != nil {
+		return invalid
+	}
+
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return invalid
+	}
+
+	user, err := s.UserRepo.GetUserByID(token.UserID)
+	if err != nil || user.Email != token.Email {
+		return invalid
+	}
+
+	if err := user.SetPassword(request.Password); err != nil {
+		return err
+	}
+
+	token.UsedAt = &now
+	token.UpdatedAt = now
+	if err := s.TokenRepo.UpdateToken(token); err != nil {
+		return fmt.Errorf("Failed to consume reset token: %w", err)
+	}
+	if err := s.TokenRepo.InvalidateTokens(user.ID, models.TokenPurposePasswordReset); err != nil {
+		return err
+	}
+
+	user.SessionsRevokedAt = &now
+	user.UpdatedAt = now
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return fmt.Errorf("Failed to reset password: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupPasswordResetService(users ...models.User) (*PasswordResetServices, *mock.MockUserRepo, *mock.MockMailer) {
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	mailer := &mock.MockMailer{}
+	service := &PasswordResetServices{
+		UserRepo:  userRepo,
+		TokenRepo: &mock.MockUserTokenRepository{},
+		Mailer:    mailer,
+		BaseURL:   "http://library.test",
+	}
+	return service, userRepo, mailer
+}
+
+func TestPasswordResetServices_ResetPassword(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	assert.NoError(t, user.SetPassword("old password"))
+	service, userRepo, mailer := setupPasswordResetService(user)
+
+	assert.NoError(t, service.RequestReset("Meenah20@gmail.com"))
+	assert.Len(t, mailer.SentMessages, 1)
+	raw := tokenFromLink(t, mailer.SentMessages[0].Body)
+
+	before := time.Now()
+	err := service.ResetPassword(Dto.PasswordResetRequest{Token: raw, Password: "new password"})
+
+	assert.NoError(t, err)
+	updated := userRepo.MockUser[0]
+	assert.True(t, updated.CheckPassword("new password"))
+	assert.False(t, updated.CheckPassword("old password"))
+	assert.False(t, updated.SessionValid(before))
+
+	err = service.ResetPassword(Dto.PasswordResetRequest{Token: raw, Password: "another password"})
+	assert.Error(t, err)
+	assert.Equal(t, "invalid or expired reset link", err.Error())
+}
+
+func TestPasswordResetServices_RequestResetIsRateLimited(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, _, mailer := setupPasswordResetService(user)
+
+	for i := 0; i < maxPasswordResetRequests+2; i++ {
+		assert.NoError(t, service.RequestReset("meenah20@gmail.com"))
+	}
+
+	assert.Len(t, mailer.SentMessages, maxPasswordResetRequests)
+}
+
+func TestPasswordResetServices_NewResetInvalidatesOlderLinks(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, _, mailer := setupPasswordResetService(user)
+
+	assert.NoError(t, service.RequestReset("meenah20@gmail.com"))
+	assert.NoError(t, service.RequestReset("meenah20@gmail.com"))
+	first := tokenFromLink(t, mailer.SentMessages[0].Body)
+	second := tokenFromLink(t, mailer.SentMessages[1].Body)
+
+	assert.NoError(t, service.ResetPassword(Dto.PasswordResetRequest{Token: second, Password: "new password"}))
+
+	err := service.ResetPassword(Dto.PasswordResetRequest{Token: first, Password: "other password"})
+	assert.Error(t, err)
+}
+
+func TestPasswordResetServices_RequestResetForUnknownEmail(t *testing.T) {
+	service, _, mailer := setupPasswordResetService()
+
+	err := service.RequestReset("nobody@example.com")
+
+	assert.NoError(t, err)
+	assert.Empty(t, mailer.SentMessages)
+}
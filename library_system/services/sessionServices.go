@@ -0,0 +1,169 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"time"
+)
+
+const (
+	// SessionLifetime is how long a browser sign-in lasts; the cookie uses the same age.
+	SessionLifetime           = 30 * 24 * time.Hour
+	sessionLastSeenResolution = time.Minute
+	maxSessionUserAgentLength = 255
+)
+
+type SessionServices struct {
+	SessionRepo repository.UserSessionRepository
+	UserRepo    repository.UserRepository
+}
+
+func (s *SessionServices) StartSession(userID uuid.UUID, userAgent, ipAddress string) (*models.UserSession, error) {
+	if len(userAgent) > maxSessionUserAgentLength {
+		userAgent = userAgent[:maxSessionUserAgentLength]
+	}
+
+	now := time.Now()
+	session := &models.UserSession{
+		ID:         uuid.Must(uuid.NewV4()),
+		UserID:     userID,
+		UserAgent:  userAgent,
+		IPAddress:  ipAddress,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(SessionLifetime),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := session.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.SessionRepo.AddSession(session); err != nil {
+		return nil, fmt.Errorf("Failed to start session: %w", err)
+	}
+	return session, nil
+}
+
+// Authenticate checks the session named in a cookie against its server-side record.
+// Unknown, expired, revoked and mismatched sessions all produce the same error.
+func (s *SessionServices) Authenticate(sessionID, userID uuid.UUID) (*models.User, *models.UserSession, error) {
+	invalid := apperrors.Unauthorized("invalid or expired session")
+
+	session, err := s.SessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return nil, nil, invalid
+	}
+
+	now := time.Now()
+	if session.UserID != userID || !session.IsActive(now) {
+		return nil, nil, invalid
+	}
+
+	user, err := s.UserRepo.GetUserByID(session.UserID)
+	if err != nil || !user.SessionValid(session.CreatedAt) {
+		return nil, nil, invalid
+	}
+
+	if now.Sub(session.LastSeenAt) > sessionLastSeenResolution {
+		session.LastSeenAt = now
+		session.UpdatedAt = now
+		_ = s.SessionRepo.UpdateSession(session)
+	}
+
+	return user, session, nil
+}
+
+// EndSession revokes the session on sign-out so a copied cookie stops working too.
+func (s *SessionServices) EndSession(sessionID uuid.UUID) error {
+	session, err := s.SessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return err
+	}
+	return s.revoke(session)
+}
+
+// ListSessions returns the user's active sessions, marking the one the request came from.
+func (s *SessionServices) ListSessions(userID, currentID uuid.UUID) ([]Dto.SessionResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	sessions, err := s.SessionRepo.GetSessionsByUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list sessions: %w", err)
+	}
+
+	now := time.Now()
+	responses := make([]Dto.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		if !session.IsActive(now) || !user.SessionValid(session.CreatedAt) {
+			continue
+		}
+		responses = append(responses, Dto.SessionResponse{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID == currentID,
+		})
+	}
+	return responses, nil
+}
+
+// RevokeSession signs one of the actor's sessions out. Admins may revoke anyone's.
+func (s *SessionServices) RevokeSession(actor *models.User, sessionID uuid.UUID) error {
+	session, err := s.SessionRepo.GetSessionByID(sessionID)
+	if err != nil {
+		return err
+	}
+	if session.UserID != actor.ID && !CanManageUsers(actor) {
+		return apperrors.NotFound("session not found")
+	}
+	return s.revoke(session)
+}
+
+// RevokeOtherSessions signs the user out everywhere except the current session.
+func (s *SessionServices) RevokeOtherSessions(userID, currentID uuid.UUID) error {
+	if err := s.SessionRepo.RevokeUserSessions(userID, currentID, time.Now()); err != nil {
+		return fmt.Errorf("Failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// ForceLogout signs a user out of every session, including ones started before
+// server-side sessions existed.
+func (s *SessionServices) ForceLogout(userID uuid.UUID) error {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := s.SessionRepo.RevokeUserSessions(user.ID, uuid.Nil, now); err != nil {
+		return fmt.Errorf("Failed to revoke sessions: %w", err)
+	}
+	user.SessionsRevokedAt = &now
+	user.UpdatedAt = now
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return fmt.Errorf("Failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+func (s *SessionServices) revoke(session *models.UserSession) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	session.RevokedAt = &now
+	session.UpdatedAt = now
+	if err := s.SessionRepo.UpdateSession(session); err != nil {
+		return fmt.Errorf("Failed to revoke session: %w", err)
+	}
+	return nil
+}
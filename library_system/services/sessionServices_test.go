@@ -0,0 +1,123 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupSessionService(users ...models.User) (*SessionServices, *mock.MockUserSessionRepository, *mock.MockUserRepo) {
+	sessionRepo := &mock.MockUserSessionRepository{}
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	return &SessionServices{SessionRepo: sessionRepo, UserRepo: userRepo}, sessionRepo, userRepo
+}
+
+func TestSessionServices_StartAndAuthenticate(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, sessionRepo, _ := setupSessionService(patron)
+
+	session, err := service.StartSession(patron.ID, "Firefox", "192.0.2.10")
+	assert.NoError(t, err)
+	assert.Len(t, sessionRepo.MockSessions, 1)
+	assert.WithinDuration(t, time.Now().Add(SessionLifetime), session.ExpiresAt, time.Minute)
+
+	user, current, err := service.Authenticate(session.ID, patron.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, patron.ID, user.ID)
+	assert.Equal(t, session.ID, current.ID)
+
+	_, _, err = service.Authenticate(session.ID, uuid.Must(uuid.NewV4()))
+	assert.Error(t, err)
+}
+
+func TestSessionServices_RevokedAndExpiredSessionsAreRejected(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, sessionRepo, _ := setupSessionService(patron)
+	past := time.Now().Add(-time.Hour)
+	sessionRepo.MockSessions = []models.UserSession{
+		{ID: uuid.Must(uuid.NewV4()), UserID: patron.ID, ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &past},
+		{ID: uuid.Must(uuid.NewV4()), UserID: patron.ID, ExpiresAt: past},
+	}
+
+	for _, session := range sessionRepo.MockSessions {
+		user, _, err := service.Authenticate(session.ID, patron.ID)
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		assert.Equal(t, "invalid or expired session", err.Error())
+	}
+}
+
+func TestSessionServices_ListAndRevokeOtherSessions(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, _, _ := setupSessionService(patron)
+	current, _ := service.StartSession(patron.ID, "Firefox", "192.0.2.10")
+	other, _ := service.StartSession(patron.ID, "Safari", "192.0.2.11")
+
+	sessions, err := service.ListSessions(patron.ID, current.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, session.ID == current.ID, session.Current)
+	}
+
+	assert.NoError(t, service.RevokeOtherSessions(patron.ID, current.ID))
+
+	sessions, err = service.ListSessions(patron.ID, current.ID)
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, current.ID, sessions[0].ID)
+	_, _, err = service.Authenticate(other.ID, patron.ID)
+	assert.Error(t, err)
+}
+
+func TestSessionServices_RevokeSessionOfAnotherUser(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	other := models.User{ID: uuid.Must(uuid.NewV4()), Email: "other@gmail.com", Role: models.RolePatron}
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), Email: "admin@library.org", Role: models.RoleAdmin}
+	service, _, _ := setupSessionService(patron, other, admin)
+	session, _ := service.StartSession(patron.ID, "Firefox", "192.0.2.10")
+
+	err := service.RevokeSession(&other, session.ID)
+	assert.Error(t, err)
+	assert.Equal(t, "session not found", err.Error())
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+
+	assert.NoError(t, service.RevokeSession(&admin, session.ID))
+	_, _, err = service.Authenticate(session.ID, patron.ID)
+	assert.Error(t, err)
+}
+
+func TestSessionServices_ForceLogout(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, sessionRepo, userRepo := setupSessionService(patron)
+	first, _ := service.StartSession(patron.ID, "Firefox", "192.0.2.10")
+	second, _ := service.StartSession(patron.ID, "Safari", "192.0.2.11")
+
+	assert.NoError(t, service.ForceLogout(patron.ID))
+
+	assert.NotNil(t, userRepo.MockUser[0].SessionsRevokedAt)
+	for _, session := range sessionRepo.MockSessions {
+		assert.NotNil(t, session.RevokedAt)
+	}
+	for _, session := range []uuid.UUID{first.ID, second.ID} {
+		_, _, err := service.Authenticate(session, patron.ID)
+		assert.Error(t, err)
+	}
+}
+
+func TestSessionServices_EndSession(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, _, _ := setupSessionService(patron)
+	session, _ := service.StartSession(patron.ID, "Firefox", "192.0.2.10")
+
+	assert.NoError(t, service.EndSession(session.ID))
+
+	_, _, err := service.Authenticate(session.ID, patron.ID)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/apperrors"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+)
+
+type SSOServices struct {
+	UserRepo     repository.UserRepository
+	IdentityRepo repository.UserIdentityRepository
+}
+
+// SignIn resolves an identity vouched for by an external provider to a local user.
+// A returning subject signs straight in; otherwise the identity is linked to the user
+// with the same email, or a new patron is created. Emails are only trusted when the
+// provider says they are verified, so nobody can claim another patron's account, and
+// only verified accounts are linked, since anyone can register an address they do not
+// own and keep its password.
+// Providers that manage roles, such as the staff directory, set the user's role on
+// every sign-in so that leaving a directory group takes effect at the next login.
+func (s *SSOServices) SignIn(identity *auth.ExternalIdentity) (*models.User, error) {
+	if identity.Subject == "" {
+		return nil, apperrors.Validation("identity provider did not return a subject")
+	}
+
+	now := time.Now()
+	link, err := s.IdentityRepo.GetIdentity(identity.Provider, identity.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to look up identity: %w", err)
+	}
+	if link != nil {
+		user, err := s.UserRepo.GetUserByID(link.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user.IsClosed() {
+			return nil, apperrors.Forbidden("Account is closed")
+		}
+		if applyProviderRole(user, identity, now) {
+			if err := s.UserRepo.UpdateUser(user); err != nil {
+				return nil, fmt.Errorf("Failed to update user: %w", err)
+			}
+		}
+		link.Username = normalizeUsername(identity.Username)
+		link.LastUsedAt = &now
+		link.UpdatedAt = now
+		if err := s.IdentityRepo.UpdateIdentity(link); err != nil {
+			log.Printf("Failed to record sign-in for identity %s: %v", link.ID, err)
+		}
+		return user, nil
+	}
+
+	email := normalizeEmail(identity.Email)
+	if !identity.EmailVerified || !isValidEmail(email) {
+		return nil, apperrors.Forbidden("Identity provider did not return a verified email address")
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		user, err = s.createUser(identity, email, now)
+		if err != nil {
+			return nil, err
+		}
+	} else if user.IsClosed() {
+		return nil, apperrors.Forbidden("Account is closed")
+	} else if !user.IsEmailVerified() {
+		return nil, apperrors.Forbidden("An account with this email address is awaiting verification; verify it before using single sign-on")
+	} else if applyProviderRole(user, identity, now) {
+		if err := s.UserRepo.UpdateUser(user); err != nil {
+			return nil, fmt.Errorf("Failed to update user: %w", err)
+		}
+	}
+
+	link = &models.UserIdentity{
+		ID:         uuid.Must(uuid.NewV4()),
+		UserID:     user.ID,
+		Provider:   identity.Provider,
+		Subject:    identity.Subject,
+		Email:      email,
+		Username:   normalizeUsername(identity.Username),
+		LastUsedAt: &now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := link.Validate(); err != nil {
+		return nil, err
+	}
+	if err := s.IdentityRepo.AddIdentity(link); err != nil {
+		return nil, fmt.Errorf("Failed to link identity: %w", err)
+	}
+
+	return user, nil
+}
+
+// createUser registers a patron for a first-time SSO sign-in. The account has no
+// password; the patron can set one later through the password reset flow.
+func (s *SSOServices) createUser(identity *auth.ExternalIdentity, email string, now time.Time) (*models.User, error) {
+	// Provider names can hold characters the registration rules do not allow.
+	name := normalizeName(strings.Join(strings.FieldsFunc(identity.Name, func(r rune) bool {
+		return !unicode.IsLetter(r) || r > unicode.MaxASCII
+	}), " "))
+	if !isNameValid(name) {
+		name = "library patron"
+	}
+
+	user := &models.User{
+		ID:              uuid.Must(uuid.NewV4()),
+		Name:            name,
+		Email:           email,
+		EmailVerifiedAt: &now,
+		Role:            models.RolePatron,
+		PatronType:      models.PatronTypeAdult,
+		CreatedAt:       now,
+		UpdatedAt:       now,
+	}
+	applyProviderRole(user, identity, now)
+	if err := assignCardNumber(s.UserRepo, user); err != nil {
+		return nil, err
+	}
+	if err := s.UserRepo.AddUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to create user: %w", err)
+	}
+	return user, nil
+}
+
+// applyProviderRole copies a provider-managed role onto the user and reports whether
+// it changed anything.
+func applyProviderRole(user *models.User, identity *auth.ExternalIdentity, now time.Time) bool {
+	if identity.Role == "" || identity.Role == user.Role || !models.IsValidRole(identity.Role) {
+		return false
+	}
+	log.Printf("Role for user %s changed from %s to %s by %s", user.ID, user.Role, identity.Role, identity.Provider)
+	user.Role = identity.Role
+	user.UpdatedAt = now
+	return true
+}
+
+// normalizeUsername matches the way sign-in names are keyed by the login throttle.
+func normalizeUsername(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupSSOService(users ...models.User) (*SSOServices, *mock.MockUserRepo, *mock.MockUserIdentityRepository) {
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	identityRepo := &mock.MockUserIdentityRepository{}
+	return &SSOServices{UserRepo: userRepo, IdentityRepo: identityRepo}, userRepo, identityRepo
+}
+
+func campusIdentity() *auth.ExternalIdentity {
+	return &auth.ExternalIdentity{
+		Provider:      auth.ProviderOIDC,
+		Subject:       "campus-123",
+		Email:         "Meenah20@University.edu",
+		EmailVerified: true,
+		Name:          "Aminat Usman-Bello",
+	}
+}
+
+func TestSSOServices_SignInCreatesPatron(t *testing.T) {
+	service, userRepo, identityRepo := setupSSOService()
+
+	user, err := service.SignIn(campusIdentity())
+
+	assert.NoError(t, err)
+	assert.Equal(t, "meenah20@university.edu", user.Email)
+	assert.Equal(t, "aminat usman bello", user.Name)
+	assert.Equal(t, models.RolePatron, user.Role)
+	assert.True(t, user.IsEmailVerified())
+	assert.True(t, models.IsValidCardNumber(user.CardNumber.String))
+	assert.Empty(t, user.PasswordHash)
+	assert.Len(t, userRepo.MockUser, 1)
+	assert.Len(t, identityRepo.MockIdentities, 1)
+	assert.Equal(t, user.ID, identityRepo.MockIdentities[0].UserID)
+}
+
+func TestSSOServices_SignInLinksExistingUserByEmail(t *testing.T) {
+	verifiedAt := time.Now()
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@university.edu", Role: models.RoleLibrarian, EmailVerifiedAt: &verifiedAt}
+	service, _, identityRepo := setupSSOService(existing)
+
+	user, err := service.SignIn(campusIdentity())
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, user.ID)
+	assert.Equal(t, models.RoleLibrarian, user.Role)
+	assert.Len(t, identityRepo.MockIdentities, 1)
+}
+
+func TestSSOServices_SignInRefusesUnverifiedAccount(t *testing.T) {
+	// Someone registered the address with a password of their own before its owner
+	// ever signed in through the provider.
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@university.edu", Role: models.RolePatron}
+	if err := existing.SetPassword("attacker knows this"); err != nil {
+		t.Fatal(err)
+	}
+	service, userRepo, identityRepo := setupSSOService(existing)
+
+	user, err := service.SignIn(campusIdentity())
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, "An account with this email address is awaiting verification; verify it before using single sign-on", err.Error())
+	assert.False(t, userRepo.MockUser[0].IsEmailVerified())
+	assert.Empty(t, identityRepo.MockIdentities)
+}
+
+func TestSSOServices_SignInBySubjectAfterEmailChange(t *testing.T) {
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, _, identityRepo := setupSSOService(existing)
+	identityRepo.MockIdentities = []models.UserIdentity{
+		{ID: uuid.Must(uuid.NewV4()), UserID: existing.ID, Provider: auth.ProviderOIDC, Subject: "campus-123"},
+	}
+
+	user, err := service.SignIn(campusIdentity())
+
+	assert.NoError(t, err)
+	assert.Equal(t, existing.ID, user.ID)
+	assert.NotNil(t, identityRepo.MockIdentities[0].LastUsedAt)
+}
+
+func TestSSOServices_SignInRejectsUnverifiedEmail(t *testing.T) {
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@university.edu"}
+	service, _, identityRepo := setupSSOService(existing)
+	identity := campusIdentity()
+	identity.EmailVerified = false
+
+	user, err := service.SignIn(identity)
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, "Identity provider did not return a verified email address", err.Error())
+	assert.Empty(t, identityRepo.MockIdentities)
+}
+
+func TestSSOServices_SignInRejectsClosedAccount(t *testing.T) {
+	closedAt := time.Now()
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@university.edu", ClosedAt: &closedAt}
+	service, _, _ := setupSSOService(existing)
+
+	user, err := service.SignIn(campusIdentity())
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, "Account is closed", err.Error())
+}
+
+func TestSSOServices_SignInAppliesDirectoryRole(t *testing.T) {
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "ada staff", Email: "ada@library.test", Role: models.RoleLibrarian}
+	service, userRepo, identityRepo := setupSSOService(existing)
+	identityRepo.MockIdentities = []models.UserIdentity{
+		{ID: uuid.Must(uuid.NewV4()), UserID: existing.ID, Provider: auth.ProviderLDAP, Subject: "uuid-ada"},
+	}
+
+	user, err := service.SignIn(&auth.ExternalIdentity{
+		Provider:      auth.ProviderLDAP,
+		Subject:       "uuid-ada",
+		Email:         "ada@library.test",
+		EmailVerified: true,
+		Username:      " Ada ",
+		Role:          models.RolePatron,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RolePatron, user.Role)
+	assert.Equal(t, models.RolePatron, userRepo.MockUser[0].Role)
+	assert.Equal(t, "ada", identityRepo.MockIdentities[0].Username)
+}
+
+func TestSSOServices_SignInKeepsDirectoryRoleOffUnverifiedAccount(t *testing.T) {
+	existing := models.User{ID: uuid.Must(uuid.NewV4()), Name: "ada staff", Email: "ada@library.test", Role: models.RolePatron}
+	service, userRepo, identityRepo := setupSSOService(existing)
+
+	user, err := service.SignIn(&auth.ExternalIdentity{
+		Provider:      auth.ProviderLDAP,
+		Subject:       "uuid-ada",
+		Email:         "ada@library.test",
+		EmailVerified: true,
+		Role:          models.RoleAdmin,
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, user)
+	assert.Equal(t, models.RolePatron, userRepo.MockUser[0].Role)
+	assert.Empty(t, identityRepo.MockIdentities)
+}
+
+func TestSSOServices_SignInCreatesStaffFromDirectory(t *testing.T) {
+	service, _, _ := setupSSOService()
+
+	user, err := service.SignIn(&auth.ExternalIdentity{
+		Provider:      auth.ProviderLDAP,
+		Subject:       "uuid-grace",
+		Email:         "grace@library.test",
+		EmailVerified: true,
+		Name:          "Grace Hopper",
+		Role:          models.RoleAdmin,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.RoleAdmin, user.Role)
+}
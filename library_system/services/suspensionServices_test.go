@@ -0,0 +1,159 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupBorrowingService(user models.User) (*UserServices, *mock.MockUserRepo, *mock.MockFineRepository, uuid.UUID) {
+	bookID := uuid.Must(uuid.NewV4())
+	userRepo := &mock.MockUserRepo{MockUser: []models.User{user}}
+	fineRepo := &mock.MockFineRepository{}
+	service := &UserServices{
+		UserRepo: userRepo,
+		LoanRepo: &mock.MockLoanRepository{},
+		BookRepo: &mock.MockBookRepository{
+			MockBooks: []models.Book{{ID: bookID, Title: "Test Book", Status: models.StatusAvailable}},
+		},
+		FineRepo:                fineRepo,
+		FineBlockThresholdCents: 1000,
+	}
+	return service, userRepo, fineRepo, bookID
+}
+
+func verifiedPatron() models.User {
+	verifiedAt := time.Now()
+	return models.User{
+		ID:              uuid.Must(uuid.NewV4()),
+		Name:            "aminat usman",
+		Email:           "meenah20@gmail.com",
+		Role:            models.RolePatron,
+		EmailVerifiedAt: &verifiedAt,
+	}
+}
+
+func TestUserServices_SuspendedPatronCannotBorrow(t *testing.T) {
+	patron := verifiedPatron()
+	librarian := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleLibrarian}
+	service, _, _, bookID := setupBorrowingService(patron)
+
+	user, err := service.SuspendUser(&librarian, patron.ID, Dto.SuspensionRequest{Reason: "damaged returned items"})
+	assert.NoError(t, err)
+	assert.True(t, user.Suspended)
+	assert.Equal(t, "damaged returned items", user.Suspension.Reason)
+
+	for _, action := range []func(Dto.BookActionRequest) (*Dto.BookActionResponse, error){service.CheckOutBook, service.ReserveBook} {
+		response, err := action(Dto.BookActionRequest{BookID: bookID, Email: patron.Email})
+		assert.Nil(t, response)
+		var blocked *BorrowingBlockedError
+		assert.True(t, errors.As(err, &blocked))
+		assert.Equal(t, BlockCodeSuspended, blocked.Code)
+	}
+
+	_, err = service.ReinstateUser(&librarian, patron.ID)
+	assert.NoError(t, err)
+
+	response, err := service.CheckOutBook(Dto.BookActionRequest{BookID: bookID, Email: patron.Email})
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+}
+
+func TestUserServices_ExpiredSuspensionAllowsBorrowing(t *testing.T) {
+	patron := verifiedPatron()
+	suspendedAt := time.Now().Add(-48 * time.Hour)
+	until := time.Now().Add(-time.Hour)
+	patron.SuspendedAt = &suspendedAt
+	patron.SuspendedUntil = &until
+	patron.SuspensionReason = "overdue items"
+	service, _, _, bookID := setupBorrowingService(patron)
+
+	response, err := service.CheckOutBook(Dto.BookActionRequest{BookID: bookID, Email: patron.Email})
+
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+}
+
+func TestUserServices_FinesOverThresholdBlockBorrowing(t *testing.T) {
+	patron := verifiedPatron()
+	service, _, fineRepo, bookID := setupBorrowingService(patron)
+	fineRepo.MockFines = []models.Fine{
+		{ID: uuid.Must(uuid.NewV4()), UserID: patron.ID, AmountCents: 600},
+		{ID: uuid.Must(uuid.NewV4()), UserID: patron.ID, AmountCents: 500},
+	}
+
+	response, err := service.CheckOutBook(Dto.BookActionRequest{BookID: bookID, Email: patron.Email})
+
+	assert.Nil(t, response)
+	var blocked *BorrowingBlockedError
+	assert.True(t, errors.As(err, &blocked))
+	assert.Equal(t, BlockCodeFinesOverLimit, blocked.Code)
+
+	paidAt := time.Now()
+	fineRepo.MockFines[1].PaidAt = &paidAt
+	response, err = service.CheckOutBook(Dto.BookActionRequest{BookID: bookID, Email: patron.Email})
+	assert.NoError(t, err)
+	assert.NotNil(t, response)
+}
+
+func TestUserServices_SuspendUser(t *testing.T) {
+	librarian := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleLibrarian}
+	admin := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleAdmin}
+
+	t.Run("reason is required", func(t *testing.T) {
+		patron := verifiedPatron()
+		service, _, _, _ := setupBorrowingService(patron)
+		_, err := service.SuspendUser(&librarian, patron.ID, Dto.SuspensionRequest{Reason: "  "})
+		assert.Error(t, err)
+		assert.Equal(t, "validation error: reason is required", err.Error())
+	})
+
+	t.Run("end date must be in the future", func(t *testing.T) {
+		patron := verifiedPatron()
+		service, _, _, _ := setupBorrowingService(patron)
+		past := time.Now().Add(-time.Hour)
+		_, err := service.SuspendUser(&librarian, patron.ID, Dto.SuspensionRequest{Reason: "overdue", Until: &past})
+		assert.Error(t, err)
+	})
+
+	t.Run("librarian cannot suspend staff", func(t *testing.T) {
+		staff := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleLibrarian}
+		service, _, _, _ := setupBorrowingService(staff)
+		_, err := service.SuspendUser(&librarian, staff.ID, Dto.SuspensionRequest{Reason: "overdue"})
+		assert.Error(t, err)
+		assert.Equal(t, "Only an admin can suspend staff accounts", err.Error())
+
+		_, err = service.SuspendUser(&admin, staff.ID, Dto.SuspensionRequest{Reason: "overdue"})
+		assert.NoError(t, err)
+	})
+
+	t.Run("reinstating an active account", func(t *testing.T) {
+		patron := verifiedPatron()
+		service, _, _, _ := setupBorrowingService(patron)
+		_, err := service.ReinstateUser(&librarian, patron.ID)
+		assert.Error(t, err)
+		assert.Equal(t, "User is not suspended", err.Error())
+	})
+
+	t.Run("librarian cannot reinstate staff", func(t *testing.T) {
+		staff := models.User{ID: uuid.Must(uuid.NewV4()), Role: models.RoleLibrarian}
+		service, _, _, _ := setupBorrowingService(staff)
+		_, err := service.SuspendUser(&admin, staff.ID, Dto.SuspensionRequest{Reason: "overdue"})
+		assert.NoError(t, err)
+
+		_, err = service.ReinstateUser(&librarian, staff.ID)
+		assert.Equal(t, "Only an admin can reinstate staff accounts", err.Error())
+
+		_, err = service.ReinstateUser(&staff, staff.ID)
+		assert.Equal(t, "You cannot reinstate your own account", err.Error())
+
+		_, err = service.ReinstateUser(&admin, staff.ID)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,96 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupTokenService(users ...models.User) (*TokenServices, *mock.MockAPITokenRepository) {
+	tokenRepo := &mock.MockAPITokenRepository{}
+	service := &TokenServices{
+		TokenRepo: tokenRepo,
+		UserRepo:  &mock.MockUserRepo{MockUser: users},
+	}
+	return service, tokenRepo
+}
+
+func TestTokenServices_CreateAndAuthenticate(t *testing.T) {
+	librarian := models.User{ID: uuid.Must(uuid.NewV4()), Email: "desk@library.org", Role: models.RoleLibrarian}
+	service, tokenRepo := setupTokenService(librarian)
+
+	created, err := service.CreateToken(&librarian, Dto.APITokenRequest{
+		Name:   "kiosk",
+		Scopes: []string{models.ScopeLoansWrite},
+	})
+
+	assert.NoError(t, err)
+	assert.True(t, len(created.Token) > len(apiTokenPrefix))
+	assert.Equal(t, []string{models.ScopeLoansWrite}, created.Scopes)
+	assert.NotEqual(t, created.Token, tokenRepo.MockTokens[0].TokenHash)
+
+	user, token, err := service.Authenticate(created.Token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, librarian.ID, user.ID)
+	assert.True(t, token.HasScope(models.ScopeLoansWrite))
+	assert.False(t, token.HasScope(models.ScopeBooksWrite))
+}
+
+func TestTokenServices_CreateTokenRejectsScopeAboveRole(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, tokenRepo := setupTokenService(patron)
+
+	created, err := service.CreateToken(&patron, Dto.APITokenRequest{
+		Name:   "script",
+		Scopes: []string{models.ScopeBooksWrite},
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, created)
+	assert.Equal(t, "Scope books:write is not allowed for role patron", err.Error())
+	assert.Empty(t, tokenRepo.MockTokens)
+}
+
+func TestTokenServices_RevokedAndExpiredTokensAreRejected(t *testing.T) {
+	patron := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	service, tokenRepo := setupTokenService(patron)
+
+	revoked, err := service.CreateToken(&patron, Dto.APITokenRequest{Name: "old", Scopes: []string{models.ScopeLoansWrite}})
+	assert.NoError(t, err)
+	assert.NoError(t, service.RevokeToken(&patron, revoked.ID))
+
+	_, _, err = service.Authenticate(revoked.Token)
+	assert.Error(t, err)
+
+	expired, err := service.CreateToken(&patron, Dto.APITokenRequest{Name: "short", Scopes: []string{models.ScopeLoansWrite}})
+	assert.NoError(t, err)
+	past := time.Now().Add(-time.Hour)
+	tokenRepo.MockTokens[1].ExpiresAt = &past
+
+	_, _, err = service.Authenticate(expired.Token)
+	assert.Error(t, err)
+	assert.Equal(t, "invalid or expired token", err.Error())
+}
+
+func TestTokenServices_RevokeTokenOfAnotherUser(t *testing.T) {
+	owner := models.User{ID: uuid.Must(uuid.NewV4()), Email: "meenah20@gmail.com", Role: models.RolePatron}
+	other := models.User{ID: uuid.Must(uuid.NewV4()), Email: "other@gmail.com", Role: models.RolePatron}
+	service, _ := setupTokenService(owner, other)
+
+	created, err := service.CreateToken(&owner, Dto.APITokenRequest{Name: "mine", Scopes: []string{models.ScopeLoansWrite}})
+	assert.NoError(t, err)
+
+	err = service.RevokeToken(&other, created.ID)
+
+	assert.Error(t, err)
+	assert.Equal(t, "token not found", err.Error())
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+}
@@ -0,0 +1,208 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"strings"
+	"time"
+)
+
+const (
+	recoveryCodeCount = 10
+	// Recovery codes stay valid until used or replaced; the expiry only satisfies the
+	// token table.
+	recoveryCodeLifetime = 10 * 365 * 24 * time.Hour
+)
+
+// ErrInvalidTwoFactorCode is returned for a wrong, reused or expired code so sign-in can
+// count it as a failed attempt.
+var ErrInvalidTwoFactorCode = apperrors.Validation("Invalid two-factor code")
+
+type TwoFactorServices struct {
+	UserRepo  repository.UserRepository
+	TokenRepo repository.UserTokenRepository
+	// Issuer names the account in authenticator apps.
+	Issuer string
+}
+
+// BeginEnrolment stores a new secret for the user. Two-factor stays off until the user
+// proves their app works with ConfirmEnrolment, so an abandoned enrolment is harmless.
+func (s *TwoFactorServices) BeginEnrolment(userID uuid.UUID) (*Dto.TwoFactorEnrolmentResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperrors.Validation("Two-factor authentication is already enabled")
+	}
+
+	secret, err := auth.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("Failed to generate two-factor secret: %w", err)
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastCounter = 0
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to start two-factor enrolment: %w", err)
+	}
+
+	return &Dto.TwoFactorEnrolmentResponse{
+		Secret:          secret,
+		ProvisioningURI: auth.TOTPProvisioningURI(s.Issuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrolment turns two-factor on once the user enters a code from their app, and
+// issues the recovery codes.
+func (s *TwoFactorServices) ConfirmEnrolment(userID uuid.UUID, code string) (*Dto.RecoveryCodesResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TwoFactorEnabled() {
+		return nil, apperrors.Validation("Two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == "" {
+		return nil, apperrors.Validation("Two-factor enrolment has not been started")
+	}
+
+	now := time.Now()
+	counter, ok := auth.ValidateTOTP(user.TOTPSecret, code, now)
+	if !ok {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	user.TOTPEnabledAt = &now
+	user.TOTPLastCounter = counter
+	user.UpdatedAt = now
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to enable two-factor authentication: %w", err)
+	}
+
+	return s.issueRecoveryCodes(user.ID)
+}
+
+// Verify checks a second factor for the user: an authenticator code that has not been
+// used before, or an unused recovery code, which is then spent.
+func (s *TwoFactorServices) Verify(userID uuid.UUID, code string) (*Dto.UserResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TwoFactorEnabled() {
+		return nil, apperrors.Validation("Two-factor authentication is not enabled")
+	}
+
+	now := time.Now()
+	if counter, ok := auth.ValidateTOTP(user.TOTPSecret, code, now); ok {
+		// A code seen once could have been shoulder-surfed or phished; refuse replays.
+		if counter <= user.TOTPLastCounter {
+			return nil, ErrInvalidTwoFactorCode
+		}
+		user.TOTPLastCounter = counter
+		user.UpdatedAt = now
+		if err := s.UserRepo.UpdateUser(user); err != nil {
+			return nil, fmt.Errorf("Failed to record two-factor code: %w", err)
+		}
+		return mapUserToResponse(user), nil
+	}
+
+	token, err := s.TokenRepo.GetTokenByHash(models.TokenPurposeRecoveryCode, hashSecret(normalizeRecoveryCode(code)))
+	if err != nil || token.UserID != user.ID || !token.IsUsable(now) {
+		return nil, ErrInvalidTwoFactorCode
+	}
+	token.UsedAt = &now
+	token.UpdatedAt = now
+	if err := s.TokenRepo.UpdateToken(token); err != nil {
+		return nil, fmt.Errorf("Failed to use recovery code: %w", err)
+	}
+	return mapUserToResponse(user), nil
+}
+
+// Disable turns two-factor off after checking a current code. Staff roles cannot turn
+// it off because the policy requires it for them.
+func (s *TwoFactorServices) Disable(userID uuid.UUID, code string) error {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.RequiresTwoFactor() {
+		return apperrors.Forbidden("Two-factor authentication is required for your role")
+	}
+	if _, err := s.Verify(userID, code); err != nil {
+		return err
+	}
+
+	user, err = s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	user.TOTPSecret = ""
+	user.TOTPEnabledAt = nil
+	user.TOTPLastCounter = 0
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return fmt.Errorf("Failed to disable two-factor authentication: %w", err)
+	}
+	return s.TokenRepo.InvalidateTokens(user.ID, models.TokenPurposeRecoveryCode)
+}
+
+// RegenerateRecoveryCodes replaces all of the user's recovery codes after checking a
+// current code.
+func (s *TwoFactorServices) RegenerateRecoveryCodes(userID uuid.UUID, code string) (*Dto.RecoveryCodesResponse, error) {
+	if _, err := s.Verify(userID, code); err != nil {
+		return nil, err
+	}
+	return s.issueRecoveryCodes(userID)
+}
+
+func (s *TwoFactorServices) issueRecoveryCodes(userID uuid.UUID) (*Dto.RecoveryCodesResponse, error) {
+	if err := s.TokenRepo.InvalidateTokens(userID, models.TokenPurposeRecoveryCode); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	codes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("Failed to generate recovery codes: %w", err)
+		}
+		token := &models.UserToken{
+			ID:        uuid.Must(uuid.NewV4()),
+			UserID:    userID,
+			Purpose:   models.TokenPurposeRecoveryCode,
+			TokenHash: hashSecret(normalizeRecoveryCode(code)),
+			ExpiresAt: now.Add(recoveryCodeLifetime),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := s.TokenRepo.AddToken(token); err != nil {
+			return nil, fmt.Errorf("Failed to save recovery codes: %w", err)
+		}
+		codes = append(codes, code)
+	}
+	return &Dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// generateRecoveryCode returns 80 random bits as four groups of base32, e.g.
+// "K7QD-2M4X-PJ6R-VN3A", which is easy to copy by hand.
+func generateRecoveryCode() (string, error) {
+	buf := make([]byte, 10)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	raw := base32.StdEncoding.EncodeToString(buf)
+	return raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/auth"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupTwoFactorService(users ...models.User) (*TwoFactorServices, *mock.MockUserRepo, *mock.MockUserTokenRepository) {
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	tokenRepo := &mock.MockUserTokenRepository{}
+	return &TwoFactorServices{UserRepo: userRepo, TokenRepo: tokenRepo, Issuer: "City Library"}, userRepo, tokenRepo
+}
+
+func currentTOTPCode(t *testing.T, secret string, offset int64) string {
+	code, err := auth.TOTPCode(secret, auth.TOTPCounter(time.Now())+offset)
+	assert.NoError(t, err)
+	return code
+}
+
+// enrolledUser returns a librarian with two-factor already enabled. The last used
+// step is in the past so any current code is accepted once.
+func enrolledUser(t *testing.T) models.User {
+	secret, err := auth.GenerateTOTPSecret()
+	assert.NoError(t, err)
+	enabledAt := time.Now().Add(-time.Hour)
+	return models.User{
+		ID:              uuid.Must(uuid.NewV4()),
+		Email:           "desk@library.org",
+		Role:            models.RoleLibrarian,
+		TOTPSecret:      secret,
+		TOTPEnabledAt:   &enabledAt,
+		TOTPLastCounter: auth.TOTPCounter(enabledAt),
+	}
+}
+
+func TestTwoFactorServices_Enrolment(t *testing.T) {
+	librarian := models.User{ID: uuid.Must(uuid.NewV4()), Email: "desk@library.org", Role: models.RoleLibrarian}
+	service, userRepo, tokenRepo := setupTwoFactorService(librarian)
+
+	enrolment, err := service.BeginEnrolment(librarian.ID)
+	assert.NoError(t, err)
+	assert.Contains(t, enrolment.ProvisioningURI, "otpauth://totp/")
+	assert.Contains(t, enrolment.ProvisioningURI, "secret="+enrolment.Secret)
+	assert.False(t, userRepo.MockUser[0].TwoFactorEnabled())
+
+	_, err = service.ConfirmEnrolment(librarian.ID, "000000")
+	assert.Error(t, err)
+	assert.Equal(t, "Invalid two-factor code", err.Error())
+
+	codes, err := service.ConfirmEnrolment(librarian.ID, currentTOTPCode(t, enrolment.Secret, 0))
+	assert.NoError(t, err)
+	assert.Len(t, codes.RecoveryCodes, recoveryCodeCount)
+	assert.Len(t, tokenRepo.MockTokens, recoveryCodeCount)
+	assert.NotEqual(t, codes.RecoveryCodes[0], tokenRepo.MockTokens[0].TokenHash)
+	assert.True(t, userRepo.MockUser[0].TwoFactorEnabled())
+
+	_, err = service.BeginEnrolment(librarian.ID)
+	assert.Error(t, err)
+}
+
+func TestTwoFactorServices_VerifyRejectsReplayedCode(t *testing.T) {
+	librarian := enrolledUser(t)
+	service, _, _ := setupTwoFactorService(librarian)
+	code := currentTOTPCode(t, librarian.TOTPSecret, 0)
+
+	user, err := service.Verify(librarian.ID, code)
+	assert.NoError(t, err)
+	assert.True(t, user.TwoFactor)
+
+	_, err = service.Verify(librarian.ID, code)
+	assert.Error(t, err)
+	assert.Equal(t, "Invalid two-factor code", err.Error())
+}
+
+func TestTwoFactorServices_RecoveryCodesWorkOnce(t *testing.T) {
+	librarian := enrolledUser(t)
+	service, _, _ := setupTwoFactorService(librarian)
+	codes, err := service.RegenerateRecoveryCodes(librarian.ID, currentTOTPCode(t, librarian.TOTPSecret, 0))
+	assert.NoError(t, err)
+
+	_, err = service.Verify(librarian.ID, " "+codes.RecoveryCodes[3]+" ")
+	assert.NoError(t, err)
+	_, err = service.Verify(librarian.ID, codes.RecoveryCodes[3])
+	assert.Error(t, err)
+
+	// Regenerating replaces the old set.
+	fresh, err := service.RegenerateRecoveryCodes(librarian.ID, codes.RecoveryCodes[4])
+	assert.NoError(t, err)
+	_, err = service.Verify(librarian.ID, codes.RecoveryCodes[5])
+	assert.Error(t, err)
+	_, err = service.Verify(librarian.ID, fresh.RecoveryCodes[0])
+	assert.NoError(t, err)
+}
+
+func TestTwoFactorServices_RecoveryCodeOfAnotherUserIsRejected(t *testing.T) {
+	librarian := enrolledUser(t)
+	other := enrolledUser(t)
+	other.ID = uuid.Must(uuid.NewV4())
+	other.Email = "admin@library.org"
+	service, _, _ := setupTwoFactorService(librarian, other)
+	codes, err := service.RegenerateRecoveryCodes(other.ID, currentTOTPCode(t, other.TOTPSecret, 0))
+	assert.NoError(t, err)
+
+	_, err = service.Verify(librarian.ID, codes.RecoveryCodes[0])
+	assert.Error(t, err)
+}
+
+func TestTwoFactorServices_DisableRespectsRolePolicy(t *testing.T) {
+	librarian := enrolledUser(t)
+	patron := enrolledUser(t)
+	patron.ID = uuid.Must(uuid.NewV4())
+	patron.Email = "meenah20@gmail.com"
+	patron.Role = models.RolePatron
+	service, userRepo, _ := setupTwoFactorService(librarian, patron)
+
+	err := service.Disable(librarian.ID, currentTOTPCode(t, librarian.TOTPSecret, 0))
+	assert.Error(t, err)
+	assert.Equal(t, "Two-factor authentication is required for your role", err.Error())
+
+	assert.Error(t, service.Disable(patron.ID, "000000"))
+	assert.NoError(t, service.Disable(patron.ID, currentTOTPCode(t, patron.TOTPSecret, 0)))
+	assert.False(t, userRepo.MockUser[1].TwoFactorEnabled())
+	assert.Empty(t, userRepo.MockUser[1].TOTPSecret)
+}
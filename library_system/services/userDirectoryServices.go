@@ -0,0 +1,131 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"strings"
+	"time"
+)
+
+const (
+	defaultUsersPerPage = 25
+	maxUsersPerPage     = 100
+)
+
+// ListUsers backs the librarian user directory. The query matches name or email, and
+// also an exact card number when it looks like one.
+func (s *UserServices) ListUsers(request Dto.UserSearchRequest) (*Dto.UserListResponse, error) {
+	filter := repository.UserFilter{
+		Query:      strings.TrimSpace(request.Query),
+		Status:     strings.ToLower(strings.TrimSpace(request.Status)),
+		PatronType: strings.ToLower(strings.TrimSpace(request.PatronType)),
+		Page:       request.Page,
+		PerPage:    request.PerPage,
+	}
+	if filter.Status != "" && !models.IsValidUserStatus(filter.Status) {
+		return nil, apperrors.Validation("validation error: unknown status")
+	}
+	if filter.PatronType != "" && !models.IsValidPatronType(filter.PatronType) {
+		return nil, apperrors.Validation("validation error: unknown patron type")
+	}
+	if card := models.NormalizeCardNumber(filter.Query); models.IsValidCardNumber(card) {
+		filter.CardNumber = card
+	}
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultUsersPerPage
+	}
+	if filter.PerPage > maxUsersPerPage {
+		filter.PerPage = maxUsersPerPage
+	}
+
+	users, total, err := s.UserRepo.ListUsers(filter)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to list users: %w", err)
+	}
+
+	response := &Dto.UserListResponse{
+		Users:      make([]Dto.UserResponse, 0, len(users)),
+		Page:       filter.Page,
+		PerPage:    filter.PerPage,
+		Total:      total,
+		TotalPages: (total + filter.PerPage - 1) / filter.PerPage,
+	}
+	for i := range users {
+		response.Users = append(response.Users, *mapUserToResponse(&users[i]))
+	}
+	return response, nil
+}
+
+// GetUserDetail shows a patron with the books they have out, their open holds and what
+// they owe, for the circulation desk.
+func (s *UserServices) GetUserDetail(userID uuid.UUID) (*Dto.UserDetailResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	loans, err := s.LoanRepo.GetLoansByUser(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load loans: %w", err)
+	}
+	var current []models.Loan
+	for _, loan := range loans {
+		if loan.ReturnDate == nil {
+			current = append(current, loan)
+		}
+	}
+
+	balance, err := s.FineRepo.GetOutstandingBalance(user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to check fines: %w", err)
+	}
+
+	detail := &Dto.UserDetailResponse{
+		User:                  mapUserToResponse(user),
+		OutstandingFinesCents: balance,
+	}
+	detail.Loans, detail.Holds = summariseLoans(s.BookRepo, current)
+	return detail, nil
+}
+
+func (s *UserServices) ChangePatronType(userID uuid.UUID, request Dto.PatronTypeRequest) (*Dto.UserResponse, error) {
+	patronType := strings.ToLower(strings.TrimSpace(request.PatronType))
+	if !models.IsValidPatronType(patronType) {
+		return nil, apperrors.Validation("Invalid Patron Type")
+	}
+
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.PatronType = patronType
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to update patron type: %w", err)
+	}
+
+	return mapUserToResponse(user), nil
+}
+
+// GetUsersByIDs looks up several users at once, for callers that batch lookups. Unknown
+// IDs are left out rather than reported as errors.
+func (s *UserServices) GetUsersByIDs(userIDs []uuid.UUID) ([]Dto.UserResponse, error) {
+	users, err := s.UserRepo.GetUsersByIDs(userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to load users: %w", err)
+	}
+
+	responses := make([]Dto.UserResponse, 0, len(users))
+	for i := range users {
+		responses = append(responses, *mapUserToResponse(&users[i]))
+	}
+	return responses, nil
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gobuffalo/nulls"
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func directoryUsers() []models.User {
+	verifiedAt := time.Now()
+	suspendedAt := time.Now()
+	closedAt := time.Now()
+	return []models.User{
+		{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com", CardNumber: nulls.NewString("29000000000015"), PatronType: models.PatronTypeAdult, EmailVerifiedAt: &verifiedAt},
+		{ID: uuid.Must(uuid.NewV4()), Name: "bola ade", Email: "bola@example.com", PatronType: models.PatronTypeStudent, EmailVerifiedAt: &verifiedAt, SuspendedAt: &suspendedAt, SuspensionReason: "overdue"},
+		{ID: uuid.Must(uuid.NewV4()), Name: "chidi okeke", Email: "chidi@example.com", PatronType: models.PatronTypeChild},
+		{ID: uuid.Must(uuid.NewV4()), Name: "closed account", Email: "closed@invalid.local", PatronType: models.PatronTypeAdult, ClosedAt: &closedAt},
+	}
+}
+
+func TestUserServices_ListUsers(t *testing.T) {
+	service := UserServices{UserRepo: &mock.MockUserRepo{MockUser: directoryUsers()}}
+
+	t.Run("search by name", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{Query: "Bola"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, response.Total)
+		assert.Equal(t, "bola@example.com", response.Users[0].Email)
+		assert.Equal(t, models.UserStatusSuspended, response.Users[0].Status)
+	})
+
+	t.Run("search by card number", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{Query: "2900 0000 0000 15"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, response.Total)
+		assert.Equal(t, "meenah20@gmail.com", response.Users[0].Email)
+	})
+
+	t.Run("filter by status and patron type", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{Status: "unverified"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, response.Total)
+		assert.Equal(t, "chidi@example.com", response.Users[0].Email)
+
+		response, err = service.ListUsers(Dto.UserSearchRequest{PatronType: "adult", Status: "active"})
+		assert.NoError(t, err)
+		assert.Equal(t, 1, response.Total)
+		assert.Equal(t, "meenah20@gmail.com", response.Users[0].Email)
+	})
+
+	t.Run("pagination", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{Page: 2, PerPage: 3})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, response.Total)
+		assert.Equal(t, 2, response.TotalPages)
+		assert.Len(t, response.Users, 1)
+		assert.Equal(t, "closed account", response.Users[0].Name)
+	})
+
+	t.Run("per page is capped", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{PerPage: 1000})
+		assert.NoError(t, err)
+		assert.Equal(t, maxUsersPerPage, response.PerPage)
+		assert.Equal(t, 1, response.Page)
+	})
+
+	t.Run("unknown status", func(t *testing.T) {
+		response, err := service.ListUsers(Dto.UserSearchRequest{Status: "sleeping"})
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "validation error: unknown status", err.Error())
+	})
+}
+
+func TestUserServices_GetUserDetail(t *testing.T) {
+	users := directoryUsers()
+	patron := users[0]
+	book := models.Book{ID: uuid.Must(uuid.NewV4()), Title: "Things Fall Apart", ISBN: "9780385474542"}
+	returnedAt := time.Now()
+	loanRepo := &mock.MockLoanRepository{MockLoans: []models.Loan{
+		{ID: uuid.Must(uuid.NewV4()), BookID: book.ID, UserID: patron.ID, Email: patron.Email, Kind: models.LoanKindLoan, LoanDate: time.Now()},
+		{ID: uuid.Must(uuid.NewV4()), BookID: book.ID, UserID: patron.ID, Email: patron.Email, Kind: models.LoanKindLoan, LoanDate: time.Now(), ReturnDate: &returnedAt},
+		{ID: uuid.Must(uuid.NewV4()), BookID: uuid.Must(uuid.NewV4()), UserID: patron.ID, Email: patron.Email, Kind: models.LoanKindHold, LoanDate: time.Now()},
+	}}
+	service := UserServices{
+		UserRepo: &mock.MockUserRepo{MockUser: users},
+		LoanRepo: loanRepo,
+		BookRepo: &mock.MockBookRepository{MockBooks: []models.Book{book}},
+		FineRepo: &mock.MockFineRepository{MockFines: []models.Fine{
+			{ID: uuid.Must(uuid.NewV4()), UserID: patron.ID, AmountCents: 150},
+		}},
+	}
+
+	detail, err := service.GetUserDetail(patron.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, patron.Email, detail.User.Email)
+	assert.Len(t, detail.Loans, 1)
+	assert.Equal(t, "Things Fall Apart", detail.Loans[0].BookTitle)
+	assert.Len(t, detail.Holds, 1)
+	assert.Equal(t, 150, detail.OutstandingFinesCents)
+
+	_, err = service.GetUserDetail(uuid.Must(uuid.NewV4()))
+	assert.Error(t, err)
+	assert.Equal(t, "user not found", err.Error())
+	assert.True(t, errors.Is(err, apperrors.ErrNotFound))
+}
+
+func TestUserServices_ChangePatronType(t *testing.T) {
+	users := directoryUsers()
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	service := UserServices{UserRepo: userRepo}
+
+	user, err := service.ChangePatronType(users[0].ID, Dto.PatronTypeRequest{PatronType: " Student "})
+	assert.NoError(t, err)
+	assert.Equal(t, models.PatronTypeStudent, user.PatronType)
+	assert.Equal(t, models.PatronTypeStudent, userRepo.MockUser[0].PatronType)
+
+	_, err = service.ChangePatronType(users[0].ID, Dto.PatronTypeRequest{PatronType: "pirate"})
+	assert.Error(t, err)
+	assert.Equal(t, "Invalid Patron Type", err.Error())
+}
+
+func TestUserServices_GetUsersByIDs(t *testing.T) {
+	users := directoryUsers()
+	service := UserServices{UserRepo: &mock.MockUserRepo{MockUser: users}}
+
+	found, err := service.GetUsersByIDs([]uuid.UUID{users[1].ID, uuid.Must(uuid.NewV4())})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, users[1].ID, found[0].ID)
+		assert.True(t, found[0].Suspended)
+	}
+
+	service.UserRepo = &mock.MockUserRepo{GetUsersByIDsError: errors.New("db down")}
+	_, err = service.GetUsersByIDs([]uuid.UUID{users[0].ID})
+	assert.Error(t, err)
+}
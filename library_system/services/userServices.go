@@ -3,8 +3,10 @@ package services
 import (
 	"errors"
 	"fmt"
+	"github.com/gobuffalo/nulls"
 	"github.com/gofrs/uuid"
 	"library-system/Dto"
+	"library-system/apperrors"
 	"library-system/models"
 	"library-system/repositories/repository"
 	"log"
@@ -17,17 +19,23 @@ type UserServices struct {
 	UserRepo repository.UserRepository
 	LoanRepo repository.LoanRepository
 	BookRepo repository.BookRepository
+	FineRepo repository.FineRepository
+	// IdentityRepo holds the directory and SSO links that CloseAccount removes.
+	IdentityRepo repository.UserIdentityRepository
+	// FineBlockThresholdCents blocks borrowing once a patron owes more than this.
+	// Zero turns automatic blocks off.
+	FineBlockThresholdCents int
 }
 
 func (s *UserServices) RegisterUser(request Dto.UserRequest) (*Dto.UserResponse, error) {
 	normalizedName := normalizeName(request.Name)
 	if !isNameValid(normalizedName) {
-		return nil, errors.New("Invalid Name")
+		return nil, apperrors.Validation("Invalid Name")
 	}
 
 	normalizedEmail := normalizeEmail(request.Email)
 	if !isValidEmail(normalizedEmail) {
-		return nil, errors.New("Invalid Email Address")
+		return nil, apperrors.Validation("Invalid Email Address")
 	}
 
 	existingUser, err := s.UserRepo.GetUserByEmail(normalizedEmail)
@@ -35,12 +43,20 @@ func (s *UserServices) RegisterUser(request Dto.UserRequest) (*Dto.UserResponse,
 		return nil, err
 	}
 	if existingUser != nil {
-		return nil, errors.New("email already registered")
+		return nil, apperrors.Conflict("email already registered")
 	}
 
 	user := &models.User{
-		Name:  normalizedName,
-		Email: normalizedEmail,
+		Name:       normalizedName,
+		Email:      normalizedEmail,
+		Role:       models.RolePatron,
+		PatronType: models.PatronTypeAdult,
+	}
+	if err := user.SetPassword(request.Password); err != nil {
+		return nil, err
+	}
+	if err := assignCardNumber(s.UserRepo, user); err != nil {
+		return nil, err
 	}
 
 	if err := s.UserRepo.AddUser(user); err != nil {
@@ -48,11 +64,180 @@ func (s *UserServices) RegisterUser(request Dto.UserRequest) (*Dto.UserResponse,
 		return nil, err
 	}
 
-	return &Dto.UserResponse{
-		ID:    user.ID,
-		Name:  user.Name,
-		Email: user.Email,
-	}, nil
+	return mapUserToResponse(user), nil
+}
+
+func (s *UserServices) Login(request Dto.LoginRequest) (*Dto.UserResponse, error) {
+	normalizedEmail := normalizeEmail(request.Email)
+	if !isValidEmail(normalizedEmail) || request.Password == "" {
+		return nil, apperrors.Unauthorized("invalid email or password")
+	}
+
+	user, err := s.UserRepo.GetUserByEmail(normalizedEmail)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil || !user.CheckPassword(request.Password) {
+		log.Printf("Failed login attempt for email: %v", normalizedEmail)
+		return nil, apperrors.Unauthorized("invalid email or password")
+	}
+
+	return mapUserToResponse(user), nil
+}
+
+func (s *UserServices) GetProfile(userID uuid.UUID) (*Dto.UserResponse, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	return mapUserToResponse(user), nil
+}
+
+// UpdateProfile changes the user's name and email. A new email address has to be
+// verified again, which the caller learns from the returned flag.
+func (s *UserServices) UpdateProfile(userID uuid.UUID, request Dto.ProfileUpdateRequest) (*Dto.UserResponse, bool, error) {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if strings.TrimSpace(request.Name) != "" {
+		normalizedName := normalizeName(request.Name)
+		if !isNameValid(normalizedName) {
+			return nil, false, apperrors.Validation("Invalid Name")
+		}
+		user.Name = normalizedName
+	}
+
+	emailChanged := false
+	if strings.TrimSpace(request.Email) != "" {
+		normalizedEmail := normalizeEmail(request.Email)
+		if !isValidEmail(normalizedEmail) {
+			return nil, false, apperrors.Validation("Invalid Email Address")
+		}
+		if normalizedEmail != user.Email {
+			existingUser, err := s.UserRepo.GetUserByEmail(normalizedEmail)
+			if err != nil {
+				return nil, false, err
+			}
+			if existingUser != nil {
+				return nil, false, apperrors.Conflict("email already registered")
+			}
+			user.Email = normalizedEmail
+			user.EmailVerifiedAt = nil
+			emailChanged = true
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, false, fmt.Errorf("Failed to update profile: %w", err)
+	}
+
+	return mapUserToResponse(user), emailChanged, nil
+}
+
+// CloseAccount anonymises the user instead of deleting the row, so loan history stays
+// intact for the library's records without identifying the patron.
+func (s *UserServices) CloseAccount(userID uuid.UUID) error {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsClosed() {
+		return apperrors.Conflict("Account is already closed")
+	}
+
+	activeLoans, err := s.LoanRepo.CountActiveLoansByUser(user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to check loans: %w", err)
+	}
+	if activeLoans > 0 {
+		return apperrors.Conflict("Account cannot be closed while loans are outstanding")
+	}
+
+	balance, err := s.FineRepo.GetOutstandingBalance(user.ID)
+	if err != nil {
+		return fmt.Errorf("Failed to check fines: %w", err)
+	}
+	if balance > 0 {
+		return apperrors.Conflict("Account cannot be closed while fines are outstanding")
+	}
+
+	anonymousEmail := fmt.Sprintf("closed-%s@invalid.local", user.ID)
+	if err := s.LoanRepo.AnonymiseLoans(user.ID, anonymousEmail); err != nil {
+		return fmt.Errorf("Failed to anonymise loan history: %w", err)
+	}
+
+	// Without this an SSO or directory sign-in would find the closed account again.
+	if err := s.IdentityRepo.DeleteIdentitiesByUser(user.ID); err != nil {
+		return fmt.Errorf("Failed to unlink sign-in identities: %w", err)
+	}
+
+	now := time.Now()
+	user.Name = "closed account"
+	user.Email = anonymousEmail
+	user.CardNumber = nulls.String{}
+	user.EmailVerifiedAt = nil
+	user.PasswordHash = ""
+	user.ClosedAt = &now
+	user.SessionsRevokedAt = &now
+	user.UpdatedAt = now
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return fmt.Errorf("Failed to close account: %w", err)
+	}
+
+	return nil
+}
+
+func (s *UserServices) ChangeUserRole(userID uuid.UUID, request Dto.UserRoleRequest) (*Dto.UserResponse, error) {
+	role := strings.ToLower(strings.TrimSpace(request.Role))
+	if !models.IsValidRole(role) {
+		return nil, apperrors.Validation("Invalid Role")
+	}
+
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Role = role
+	user.UpdatedAt = time.Now()
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to update user role: %w", err)
+	}
+
+	return mapUserToResponse(user), nil
+}
+
+// ResolveCardNumber fills in the patron's email from a scanned library card so the rest
+// of the circulation flow can keep working by email. Requests without a card are left alone.
+// AuthorizeLoanAction calls it once per request; CheckOutBook, ReturnBook and ReserveBook
+// only read the email it leaves behind.
+func (s *UserServices) ResolveCardNumber(request *Dto.BookActionRequest) error {
+	if strings.TrimSpace(request.CardNumber) == "" {
+		return nil
+	}
+
+	card := models.NormalizeCardNumber(request.CardNumber)
+	if !models.IsValidCardNumber(card) {
+		return apperrors.Validation("Invalid Card Number")
+	}
+
+	user, err := s.UserRepo.GetUserByCardNumber(card)
+	if err != nil {
+		return fmt.Errorf("User not found: %w", err)
+	}
+	if user == nil {
+		return apperrors.NotFound("No patron found for this card")
+	}
+	if request.Email != "" && normalizeEmail(request.Email) != user.Email {
+		return apperrors.Validation("Card number does not match email")
+	}
+
+	request.CardNumber = card
+	request.Email = user.Email
+	return nil
 }
 
 func (s *UserServices) CheckOutBook(request Dto.BookActionRequest) (*Dto.BookActionResponse, error) {
@@ -60,27 +245,34 @@ func (s *UserServices) CheckOutBook(request Dto.BookActionRequest) (*Dto.BookAct
 
 	normalizedEmail := normalizeEmail(request.Email)
 	if !isValidEmail(normalizedEmail) {
-		return nil, errors.New("Invalid Email Address")
+		return nil, apperrors.Validation("Invalid Email Address")
 	}
 
 	user, err := s.UserRepo.GetUserByEmail(normalizedEmail)
 	if err != nil {
-		return nil, fmt.Errorf("User not found: %v", err)
+		return nil, fmt.Errorf("User not found: %w", err)
+	}
+	if user == nil {
+		return nil, apperrors.NotFound("User not found")
 	}
 
 	book, err := s.BookRepo.GetBookByID(request.BookID)
 	if err != nil {
-		return nil, fmt.Errorf("Book not found: %v", err)
+		return nil, fmt.Errorf("Book not found: %w", err)
 	}
 
-	if strings.ToLower(book.Status) != "available" {
+	if normalizeStatus(book.Status) != models.StatusAvailable {
 		log.Printf("Book is currently %s", book.Status)
-		return nil, fmt.Errorf("Book is not available for checkout")
+		return nil, apperrors.Unavailable("Book is not available for checkout")
+	}
+
+	if err := s.checkPatronCanBorrow(user); err != nil {
+		return nil, err
 	}
 
 	existingLoan, err := s.LoanRepo.GetLoanByBookAndEmail(request.BookID, normalizedEmail)
 	if err == nil && existingLoan != nil && existingLoan.ReturnDate == nil {
-		return nil, errors.New("You have already borrowed this book")
+		return nil, apperrors.Validation("You have already borrowed this book")
 	}
 
 	now := time.Now()
@@ -89,22 +281,23 @@ func (s *UserServices) CheckOutBook(request Dto.BookActionRequest) (*Dto.BookAct
 		BookID:     request.BookID,
 		Email:      normalizedEmail,
 		UserID:     user.ID,
+		Kind:       models.LoanKindLoan,
 		LoanDate:   now,
 		ReturnDate: nil,
 		CreatedAt:  now,
 		UpdatedAt:  now,
 	}
 
-	book.Status = "borrowed"
+	book.Status = models.StatusBorrowed
 	if err := s.BookRepo.UpdateBook(book); err != nil {
 		log.Printf("Failed to update book status: %v", err)
-		return nil, fmt.Errorf("Failed to update book status: %v", err)
+		return nil, fmt.Errorf("Failed to update book status: %w", err)
 	}
 
 	if err := s.LoanRepo.AddLoan(loan); err != nil {
-		book.Status = "available"
+		book.Status = models.StatusAvailable
 		_ = s.BookRepo.UpdateBook(book)
-		return nil, fmt.Errorf("Failed to create loan: %v", err)
+		return nil, fmt.Errorf("Failed to create loan: %w", err)
 	}
 
 	return &Dto.BookActionResponse{
@@ -112,7 +305,7 @@ func (s *UserServices) CheckOutBook(request Dto.BookActionRequest) (*Dto.BookAct
 		UserID:   user.ID,
 		BookID:   loan.BookID,
 		Email:    loan.Email,
-		Status:   "borrowed",
+		Status:   models.StatusBorrowed,
 		LoanDate: loan.LoanDate,
 	}, nil
 }
@@ -122,43 +315,52 @@ func (s *UserServices) ReturnBook(request Dto.BookActionRequest) (*Dto.BookActio
 
 	normalizedEmail := normalizeEmail(request.Email)
 	if !isValidEmail(normalizedEmail) {
-		return nil, errors.New("Invalid Email Address")
+		return nil, apperrors.Validation("Invalid Email Address")
 	}
 
 	loan, err := s.LoanRepo.GetLoanByBookAndEmail(request.BookID, normalizedEmail)
 	if err != nil || loan == nil {
-		return nil, errors.New("No active loan found for this book")
+		return nil, apperrors.NotFound("No active loan found for this book")
 	}
 
+	return s.completeReturn(loan)
+}
+
+// completeReturn closes the loan and puts the book back on the shelf.
+func (s *UserServices) completeReturn(loan *models.Loan) (*Dto.BookActionResponse, error) {
 	if loan.ReturnDate != nil {
-		return nil, errors.New("Book has already been returned")
+		return nil, apperrors.Validation("Book has already been returned")
 	}
 
-	book, err := s.BookRepo.GetBookByID(request.BookID)
+	book, err := s.BookRepo.GetBookByID(loan.BookID)
 	if err != nil {
-		return nil, fmt.Errorf("Book not found: %v", err)
+		return nil, fmt.Errorf("Book not found: %w", err)
 	}
 
 	now := time.Now()
 	loan.ReturnDate = &now
 	loan.UpdatedAt = now
 
-	book.Status = "available"
+	previousStatus := book.Status
+	if err := book.TransitionTo(models.StatusAvailable); err != nil {
+		return nil, apperrors.Validation("Book cannot be returned: %v", err)
+	}
 	if err := s.BookRepo.UpdateBook(book); err != nil {
-		return nil, fmt.Errorf("Failed to update book status: %v", err)
+		return nil, fmt.Errorf("Failed to update book status: %w", err)
 	}
 
 	if err := s.LoanRepo.UpdateLoan(loan); err != nil {
-		book.Status = "borrowed"
+		book.Status = previousStatus
 		_ = s.BookRepo.UpdateBook(book)
-		return nil, fmt.Errorf("Failed to update loan: %v", err)
+		return nil, fmt.Errorf("Failed to update loan: %w", err)
 	}
 
 	return &Dto.BookActionResponse{
 		ID:         loan.ID,
+		UserID:     loan.UserID,
 		BookID:     loan.BookID,
 		Email:      loan.Email,
-		Status:     "available",
+		Status:     models.StatusAvailable,
 		LoanDate:   loan.LoanDate,
 		ReturnDate: loan.ReturnDate,
 	}, nil
@@ -169,21 +371,28 @@ func (s *UserServices) ReserveBook(request Dto.BookActionRequest) (*Dto.BookActi
 
 	normalizedEmail := normalizeEmail(request.Email)
 	if !isValidEmail(normalizedEmail) {
-		return nil, errors.New("Invalid Email Address")
+		return nil, apperrors.Validation("Invalid Email Address")
 	}
 
 	user, err := s.UserRepo.GetUserByEmail(normalizedEmail)
 	if err != nil {
-		return nil, fmt.Errorf("User not found: %v", err)
+		return nil, fmt.Errorf("User not found: %w", err)
+	}
+	if user == nil {
+		return nil, apperrors.NotFound("User not found")
 	}
 
 	book, err := s.BookRepo.GetBookByID(request.BookID)
 	if err != nil {
-		return nil, fmt.Errorf("Book not found: %v", err)
+		return nil, fmt.Errorf("Book not found: %w", err)
 	}
 
-	if strings.ToLower(book.Status) != "available" {
-		return nil, errors.New("Book is not available for reservation")
+	if normalizeStatus(book.Status) != models.StatusAvailable {
+		return nil, apperrors.Unavailable("Book is not available for reservation")
+	}
+
+	if err := s.checkPatronCanBorrow(user); err != nil {
+		return nil, err
 	}
 
 	now := time.Now()
@@ -192,20 +401,21 @@ func (s *UserServices) ReserveBook(request Dto.BookActionRequest) (*Dto.BookActi
 		BookID:    request.BookID,
 		Email:     normalizedEmail,
 		UserID:    user.ID,
+		Kind:      models.LoanKindHold,
 		LoanDate:  now,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	book.Status = "reserved"
+	book.Status = models.StatusReserved
 	if err := s.BookRepo.UpdateBook(book); err != nil {
-		return nil, fmt.Errorf("Failed to update book status: %v", err)
+		return nil, fmt.Errorf("Failed to update book status: %w", err)
 	}
 
 	if err := s.LoanRepo.AddLoan(loan); err != nil {
-		book.Status = "available"
+		book.Status = models.StatusAvailable
 		_ = s.BookRepo.UpdateBook(book)
-		return nil, fmt.Errorf("Failed to create reservation: %v", err)
+		return nil, fmt.Errorf("Failed to create reservation: %w", err)
 	}
 
 	return &Dto.BookActionResponse{
@@ -213,13 +423,108 @@ func (s *UserServices) ReserveBook(request Dto.BookActionRequest) (*Dto.BookActi
 		UserID:   user.ID,
 		BookID:   loan.BookID,
 		Email:    loan.Email,
-		Status:   "reserved",
+		Status:   models.StatusReserved,
 		LoanDate: loan.LoanDate,
 	}, nil
 }
 
 // Helper functions
 
+// checkPatronCanBorrow holds the account-level rules a patron must meet before
+// checking out or reserving a book.
+func (s *UserServices) checkPatronCanBorrow(user *models.User) error {
+	if !user.IsEmailVerified() {
+		return apperrors.Forbidden("Email address must be verified before borrowing")
+	}
+	if user.IsSuspended(time.Now()) {
+		return &BorrowingBlockedError{
+			Code:    BlockCodeSuspended,
+			Message: "Account is suspended: " + user.SuspensionReason,
+		}
+	}
+	if s.FineBlockThresholdCents > 0 {
+		balance, err := s.FineRepo.GetOutstandingBalance(user.ID)
+		if err != nil {
+			return fmt.Errorf("Failed to check fines: %w", err)
+		}
+		if balance > s.FineBlockThresholdCents {
+			return &BorrowingBlockedError{
+				Code:    BlockCodeFinesOverLimit,
+				Message: "Borrowing is blocked until outstanding fines are paid",
+			}
+		}
+	}
+	return nil
+}
+
+// AssignMissingCardNumbers gives a library card to every open account created before
+// card numbers existed, and returns how many it assigned.
+func (s *UserServices) AssignMissingCardNumbers() (int, error) {
+	users, err := s.UserRepo.GetUsersWithoutCardNumber()
+	if err != nil {
+		return 0, fmt.Errorf("Failed to find users without a card: %w", err)
+	}
+	for i := range users {
+		if err := assignCardNumber(s.UserRepo, &users[i]); err != nil {
+			return i, err
+		}
+		users[i].UpdatedAt = time.Now()
+		if err := s.UserRepo.UpdateUser(&users[i]); err != nil {
+			return i, fmt.Errorf("Failed to assign a card to %s: %w", users[i].Email, err)
+		}
+	}
+	return len(users), nil
+}
+
+// assignCardNumber gives the user a card number nobody else holds.
+func assignCardNumber(userRepo repository.UserRepository, user *models.User) error {
+	for attempt := 0; attempt < 5; attempt++ {
+		card, err := models.GenerateCardNumber()
+		if err != nil {
+			return fmt.Errorf("Failed to generate card number: %w", err)
+		}
+		existingUser, err := userRepo.GetUserByCardNumber(card)
+		if err != nil {
+			return err
+		}
+		if existingUser == nil {
+			user.CardNumber = nulls.NewString(card)
+			return nil
+		}
+	}
+	return errors.New("Failed to generate a unique card number")
+}
+
+// NewUserResponse maps a user for handlers that hold the model itself, such as the
+// directory sign-in, so they answer with the same fields as every other login.
+func NewUserResponse(user *models.User) *Dto.UserResponse {
+	return mapUserToResponse(user)
+}
+
+func mapUserToResponse(user *models.User) *Dto.UserResponse {
+	now := time.Now()
+	response := &Dto.UserResponse{
+		ID:            user.ID,
+		Name:          user.Name,
+		Email:         user.Email,
+		CardNumber:    user.CardNumber.String,
+		EmailVerified: user.IsEmailVerified(),
+		Role:          user.Role,
+		PatronType:    user.PatronType,
+		Status:        user.Status(now),
+		Suspended:     user.IsSuspended(now),
+		TwoFactor:     user.TwoFactorEnabled(),
+	}
+	if response.Suspended {
+		response.Suspension = &Dto.SuspensionResponse{
+			Reason:         user.SuspensionReason,
+			SuspendedAt:    *user.SuspendedAt,
+			SuspendedUntil: user.SuspendedUntil,
+		}
+	}
+	return response
+}
+
 func isValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`)
 	return emailRegex.MatchString(email)
@@ -10,8 +10,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/gobuffalo/nulls"
 	"github.com/gofrs/uuid"
 	"github.com/stretchr/testify/assert"
+	"library-system/apperrors"
 )
 
 func TestUserServices_RegisterUser(t *testing.T) {
@@ -20,8 +22,9 @@ func TestUserServices_RegisterUser(t *testing.T) {
 		userService := UserServices{UserRepo: userRepo}
 
 		request := Dto.UserRequest{
-			Name:  "Aminat Usman",
-			Email: "meenah20@gmail.com",
+			Name:     "Aminat Usman",
+			Email:    "meenah20@gmail.com",
+			Password: "correct horse battery",
 		}
 
 		// Call the RegisterUser method
@@ -38,6 +41,22 @@ func TestUserServices_RegisterUser(t *testing.T) {
 		assert.NotNil(t, user)
 		assert.Equal(t, user.Name, "aminat usman")
 		assert.Equal(t, user.Email, "meenah20@gmail.com")
+		assert.NotEmpty(t, userRepo.MockUser[0].PasswordHash)
+		assert.NotEqual(t, "correct horse battery", userRepo.MockUser[0].PasswordHash)
+		assert.True(t, models.IsValidCardNumber(user.CardNumber))
+		assert.Equal(t, user.CardNumber, userRepo.MockUser[0].CardNumber.String)
+	})
+
+	t.Run("password too short", func(t *testing.T) {
+		userRepo := &mock.MockUserRepo{}
+		service := UserServices{UserRepo: userRepo}
+
+		request := Dto.UserRequest{Name: "Aminat Usman", Email: "meenah20@gmail.com", Password: "short"}
+		user, err := service.RegisterUser(request)
+		assert.Error(t, err)
+		assert.Nil(t, user)
+		assert.Equal(t, "password must be at least 8 characters", err.Error())
+		assert.Empty(t, userRepo.MockUser)
 	})
 
 	t.Run("Invalid Email", func(t *testing.T) {
@@ -68,7 +87,7 @@ func TestUserServices_RegisterUser(t *testing.T) {
 		}
 		service := UserServices{UserRepo: userRepo}
 
-		request := Dto.UserRequest{Name: "Aminat Usman", Email: "meenah20@gmail.com"}
+		request := Dto.UserRequest{Name: "Aminat Usman", Email: "meenah20@gmail.com", Password: "correct horse battery"}
 		user, err := service.RegisterUser(request)
 		assert.Error(t, err)
 		assert.Nil(t, user)
@@ -98,15 +117,56 @@ func TestUserServices_RegisterUser(t *testing.T) {
 	})
 }
 
+func TestUserServices_Login(t *testing.T) {
+	newUser := func(t *testing.T) models.User {
+		user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+		if err := user.SetPassword("correct horse battery"); err != nil {
+			t.Fatal(err)
+		}
+		return user
+	}
+
+	t.Run("success", func(t *testing.T) {
+		user := newUser(t)
+		service := UserServices{UserRepo: &mock.MockUserRepo{MockUser: []models.User{user}}}
+
+		response, err := service.Login(Dto.LoginRequest{Email: " Meenah20@gmail.com ", Password: "correct horse battery"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, user.ID, response.ID)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		service := UserServices{UserRepo: &mock.MockUserRepo{MockUser: []models.User{newUser(t)}}}
+
+		response, err := service.Login(Dto.LoginRequest{Email: "meenah20@gmail.com", Password: "wrong password"})
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "invalid email or password", err.Error())
+	})
+
+	t.Run("unknown email", func(t *testing.T) {
+		service := UserServices{UserRepo: &mock.MockUserRepo{}}
+
+		response, err := service.Login(Dto.LoginRequest{Email: "nobody@example.com", Password: "correct horse battery"})
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "invalid email or password", err.Error())
+	})
+}
+
 func TestUserServices_CheckOutBook(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		bookID := uuid.Must(uuid.NewV4())
 		userID := uuid.Must(uuid.NewV4())
 		email := "meenah20@gmail.com"
 
+		verifiedAt := time.Now()
 		userRepo := &mock.MockUserRepo{
 			MockUser: []models.User{
-				{ID: userID, Name: "Aminat Usman", Email: email},
+				{ID: userID, Name: "Aminat Usman", Email: email, EmailVerifiedAt: &verifiedAt},
 			},
 		}
 		bookRepo := &mock.MockBookRepository{
@@ -130,6 +190,41 @@ func TestUserServices_CheckOutBook(t *testing.T) {
 		assert.Equal(t, email, response.Email)
 	})
 
+	t.Run("success by card number", func(t *testing.T) {
+		bookID := uuid.Must(uuid.NewV4())
+		userID := uuid.Must(uuid.NewV4())
+		email := "meenah20@gmail.com"
+		card, _ := models.GenerateCardNumber()
+
+		verifiedAt := time.Now()
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{
+				{ID: userID, Name: "Aminat Usman", Email: email, CardNumber: nulls.NewString(card), EmailVerifiedAt: &verifiedAt},
+			},
+		}
+		bookRepo := &mock.MockBookRepository{
+			MockBooks: []models.Book{
+				{ID: bookID, Title: "Test Book", Status: "available"},
+			},
+		}
+		loanRepo := &mock.MockLoanRepository{}
+		service := UserServices{UserRepo: userRepo, LoanRepo: loanRepo, BookRepo: bookRepo}
+
+		request := Dto.BookActionRequest{
+			BookID:     bookID,
+			CardNumber: card[:4] + " " + card[4:],
+		}
+		librarian := &models.User{Email: "desk@library.org", Role: models.RoleLibrarian}
+		assert.NoError(t, service.AuthorizeLoanAction(librarian, &request))
+
+		response, err := service.CheckOutBook(request)
+
+		assert.NoError(t, err)
+		assert.NotNil(t, response)
+		assert.Equal(t, userID, response.UserID)
+		assert.Equal(t, email, response.Email)
+	})
+
 	t.Run("invalid email", func(t *testing.T) {
 		bookID := uuid.Must(uuid.NewV4())
 		service := UserServices{}
@@ -204,6 +299,32 @@ func TestUserServices_CheckOutBook(t *testing.T) {
 		assert.Equal(t, "Book not found: book not found", err.Error())
 	})
 
+	t.Run("email not verified", func(t *testing.T) {
+		bookID := uuid.Must(uuid.NewV4())
+		email := "meenah20@gmail.com"
+
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{
+				{ID: uuid.Must(uuid.NewV4()), Email: email},
+			},
+		}
+		bookRepo := &mock.MockBookRepository{
+			MockBooks: []models.Book{
+				{ID: bookID, Status: "available"},
+			},
+		}
+		loanRepo := &mock.MockLoanRepository{}
+		service := UserServices{UserRepo: userRepo, BookRepo: bookRepo, LoanRepo: loanRepo}
+
+		response, err := service.CheckOutBook(Dto.BookActionRequest{BookID: bookID, Email: email})
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "Email address must be verified before borrowing", err.Error())
+		assert.Empty(t, loanRepo.MockLoans)
+		assert.Equal(t, "available", bookRepo.MockBooks[0].Status)
+	})
+
 	t.Run("book already borrowed", func(t *testing.T) {
 		bookID := uuid.Must(uuid.NewV4())
 		email := "meenah20@gmail.com"
@@ -239,6 +360,7 @@ func TestUserServices_CheckOutBook(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, response)
 		assert.Equal(t, "Book is not available for checkout", err.Error())
+		assert.True(t, errors.Is(err, apperrors.ErrUnavailable))
 	})
 }
 
@@ -366,9 +488,10 @@ func TestUserServices_ReserveBook(t *testing.T) {
 		userID := uuid.Must(uuid.NewV4())
 		email := "meenah20@gmail.com"
 
+		verifiedAt := time.Now()
 		userRepo := &mock.MockUserRepo{
 			MockUser: []models.User{
-				{ID: userID, Email: email},
+				{ID: userID, Email: email, EmailVerifiedAt: &verifiedAt},
 			},
 		}
 		bookRepo := &mock.MockBookRepository{
@@ -503,3 +626,224 @@ func TestUserServices_ReserveBook(t *testing.T) {
 		assert.Equal(t, "Book is not available for reservation", err.Error())
 	})
 }
+
+func TestUserServices_ResolveCardNumber(t *testing.T) {
+	card, _ := models.GenerateCardNumber()
+	userRepo := &mock.MockUserRepo{
+		MockUser: []models.User{
+			{ID: uuid.Must(uuid.NewV4()), Name: "Aminat Usman", Email: "meenah20@gmail.com", CardNumber: nulls.NewString(card)},
+		},
+	}
+	service := UserServices{UserRepo: userRepo}
+
+	t.Run("resolves email", func(t *testing.T) {
+		request := Dto.BookActionRequest{CardNumber: card}
+		assert.NoError(t, service.ResolveCardNumber(&request))
+		assert.Equal(t, "meenah20@gmail.com", request.Email)
+	})
+
+	t.Run("bad check digit", func(t *testing.T) {
+		last := (card[len(card)-1]-'0'+1)%10 + '0'
+		request := Dto.BookActionRequest{CardNumber: card[:len(card)-1] + string(last)}
+		err := service.ResolveCardNumber(&request)
+		assert.Error(t, err)
+		assert.Equal(t, "Invalid Card Number", err.Error())
+	})
+
+	t.Run("unknown card", func(t *testing.T) {
+		other, _ := models.GenerateCardNumber()
+		request := Dto.BookActionRequest{CardNumber: other}
+		err := service.ResolveCardNumber(&request)
+		assert.Error(t, err)
+		assert.Equal(t, "No patron found for this card", err.Error())
+	})
+
+	t.Run("card and email disagree", func(t *testing.T) {
+		request := Dto.BookActionRequest{CardNumber: card, Email: "someone@else.com"}
+		err := service.ResolveCardNumber(&request)
+		assert.Error(t, err)
+		assert.Equal(t, "Card number does not match email", err.Error())
+	})
+
+	t.Run("no card leaves request alone", func(t *testing.T) {
+		request := Dto.BookActionRequest{Email: "someone@else.com"}
+		assert.NoError(t, service.ResolveCardNumber(&request))
+		assert.Equal(t, "someone@else.com", request.Email)
+	})
+}
+
+func TestUserServices_ChangeUserRole(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{{ID: userID, Email: "meenah20@gmail.com", Role: models.RolePatron}},
+		}
+		service := UserServices{UserRepo: userRepo}
+
+		response, err := service.ChangeUserRole(userID, Dto.UserRoleRequest{Role: "Librarian"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, models.RoleLibrarian, response.Role)
+		assert.Equal(t, models.RoleLibrarian, userRepo.MockUser[0].Role)
+	})
+
+	t.Run("invalid role", func(t *testing.T) {
+		service := UserServices{UserRepo: &mock.MockUserRepo{}}
+
+		response, err := service.ChangeUserRole(uuid.Must(uuid.NewV4()), Dto.UserRoleRequest{Role: "superuser"})
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "Invalid Role", err.Error())
+	})
+}
+
+func TestCanActOnPatronLoans(t *testing.T) {
+	patron := &models.User{Email: "meenah20@gmail.com", Role: models.RolePatron}
+	librarian := &models.User{Email: "desk@library.org", Role: models.RoleLibrarian}
+
+	assert.True(t, CanActOnPatronLoans(patron, "Meenah20@gmail.com"))
+	assert.False(t, CanActOnPatronLoans(patron, "someone.else@gmail.com"))
+	assert.True(t, CanActOnPatronLoans(librarian, "someone.else@gmail.com"))
+	assert.False(t, CanActOnPatronLoans(nil, "meenah20@gmail.com"))
+}
+
+func TestUserServices_UpdateProfile(t *testing.T) {
+	t.Run("email change requires verification again", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		verifiedAt := time.Now()
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com", EmailVerifiedAt: &verifiedAt}},
+		}
+		service := UserServices{UserRepo: userRepo}
+
+		response, emailChanged, err := service.UpdateProfile(userID, Dto.ProfileUpdateRequest{
+			Name:  "Aminat Bello",
+			Email: "Aminat.Bello@gmail.com",
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, emailChanged)
+		assert.Equal(t, "aminat bello", response.Name)
+		assert.Equal(t, "aminat.bello@gmail.com", response.Email)
+		assert.False(t, response.EmailVerified)
+		assert.Nil(t, userRepo.MockUser[0].EmailVerifiedAt)
+	})
+
+	t.Run("email taken by another user", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{
+				{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com"},
+				{ID: uuid.Must(uuid.NewV4()), Name: "someone else", Email: "taken@gmail.com"},
+			},
+		}
+		service := UserServices{UserRepo: userRepo}
+
+		response, _, err := service.UpdateProfile(userID, Dto.ProfileUpdateRequest{Email: "taken@gmail.com"})
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+		assert.Equal(t, "email already registered", err.Error())
+	})
+
+	t.Run("keeps the kind of a failed update", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		userRepo := &mock.MockUserRepo{
+			MockUser:        []models.User{{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com"}},
+			UpdateUserError: apperrors.Conflict("email already registered"),
+		}
+		service := UserServices{UserRepo: userRepo}
+
+		_, _, err := service.UpdateProfile(userID, Dto.ProfileUpdateRequest{Name: "Aminat Bello"})
+
+		assert.True(t, errors.Is(err, apperrors.ErrConflict))
+	})
+}
+
+func TestUserServices_CloseAccount(t *testing.T) {
+	t.Run("anonymises the account and loan history", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		returnedAt := time.Now()
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com", CardNumber: nulls.NewString("29000000000015")}},
+		}
+		loanRepo := &mock.MockLoanRepository{
+			MockLoans: []models.Loan{{ID: uuid.Must(uuid.NewV4()), UserID: userID, Email: "meenah20@gmail.com", ReturnDate: &returnedAt}},
+		}
+		otherID := uuid.Must(uuid.NewV4())
+		identityRepo := &mock.MockUserIdentityRepository{
+			MockIdentities: []models.UserIdentity{
+				{ID: uuid.Must(uuid.NewV4()), UserID: userID, Provider: "ldap", Subject: "uid=amusman"},
+				{ID: uuid.Must(uuid.NewV4()), UserID: otherID, Provider: "ldap", Subject: "uid=someone"},
+			},
+		}
+		service := UserServices{UserRepo: userRepo, LoanRepo: loanRepo, FineRepo: &mock.MockFineRepository{}, IdentityRepo: identityRepo}
+
+		err := service.CloseAccount(userID)
+
+		assert.NoError(t, err)
+		closed := userRepo.MockUser[0]
+		assert.True(t, closed.IsClosed())
+		assert.NotContains(t, closed.Email, "meenah20")
+		assert.Empty(t, closed.PasswordHash)
+		assert.False(t, closed.CardNumber.Valid)
+		assert.Len(t, identityRepo.MockIdentities, 1)
+		assert.Equal(t, otherID, identityRepo.MockIdentities[0].UserID)
+		assert.Len(t, loanRepo.MockLoans, 1)
+		assert.Equal(t, closed.Email, loanRepo.MockLoans[0].Email)
+	})
+
+	t.Run("blocked by active loan", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com"}},
+		}
+		loanRepo := &mock.MockLoanRepository{
+			MockLoans: []models.Loan{{ID: uuid.Must(uuid.NewV4()), UserID: userID, Email: "meenah20@gmail.com"}},
+		}
+		service := UserServices{UserRepo: userRepo, LoanRepo: loanRepo, FineRepo: &mock.MockFineRepository{}}
+
+		err := service.CloseAccount(userID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "Account cannot be closed while loans are outstanding", err.Error())
+		assert.False(t, userRepo.MockUser[0].IsClosed())
+	})
+
+	t.Run("blocked by outstanding fines", func(t *testing.T) {
+		userID := uuid.Must(uuid.NewV4())
+		userRepo := &mock.MockUserRepo{
+			MockUser: []models.User{{ID: userID, Name: "aminat usman", Email: "meenah20@gmail.com"}},
+		}
+		fineRepo := &mock.MockFineRepository{
+			MockFines: []models.Fine{{ID: uuid.Must(uuid.NewV4()), UserID: userID, AmountCents: 250, Reason: "Overdue"}},
+		}
+		service := UserServices{UserRepo: userRepo, LoanRepo: &mock.MockLoanRepository{}, FineRepo: fineRepo}
+
+		err := service.CloseAccount(userID)
+
+		assert.Error(t, err)
+		assert.Equal(t, "Account cannot be closed while fines are outstanding", err.Error())
+	})
+}
+
+func TestUserServices_AssignMissingCardNumbers(t *testing.T) {
+	closedAt := time.Now()
+	userRepo := &mock.MockUserRepo{
+		MockUser: []models.User{
+			{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"},
+			{ID: uuid.Must(uuid.NewV4()), Name: "card holder", Email: "holder@gmail.com", CardNumber: nulls.NewString("29000000000015")},
+			{ID: uuid.Must(uuid.NewV4()), Name: "closed account", Email: "closed@invalid.local", ClosedAt: &closedAt},
+		},
+	}
+	service := UserServices{UserRepo: userRepo}
+
+	assigned, err := service.AssignMissingCardNumbers()
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, assigned)
+	assert.True(t, models.IsValidCardNumber(userRepo.MockUser[0].CardNumber.String))
+	assert.Equal(t, "29000000000015", userRepo.MockUser[1].CardNumber.String)
+	assert.False(t, userRepo.MockUser[2].CardNumber.Valid)
+}
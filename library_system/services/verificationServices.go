@@ -0,0 +1,141 @@
+package services
+
+import (
+	"fmt"
+	"github.com/gofrs/uuid"
+	"library-system/Dto"
+	"library-system/apperrors"
+	"library-system/mailers"
+	"library-system/models"
+	"library-system/repositories/repository"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	emailVerificationLifetime = 48 * time.Hour
+	emailVerificationWindow   = time.Hour
+	maxVerificationRequests   = 3
+)
+
+type VerificationServices struct {
+	UserRepo  repository.UserRepository
+	TokenRepo repository.UserTokenRepository
+	Mailer    mailers.Mailer
+	BaseURL   string
+}
+
+func (s *VerificationServices) SendVerification(userID uuid.UUID) error {
+	user, err := s.UserRepo.GetUserByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsEmailVerified() {
+		return nil
+	}
+
+	raw, err := issueUserToken(s.TokenRepo, user, models.TokenPurposeEmailVerification, emailVerificationLifetime)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/users/verify?token=%s", strings.TrimRight(s.BaseURL, "/"), url.QueryEscape(raw))
+	return s.Mailer.Send(mailers.Message{
+		To:      user.Email,
+		Subject: "Verify your library account email",
+		Body: fmt.Sprintf("Hello %s,\n\nPlease confirm your email address by opening the link below:\n\n%s\n\n"+
+			"The link expires in %d hours. You will not be able to borrow books until your address is verified.\n",
+			user.Name, link, int(emailVerificationLifetime.Hours())),
+	})
+}
+
+// ResendVerification sends a fresh link to an unverified address, at most
+// maxVerificationRequests an hour. Unknown addresses and requests over the limit are
+// ignored so the endpoint cannot be used to discover who is registered.
+func (s *VerificationServices) ResendVerification(email string) error {
+	user, err := s.UserRepo.GetUserByEmail(normalizeEmail(email))
+	if err != nil {
+		return err
+	}
+	if user == nil {
+		log.Printf("Verification requested for unknown email: %v", normalizeEmail(email))
+		return nil
+	}
+
+	recent, err := s.TokenRepo.CountTokensSince(user.ID, models.TokenPurposeEmailVerification, time.Now().Add(-emailVerificationWindow))
+	if err != nil {
+		return err
+	}
+	if recent >= maxVerificationRequests {
+		log.Printf("Verification rate limit reached for email: %v", user.Email)
+		return nil
+	}
+	return s.SendVerification(user.ID)
+}
+
+func (s *VerificationServices) VerifyEmail(raw string) (*Dto.UserResponse, error) {
+	invalid := apperrors.Validation("invalid or expired verification link")
+
+	token, err := s.TokenRepo.GetTokenByHash(models.TokenPurposeEmailVerification, hashSecret(raw))
+	if err != nil {
+		return nil, invalid
+	}
+
+	now := time.Now()
+	if !token.IsUsable(now) {
+		return nil, invalid
+	}
+
+	user, err := s.UserRepo.GetUserByID(token.UserID)
+	if err != nil {
+		return nil, invalid
+	}
+	// The link only proves ownership of the address it was sent to.
+	if user.Email != token.Email {
+		return nil, invalid
+	}
+
+	token.UsedAt = &now
+	token.UpdatedAt = now
+	if err := s.TokenRepo.UpdateToken(token); err != nil {
+		return nil, fmt.Errorf("Failed to consume verification token: %w", err)
+	}
+
+	user.EmailVerifiedAt = &now
+	user.UpdatedAt = now
+	if err := s.UserRepo.UpdateUser(user); err != nil {
+		return nil, fmt.Errorf("Failed to verify email: %w", err)
+	}
+
+	return mapUserToResponse(user), nil
+}
+
+// issueUserToken stores a hashed single-use token for the user's current email
+// address and returns the plain secret to be sent to them.
+func issueUserToken(repo repository.UserTokenRepository, user *models.User, purpose string, lifetime time.Duration) (string, error) {
+	raw, err := generateSecret()
+	if err != nil {
+		return "", fmt.Errorf("Failed to generate token: %w", err)
+	}
+
+	now := time.Now()
+	token := &models.UserToken{
+		ID:        uuid.Must(uuid.NewV4()),
+		UserID:    user.ID,
+		Purpose:   purpose,
+		Email:     user.Email,
+		TokenHash: hashSecret(raw),
+		ExpiresAt: now.Add(lifetime),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := token.Validate(); err != nil {
+		return "", err
+	}
+	if err := repo.AddToken(token); err != nil {
+		return "", fmt.Errorf("Failed to store token: %w", err)
+	}
+	return raw, nil
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/models"
+	"library-system/repositories/mock"
+)
+
+func setupVerificationService(users ...models.User) (*VerificationServices, *mock.MockUserRepo, *mock.MockMailer) {
+	userRepo := &mock.MockUserRepo{MockUser: users}
+	mailer := &mock.MockMailer{}
+	service := &VerificationServices{
+		UserRepo:  userRepo,
+		TokenRepo: &mock.MockUserTokenRepository{},
+		Mailer:    mailer,
+		BaseURL:   "http://library.test",
+	}
+	return service, userRepo, mailer
+}
+
+// tokenFromLink pulls the token query parameter out of the link in an email body.
+func tokenFromLink(t *testing.T, body string) string {
+	start := strings.Index(body, "http://library.test")
+	if start < 0 {
+		t.Fatalf("no link found in %q", body)
+	}
+	link := strings.Fields(body[start:])[0]
+	parsed, err := url.Parse(link)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parsed.Query().Get("token")
+}
+
+func TestVerificationServices_VerifyEmail(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, userRepo, mailer := setupVerificationService(user)
+
+	err := service.SendVerification(user.ID)
+	assert.NoError(t, err)
+	assert.Len(t, mailer.SentMessages, 1)
+	assert.Equal(t, "meenah20@gmail.com", mailer.SentMessages[0].To)
+
+	raw := tokenFromLink(t, mailer.SentMessages[0].Body)
+	response, err := service.VerifyEmail(raw)
+
+	assert.NoError(t, err)
+	assert.True(t, response.EmailVerified)
+	assert.NotNil(t, userRepo.MockUser[0].EmailVerifiedAt)
+
+	_, err = service.VerifyEmail(raw)
+	assert.Error(t, err)
+	assert.Equal(t, "invalid or expired verification link", err.Error())
+}
+
+func TestVerificationServices_VerifyEmailRejectsExpiredToken(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, userRepo, mailer := setupVerificationService(user)
+
+	assert.NoError(t, service.SendVerification(user.ID))
+	tokenRepo := service.TokenRepo.(*mock.MockUserTokenRepository)
+	tokenRepo.MockTokens[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+	_, err := service.VerifyEmail(tokenFromLink(t, mailer.SentMessages[0].Body))
+
+	assert.Error(t, err)
+	assert.Nil(t, userRepo.MockUser[0].EmailVerifiedAt)
+}
+
+func TestVerificationServices_ResendVerificationIgnoresUnknownEmail(t *testing.T) {
+	service, _, mailer := setupVerificationService()
+
+	err := service.ResendVerification("nobody@example.com")
+
+	assert.NoError(t, err)
+	assert.Empty(t, mailer.SentMessages)
+}
+
+func TestVerificationServices_ResendVerificationIsRateLimited(t *testing.T) {
+	user := models.User{ID: uuid.Must(uuid.NewV4()), Name: "aminat usman", Email: "meenah20@gmail.com"}
+	service, _, mailer := setupVerificationService(user)
+
+	for i := 0; i < maxVerificationRequests+2; i++ {
+		assert.NoError(t, service.ResendVerification("meenah20@gmail.com"))
+	}
+
+	assert.Len(t, mailer.SentMessages, maxVerificationRequests)
+}
@@ -0,0 +1,125 @@
+// Package validation checks request DTOs against their `validate` struct tags. It
+// reports every failing field at once, so a client can correct a whole form in one
+// round trip instead of discovering problems one request at a time.
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"library-system/apperrors"
+	"library-system/models"
+)
+
+// FieldError describes one failing field. Field is the JSON name the client sent,
+// Code the rule that failed, such as "required" or "email".
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors lists the failing fields of one request. It matches apperrors.ErrValidation,
+// so code that only looks at the error's kind still answers 400.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, field := range e {
+		messages[i] = field.Message
+	}
+	return "Validation failed: " + strings.Join(messages, "; ")
+}
+
+func (e Errors) Is(target error) bool {
+	return target == apperrors.ErrValidation
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	v.RegisterValidation("notblank", func(fl validator.FieldLevel) bool {
+		return strings.TrimSpace(fl.Field().String()) != ""
+	})
+	v.RegisterValidation("isbn", func(fl validator.FieldLevel) bool {
+		return models.ValidateISBN(fl.Field().String())
+	})
+	v.RegisterValidation("book_status", func(fl validator.FieldLevel) bool {
+		return models.IsValidBookStatus(strings.ToLower(strings.TrimSpace(fl.Field().String())))
+	})
+	v.RegisterValidation("password", func(fl validator.FieldLevel) bool {
+		return len(fl.Field().String()) >= models.MinPasswordLength
+	})
+	return v
+}
+
+// Struct validates request, which must be a struct or a pointer to one. It returns nil
+// or Errors.
+func Struct(request interface{}) error {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+	failures, ok := err.(validator.ValidationErrors)
+	if !ok {
+		return err
+	}
+
+	errs := make(Errors, len(failures))
+	for i, failure := range failures {
+		errs[i] = FieldError{
+			Field:   failure.Field(),
+			Code:    failure.Tag(),
+			Message: message(failure),
+		}
+	}
+	return errs
+}
+
+func message(failure validator.FieldError) string {
+	field, param := failure.Field(), failure.Param()
+	switch failure.Tag() {
+	case "required":
+		return fmt.Sprintf("%s is required", field)
+	case "notblank":
+		return fmt.Sprintf("%s cannot be blank", field)
+	case "email":
+		return fmt.Sprintf("%s must be a valid email address", field)
+	case "oneof":
+		return fmt.Sprintf("%s must be one of: %s", field, strings.Join(strings.Fields(param), ", "))
+	case "min":
+		if failure.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at least %s characters", field, param)
+		}
+		if failure.Kind() == reflect.Slice {
+			return fmt.Sprintf("%s must have at least %s items", field, param)
+		}
+		return fmt.Sprintf("%s must be at least %s", field, param)
+	case "max":
+		if failure.Kind() == reflect.String {
+			return fmt.Sprintf("%s must be at most %s characters", field, param)
+		}
+		return fmt.Sprintf("%s must be at most %s", field, param)
+	case "isbn":
+		return fmt.Sprintf("%s must be a valid ISBN-10 or ISBN-13", field)
+	case "book_status":
+		return fmt.Sprintf("%s is not a known book status", field)
+	case "password":
+		return fmt.Sprintf("%s must be at least %d characters", field, models.MinPasswordLength)
+	default:
+		return fmt.Sprintf("%s is invalid", field)
+	}
+}
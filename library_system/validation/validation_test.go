@@ -0,0 +1,57 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gofrs/uuid"
+	"github.com/stretchr/testify/assert"
+	"library-system/Dto"
+	"library-system/apperrors"
+)
+
+func TestStructReportsEveryFailingField(t *testing.T) {
+	err := Struct(Dto.BookRequest{Title: "Dune", ISBN: "not-an-isbn", Status: "misplaced"})
+
+	var errs Errors
+	if assert.True(t, errors.As(err, &errs)) {
+		assert.Equal(t, Errors{
+			{Field: "author", Code: "required", Message: "author is required"},
+			{Field: "isbn", Code: "isbn", Message: "isbn must be a valid ISBN-10 or ISBN-13"},
+			{Field: "status", Code: "book_status", Message: "status is not a known book status"},
+		}, errs)
+	}
+	assert.True(t, errors.Is(err, apperrors.ErrValidation))
+}
+
+func TestStructAcceptsValidRequests(t *testing.T) {
+	assert.NoError(t, Struct(Dto.BookRequest{Title: "Dune", Author: "Frank Herbert", ISBN: "0-441-17271-7"}))
+	assert.NoError(t, Struct(&Dto.BookStatusRequest{Status: "Lost", Reason: "Not returned"}))
+	assert.NoError(t, Struct(Dto.BookActionRequest{BookID: uuid.Must(uuid.NewV4())}))
+	assert.NoError(t, Struct(Dto.BookPatchRequest{}))
+}
+
+func TestStructMessages(t *testing.T) {
+	blank := "  "
+	tests := []struct {
+		name    string
+		request interface{}
+		want    FieldError
+	}{
+		{"zero uuid", Dto.BookActionRequest{}, FieldError{"book_id", "required", "book_id is required"}},
+		{"email", Dto.EmailRequest{Email: "meenah20"}, FieldError{"email", "email", "email must be a valid email address"}},
+		{"password", Dto.PasswordResetRequest{Token: "abc", Password: "short"}, FieldError{"password", "password", "password must be at least 8 characters"}},
+		{"oneof", Dto.UserRoleRequest{Role: "owner"}, FieldError{"role", "oneof", "role must be one of: patron, librarian, admin"}},
+		{"present but blank", Dto.BookPatchRequest{Title: &blank}, FieldError{"title", "notblank", "title cannot be blank"}},
+		{"slice element", Dto.APITokenRequest{Name: "kiosk", Scopes: []string{"books:read"}}, FieldError{"scopes[0]", "oneof", "scopes[0] must be one of: books:write, loans:write, users:admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var errs Errors
+			if assert.True(t, errors.As(Struct(tt.request), &errs)) {
+				assert.Equal(t, Errors{tt.want}, errs)
+			}
+		})
+	}
+}